@@ -3,6 +3,10 @@ package archive
 import (
 	"archive/tar"
 	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
 )
 
 // TarFileInBuffer will create a tar archive containing a single file named via fileName and using the content
@@ -34,3 +38,105 @@ func TarFileInBuffer(fileContent []byte, fileName string) ([]byte, error) {
 
 	return buffer.Bytes(), nil
 }
+
+// TarFilesInBuffer will create a tar archive containing the specified files. The keys of the map
+// are used as the path of each file inside the archive. Returns the archive as a byte array.
+func TarFilesInBuffer(files map[string][]byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	tarWriter := tar.NewWriter(&buffer)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		header := &tar.Header{
+			Name: name,
+			Mode: 0600,
+			Size: int64(len(files[name])),
+		}
+
+		err := tarWriter.WriteHeader(header)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tarWriter.Write(files[name])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := tarWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// TarDirectory writes a tar archive containing the content of the specified directory to writer.
+// Entries whose name is part of the excluded list (e.g. .git) are skipped. Symbolic links are archived
+// as links and are never followed.
+func TarDirectory(writer io.Writer, directory string, excluded []string) error {
+	tarWriter := tar.NewWriter(writer)
+
+	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		for _, name := range excluded {
+			if info.Name() == name {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		relativePath, err := filepath.Rel(directory, path)
+		if err != nil || relativePath == "." {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relativePath)
+
+		err = tarWriter.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(tarWriter, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tarWriter.Close()
+}
@@ -0,0 +1,95 @@
+package cleanuppolicy
+
+import (
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "cleanup_policies"
+)
+
+// Service represents a service for managing cleanup policy data.
+type Service struct {
+	db *bolt.DB
+}
+
+// NewService creates a new instance of a service.
+func NewService(db *bolt.DB) (*Service, error) {
+	err := internal.CreateBucket(db, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		db: db,
+	}, nil
+}
+
+// CleanupPolicy returns a cleanup policy object by ID.
+func (service *Service) CleanupPolicy(ID portainer.CleanupPolicyID) (*portainer.CleanupPolicy, error) {
+	var policy portainer.CleanupPolicy
+	identifier := internal.Itob(int(ID))
+
+	err := internal.GetObject(service.db, BucketName, identifier, &policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// CleanupPolicies returns an array containing all the cleanup policies.
+func (service *Service) CleanupPolicies() ([]portainer.CleanupPolicy, error) {
+	var policies = make([]portainer.CleanupPolicy, 0)
+
+	err := service.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var policy portainer.CleanupPolicy
+			err := internal.UnmarshalObject(v, &policy)
+			if err != nil {
+				return err
+			}
+			policies = append(policies, policy)
+		}
+
+		return nil
+	})
+
+	return policies, err
+}
+
+// CreateCleanupPolicy creates a new cleanup policy.
+func (service *Service) CreateCleanupPolicy(policy *portainer.CleanupPolicy) error {
+	return service.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		policy.ID = portainer.CleanupPolicyID(id)
+
+		data, err := internal.MarshalObject(policy)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(policy.ID)), data)
+	})
+}
+
+// UpdateCleanupPolicy updates a cleanup policy.
+func (service *Service) UpdateCleanupPolicy(ID portainer.CleanupPolicyID, policy *portainer.CleanupPolicy) error {
+	identifier := internal.Itob(int(ID))
+	return internal.UpdateObject(service.db, BucketName, identifier, policy)
+}
+
+// DeleteCleanupPolicy deletes a cleanup policy.
+func (service *Service) DeleteCleanupPolicy(ID portainer.CleanupPolicyID) error {
+	identifier := internal.Itob(int(ID))
+	return internal.DeleteObject(service.db, BucketName, identifier)
+}
@@ -0,0 +1,79 @@
+package cleanuprun
+
+import (
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "cleanup_runs"
+)
+
+// Service represents a service for managing cleanup run data.
+type Service struct {
+	db *bolt.DB
+}
+
+// NewService creates a new instance of a service.
+func NewService(db *bolt.DB) (*Service, error) {
+	err := internal.CreateBucket(db, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		db: db,
+	}, nil
+}
+
+// CleanupRunsByPolicy returns an array containing the runs of a cleanup policy, oldest first.
+func (service *Service) CleanupRunsByPolicy(policyID portainer.CleanupPolicyID) ([]portainer.CleanupRun, error) {
+	var runs = make([]portainer.CleanupRun, 0)
+
+	err := service.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var run portainer.CleanupRun
+			err := internal.UnmarshalObject(v, &run)
+			if err != nil {
+				return err
+			}
+
+			if run.PolicyID == policyID {
+				runs = append(runs, run)
+			}
+		}
+
+		return nil
+	})
+
+	return runs, err
+}
+
+// CreateCleanupRun creates a new cleanup run.
+func (service *Service) CreateCleanupRun(run *portainer.CleanupRun) error {
+	return service.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		run.ID = portainer.CleanupRunID(id)
+
+		data, err := internal.MarshalObject(run)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(run.ID)), data)
+	})
+}
+
+// DeleteCleanupRun deletes a cleanup run.
+func (service *Service) DeleteCleanupRun(ID portainer.CleanupRunID) error {
+	identifier := internal.Itob(int(ID))
+	return internal.DeleteObject(service.db, BucketName, identifier)
+}
@@ -7,14 +7,21 @@ import (
 
 	"github.com/boltdb/bolt"
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/bolt/cleanuppolicy"
+	"github.com/portainer/portainer/bolt/cleanuprun"
 	"github.com/portainer/portainer/bolt/dockerhub"
 	"github.com/portainer/portainer/bolt/endpoint"
 	"github.com/portainer/portainer/bolt/endpointgroup"
+	"github.com/portainer/portainer/bolt/environmentset"
 	"github.com/portainer/portainer/bolt/migrator"
 	"github.com/portainer/portainer/bolt/registry"
 	"github.com/portainer/portainer/bolt/resourcecontrol"
+	"github.com/portainer/portainer/bolt/schedule"
+	"github.com/portainer/portainer/bolt/scheduleexecution"
+	"github.com/portainer/portainer/bolt/sessionrecording"
 	"github.com/portainer/portainer/bolt/settings"
 	"github.com/portainer/portainer/bolt/stack"
+	"github.com/portainer/portainer/bolt/stackjob"
 	"github.com/portainer/portainer/bolt/tag"
 	"github.com/portainer/portainer/bolt/team"
 	"github.com/portainer/portainer/bolt/teammembership"
@@ -30,23 +37,30 @@ const (
 // Store defines the implementation of portainer.DataStore using
 // BoltDB as the storage system.
 type Store struct {
-	path                   string
-	db                     *bolt.DB
-	checkForDataMigration  bool
-	fileService            portainer.FileService
-	DockerHubService       *dockerhub.Service
-	EndpointGroupService   *endpointgroup.Service
-	EndpointService        *endpoint.Service
-	RegistryService        *registry.Service
-	ResourceControlService *resourcecontrol.Service
-	SettingsService        *settings.Service
-	StackService           *stack.Service
-	TagService             *tag.Service
-	TeamMembershipService  *teammembership.Service
-	TeamService            *team.Service
-	TemplateService        *template.Service
-	UserService            *user.Service
-	VersionService         *version.Service
+	path                     string
+	db                       *bolt.DB
+	checkForDataMigration    bool
+	fileService              portainer.FileService
+	CleanupPolicyService     *cleanuppolicy.Service
+	CleanupRunService        *cleanuprun.Service
+	DockerHubService         *dockerhub.Service
+	EndpointGroupService     *endpointgroup.Service
+	EnvironmentSetService    *environmentset.Service
+	EndpointService          *endpoint.Service
+	RegistryService          *registry.Service
+	ResourceControlService   *resourcecontrol.Service
+	ScheduleService          *schedule.Service
+	ScheduleExecutionService *scheduleexecution.Service
+	SessionRecordingService  *sessionrecording.Service
+	SettingsService          *settings.Service
+	StackService             *stack.Service
+	StackJobService          *stackjob.Service
+	TagService               *tag.Service
+	TeamMembershipService    *teammembership.Service
+	TeamService              *team.Service
+	TemplateService          *template.Service
+	UserService              *user.Service
+	VersionService           *version.Service
 }
 
 // NewStore initializes a new Store and the associated services
@@ -133,9 +147,11 @@ func (store *Store) MigrateData() error {
 			DatabaseVersion:        version,
 			EndpointGroupService:   store.EndpointGroupService,
 			EndpointService:        store.EndpointService,
+			RegistryService:        store.RegistryService,
 			ResourceControlService: store.ResourceControlService,
 			SettingsService:        store.SettingsService,
 			StackService:           store.StackService,
+			TemplateService:        store.TemplateService,
 			UserService:            store.UserService,
 			VersionService:         store.VersionService,
 			FileService:            store.fileService,
@@ -154,6 +170,18 @@ func (store *Store) MigrateData() error {
 }
 
 func (store *Store) initServices() error {
+	cleanuppolicyService, err := cleanuppolicy.NewService(store.db)
+	if err != nil {
+		return err
+	}
+	store.CleanupPolicyService = cleanuppolicyService
+
+	cleanuprunService, err := cleanuprun.NewService(store.db)
+	if err != nil {
+		return err
+	}
+	store.CleanupRunService = cleanuprunService
+
 	dockerhubService, err := dockerhub.NewService(store.db)
 	if err != nil {
 		return err
@@ -166,6 +194,12 @@ func (store *Store) initServices() error {
 	}
 	store.EndpointGroupService = endpointgroupService
 
+	environmentsetService, err := environmentset.NewService(store.db)
+	if err != nil {
+		return err
+	}
+	store.EnvironmentSetService = environmentsetService
+
 	endpointService, err := endpoint.NewService(store.db)
 	if err != nil {
 		return err
@@ -184,6 +218,24 @@ func (store *Store) initServices() error {
 	}
 	store.ResourceControlService = resourcecontrolService
 
+	scheduleService, err := schedule.NewService(store.db)
+	if err != nil {
+		return err
+	}
+	store.ScheduleService = scheduleService
+
+	scheduleexecutionService, err := scheduleexecution.NewService(store.db)
+	if err != nil {
+		return err
+	}
+	store.ScheduleExecutionService = scheduleexecutionService
+
+	sessionrecordingService, err := sessionrecording.NewService(store.db)
+	if err != nil {
+		return err
+	}
+	store.SessionRecordingService = sessionrecordingService
+
 	settingsService, err := settings.NewService(store.db)
 	if err != nil {
 		return err
@@ -196,6 +248,12 @@ func (store *Store) initServices() error {
 	}
 	store.StackService = stackService
 
+	stackjobService, err := stackjob.NewService(store.db)
+	if err != nil {
+		return err
+	}
+	store.StackJobService = stackjobService
+
 	tagService, err := tag.NewService(store.db)
 	if err != nil {
 		return err
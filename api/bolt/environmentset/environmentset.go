@@ -0,0 +1,95 @@
+package environmentset
+
+import (
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "environment_sets"
+)
+
+// Service represents a service for managing environment set data.
+type Service struct {
+	db *bolt.DB
+}
+
+// NewService creates a new instance of a service.
+func NewService(db *bolt.DB) (*Service, error) {
+	err := internal.CreateBucket(db, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		db: db,
+	}, nil
+}
+
+// EnvironmentSet returns an environment set object by ID.
+func (service *Service) EnvironmentSet(ID portainer.EnvironmentSetID) (*portainer.EnvironmentSet, error) {
+	var set portainer.EnvironmentSet
+	identifier := internal.Itob(int(ID))
+
+	err := internal.GetObject(service.db, BucketName, identifier, &set)
+	if err != nil {
+		return nil, err
+	}
+
+	return &set, nil
+}
+
+// EnvironmentSets returns an array containing all the environment sets.
+func (service *Service) EnvironmentSets() ([]portainer.EnvironmentSet, error) {
+	var sets = make([]portainer.EnvironmentSet, 0)
+
+	err := service.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var set portainer.EnvironmentSet
+			err := internal.UnmarshalObject(v, &set)
+			if err != nil {
+				return err
+			}
+			sets = append(sets, set)
+		}
+
+		return nil
+	})
+
+	return sets, err
+}
+
+// CreateEnvironmentSet creates a new environment set.
+func (service *Service) CreateEnvironmentSet(set *portainer.EnvironmentSet) error {
+	return service.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		set.ID = portainer.EnvironmentSetID(id)
+
+		data, err := internal.MarshalObject(set)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(set.ID)), data)
+	})
+}
+
+// UpdateEnvironmentSet updates a environment set.
+func (service *Service) UpdateEnvironmentSet(ID portainer.EnvironmentSetID, set *portainer.EnvironmentSet) error {
+	identifier := internal.Itob(int(ID))
+	return internal.UpdateObject(service.db, BucketName, identifier, set)
+}
+
+// DeleteEnvironmentSet deletes an environment set.
+func (service *Service) DeleteEnvironmentSet(ID portainer.EnvironmentSetID) error {
+	identifier := internal.Itob(int(ID))
+	return internal.DeleteObject(service.db, BucketName, identifier)
+}
@@ -0,0 +1,21 @@
+package migrator
+
+import "github.com/portainer/portainer"
+
+func (m *Migrator) updateRegistriesToVersion14() error {
+	legacyRegistries, err := m.registryService.Registries()
+	if err != nil {
+		return err
+	}
+
+	for _, registry := range legacyRegistries {
+		registry.Type = portainer.CustomRegistry
+
+		err = m.registryService.UpdateRegistry(registry.ID, &registry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package migrator
+
+import "github.com/portainer/portainer"
+
+func (m *Migrator) updateSettingsToVersion15() error {
+	legacySettings, err := m.settingsService.Settings()
+	if err != nil {
+		return err
+	}
+
+	legacySettings.SessionRecording = portainer.SessionRecordingSettings{
+		Enabled:       false,
+		RetentionDays: 30,
+	}
+
+	return m.settingsService.UpdateSettings(legacySettings)
+}
@@ -0,0 +1,14 @@
+package migrator
+
+import "github.com/portainer/portainer"
+
+func (m *Migrator) updateSettingsToVersion16() error {
+	legacySettings, err := m.settingsService.Settings()
+	if err != nil {
+		return err
+	}
+
+	legacySettings.TemplateSources = []portainer.TemplateSource{}
+
+	return m.settingsService.UpdateSettings(legacySettings)
+}
@@ -0,0 +1,25 @@
+package migrator
+
+import "github.com/portainer/portainer/templates"
+
+func (m *Migrator) updateTemplatesToVersion17() error {
+	legacyTemplates, err := m.templateService.Templates()
+	if err != nil {
+		return err
+	}
+
+	for _, template := range legacyTemplates {
+		if len(template.Inputs) != 0 || len(template.Env) == 0 {
+			continue
+		}
+
+		template.Inputs = templates.ConvertEnvToInputs(template.Env)
+
+		err = m.templateService.UpdateTemplate(template.ID, &template)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
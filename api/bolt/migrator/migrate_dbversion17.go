@@ -0,0 +1,19 @@
+package migrator
+
+func (m *Migrator) updateStacksToVersion18() error {
+	legacyStacks, err := m.stackService.Stacks()
+	if err != nil {
+		return err
+	}
+
+	for _, stack := range legacyStacks {
+		stack.AdditionalFiles = []string{}
+
+		err = m.stackService.UpdateStack(stack.ID, &stack)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
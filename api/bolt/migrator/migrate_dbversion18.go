@@ -0,0 +1,45 @@
+package migrator
+
+import (
+	"strconv"
+
+	"github.com/portainer/portainer"
+)
+
+func (m *Migrator) updateResourceControlsToVersion19() error {
+	legacyResourceControls, err := m.resourceControlService.ResourceControls()
+	if err != nil {
+		return err
+	}
+
+	stacks, err := m.stackService.Stacks()
+	if err != nil {
+		return err
+	}
+
+	for _, resourceControl := range legacyResourceControls {
+		if resourceControl.Type != portainer.StackResourceControl {
+			continue
+		}
+
+		for _, stack := range stacks {
+			if stack.Name != resourceControl.ResourceID {
+				continue
+			}
+
+			if stack.Type == portainer.DockerSwarmStack && stack.SwarmID != "" {
+				resourceControl.ResourceID = stack.Name + "_" + stack.SwarmID
+			} else {
+				resourceControl.ResourceID = stack.Name + "_" + strconv.Itoa(int(stack.EndpointID))
+			}
+
+			err = m.resourceControlService.UpdateResourceControl(resourceControl.ID, &resourceControl)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package migrator
+
+import "github.com/portainer/portainer"
+
+func (m *Migrator) updateStacksToVersion21() error {
+	legacyStacks, err := m.stackService.Stacks()
+	if err != nil {
+		return err
+	}
+
+	for _, stack := range legacyStacks {
+		stack.Status = portainer.StackStatusActive
+
+		err = m.stackService.UpdateStack(stack.ID, &stack)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -5,9 +5,11 @@ import (
 	"github.com/portainer/portainer"
 	"github.com/portainer/portainer/bolt/endpoint"
 	"github.com/portainer/portainer/bolt/endpointgroup"
+	"github.com/portainer/portainer/bolt/registry"
 	"github.com/portainer/portainer/bolt/resourcecontrol"
 	"github.com/portainer/portainer/bolt/settings"
 	"github.com/portainer/portainer/bolt/stack"
+	"github.com/portainer/portainer/bolt/template"
 	"github.com/portainer/portainer/bolt/user"
 	"github.com/portainer/portainer/bolt/version"
 )
@@ -19,9 +21,11 @@ type (
 		db                     *bolt.DB
 		endpointGroupService   *endpointgroup.Service
 		endpointService        *endpoint.Service
+		registryService        *registry.Service
 		resourceControlService *resourcecontrol.Service
 		settingsService        *settings.Service
 		stackService           *stack.Service
+		templateService        *template.Service
 		userService            *user.Service
 		versionService         *version.Service
 		fileService            portainer.FileService
@@ -33,9 +37,11 @@ type (
 		DatabaseVersion        int
 		EndpointGroupService   *endpointgroup.Service
 		EndpointService        *endpoint.Service
+		RegistryService        *registry.Service
 		ResourceControlService *resourcecontrol.Service
 		SettingsService        *settings.Service
 		StackService           *stack.Service
+		TemplateService        *template.Service
 		UserService            *user.Service
 		VersionService         *version.Service
 		FileService            portainer.FileService
@@ -49,9 +55,11 @@ func NewMigrator(parameters *Parameters) *Migrator {
 		currentDBVersion:       parameters.DatabaseVersion,
 		endpointGroupService:   parameters.EndpointGroupService,
 		endpointService:        parameters.EndpointService,
+		registryService:        parameters.RegistryService,
 		resourceControlService: parameters.ResourceControlService,
 		settingsService:        parameters.SettingsService,
 		stackService:           parameters.StackService,
+		templateService:        parameters.TemplateService,
 		userService:            parameters.UserService,
 		versionService:         parameters.VersionService,
 		fileService:            parameters.FileService,
@@ -178,5 +186,68 @@ func (m *Migrator) Migrate() error {
 		}
 	}
 
+	if m.currentDBVersion < 14 {
+		err := m.updateRegistriesToVersion14()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.currentDBVersion < 15 {
+		err := m.updateSettingsToVersion15()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.currentDBVersion < 16 {
+		err := m.updateSettingsToVersion16()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.currentDBVersion < 17 {
+		err := m.updateTemplatesToVersion17()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.currentDBVersion < 18 {
+		err := m.updateStacksToVersion18()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.currentDBVersion < 19 {
+		err := m.updateResourceControlsToVersion19()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.currentDBVersion < 20 {
+		err := m.updateEndpointsToVersion20()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.currentDBVersion < 21 {
+		err := m.updateStacksToVersion21()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.currentDBVersion < 22 {
+		err := m.updateStacksToVersion22()
+		if err != nil {
+			return err
+		}
+	}
+
 	return m.versionService.StoreDBVersion(portainer.DBVersion)
 }
@@ -0,0 +1,98 @@
+package scheduleexecution
+
+import (
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "schedule_executions"
+)
+
+// Service represents a service for managing schedule execution data.
+type Service struct {
+	db *bolt.DB
+}
+
+// NewService creates a new instance of a service.
+func NewService(db *bolt.DB) (*Service, error) {
+	err := internal.CreateBucket(db, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		db: db,
+	}, nil
+}
+
+// ScheduleExecution returns a schedule execution object by ID.
+func (service *Service) ScheduleExecution(ID portainer.ScheduleExecutionID) (*portainer.ScheduleExecution, error) {
+	var execution portainer.ScheduleExecution
+	identifier := internal.Itob(int(ID))
+
+	err := internal.GetObject(service.db, BucketName, identifier, &execution)
+	if err != nil {
+		return nil, err
+	}
+
+	return &execution, nil
+}
+
+// ScheduleExecutionsBySchedule returns an array containing the executions of a schedule, oldest first.
+func (service *Service) ScheduleExecutionsBySchedule(scheduleID portainer.ScheduleID) ([]portainer.ScheduleExecution, error) {
+	var executions = make([]portainer.ScheduleExecution, 0)
+
+	err := service.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var execution portainer.ScheduleExecution
+			err := internal.UnmarshalObject(v, &execution)
+			if err != nil {
+				return err
+			}
+
+			if execution.ScheduleID == scheduleID {
+				executions = append(executions, execution)
+			}
+		}
+
+		return nil
+	})
+
+	return executions, err
+}
+
+// CreateScheduleExecution creates a new schedule execution.
+func (service *Service) CreateScheduleExecution(execution *portainer.ScheduleExecution) error {
+	return service.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		execution.ID = portainer.ScheduleExecutionID(id)
+
+		data, err := internal.MarshalObject(execution)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(execution.ID)), data)
+	})
+}
+
+// UpdateScheduleExecution updates a schedule execution.
+func (service *Service) UpdateScheduleExecution(ID portainer.ScheduleExecutionID, execution *portainer.ScheduleExecution) error {
+	identifier := internal.Itob(int(ID))
+	return internal.UpdateObject(service.db, BucketName, identifier, execution)
+}
+
+// DeleteScheduleExecution deletes a schedule execution.
+func (service *Service) DeleteScheduleExecution(ID portainer.ScheduleExecutionID) error {
+	identifier := internal.Itob(int(ID))
+	return internal.DeleteObject(service.db, BucketName, identifier)
+}
@@ -0,0 +1,95 @@
+package sessionrecording
+
+import (
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "session_recordings"
+)
+
+// Service represents a service for managing session recording data.
+type Service struct {
+	db *bolt.DB
+}
+
+// NewService creates a new instance of a service.
+func NewService(db *bolt.DB) (*Service, error) {
+	err := internal.CreateBucket(db, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		db: db,
+	}, nil
+}
+
+// SessionRecording returns a session recording by ID.
+func (service *Service) SessionRecording(ID portainer.SessionRecordingID) (*portainer.SessionRecording, error) {
+	var recording portainer.SessionRecording
+	identifier := internal.Itob(int(ID))
+
+	err := internal.GetObject(service.db, BucketName, identifier, &recording)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recording, nil
+}
+
+// SessionRecordings returns an array containing all the session recordings.
+func (service *Service) SessionRecordings() ([]portainer.SessionRecording, error) {
+	var recordings = make([]portainer.SessionRecording, 0)
+
+	err := service.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var recording portainer.SessionRecording
+			err := internal.UnmarshalObject(v, &recording)
+			if err != nil {
+				return err
+			}
+			recordings = append(recordings, recording)
+		}
+
+		return nil
+	})
+
+	return recordings, err
+}
+
+// CreateSessionRecording creates a new session recording.
+func (service *Service) CreateSessionRecording(recording *portainer.SessionRecording) error {
+	return service.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		recording.ID = portainer.SessionRecordingID(id)
+
+		data, err := internal.MarshalObject(recording)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(recording.ID)), data)
+	})
+}
+
+// UpdateSessionRecording updates a session recording.
+func (service *Service) UpdateSessionRecording(ID portainer.SessionRecordingID, recording *portainer.SessionRecording) error {
+	identifier := internal.Itob(int(ID))
+	return internal.UpdateObject(service.db, BucketName, identifier, recording)
+}
+
+// DeleteSessionRecording deletes a session recording.
+func (service *Service) DeleteSessionRecording(ID portainer.SessionRecordingID) error {
+	identifier := internal.Itob(int(ID))
+	return internal.DeleteObject(service.db, BucketName, identifier)
+}
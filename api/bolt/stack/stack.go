@@ -42,8 +42,9 @@ func (service *Service) Stack(ID portainer.StackID) (*portainer.Stack, error) {
 	return &stack, nil
 }
 
-// StackByName returns a stack object by name.
-func (service *Service) StackByName(name string) (*portainer.Stack, error) {
+// StackByName returns a stack object by name. Stack names are only unique per endpoint,
+// the lookup is restricted to the stacks associated to the specified endpoint.
+func (service *Service) StackByName(endpointID portainer.EndpointID, name string) (*portainer.Stack, error) {
 	var stack *portainer.Stack
 
 	err := service.db.View(func(tx *bolt.Tx) error {
@@ -57,7 +58,7 @@ func (service *Service) StackByName(name string) (*portainer.Stack, error) {
 				return err
 			}
 
-			if t.Name == name {
+			if t.EndpointID == endpointID && t.Name == name {
 				stack = &t
 				break
 			}
@@ -0,0 +1,95 @@
+package stackjob
+
+import (
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "stack_jobs"
+)
+
+// Service represents a service for managing stack job data.
+type Service struct {
+	db *bolt.DB
+}
+
+// NewService creates a new instance of a service.
+func NewService(db *bolt.DB) (*Service, error) {
+	err := internal.CreateBucket(db, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		db: db,
+	}, nil
+}
+
+// StackJob returns a stack job object by ID.
+func (service *Service) StackJob(ID portainer.StackJobID) (*portainer.StackJob, error) {
+	var job portainer.StackJob
+	identifier := internal.Itob(int(ID))
+
+	err := internal.GetObject(service.db, BucketName, identifier, &job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// StackJobs returns an array containing all the stack jobs.
+func (service *Service) StackJobs() ([]portainer.StackJob, error) {
+	var jobs = make([]portainer.StackJob, 0)
+
+	err := service.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var job portainer.StackJob
+			err := internal.UnmarshalObject(v, &job)
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+		}
+
+		return nil
+	})
+
+	return jobs, err
+}
+
+// CreateStackJob creates a new stack job.
+func (service *Service) CreateStackJob(job *portainer.StackJob) error {
+	return service.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		job.ID = portainer.StackJobID(id)
+
+		data, err := internal.MarshalObject(job)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(job.ID)), data)
+	})
+}
+
+// UpdateStackJob updates a stack job.
+func (service *Service) UpdateStackJob(ID portainer.StackJobID, job *portainer.StackJob) error {
+	identifier := internal.Itob(int(ID))
+	return internal.UpdateObject(service.db, BucketName, identifier, job)
+}
+
+// DeleteStackJob deletes a stack job.
+func (service *Service) DeleteStackJob(ID portainer.StackJobID) error {
+	identifier := internal.Itob(int(ID))
+	return internal.DeleteObject(service.db, BucketName, identifier)
+}
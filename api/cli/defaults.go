@@ -3,21 +3,24 @@
 package cli
 
 const (
-	defaultBindAddress      = ":9000"
-	defaultDataDirectory    = "/data"
-	defaultAssetsDirectory  = "./"
-	defaultNoAuth           = "false"
-	defaultNoAnalytics      = "false"
-	defaultTLS              = "false"
-	defaultTLSSkipVerify    = "false"
-	defaultTLSCACertPath    = "/certs/ca.pem"
-	defaultTLSCertPath      = "/certs/cert.pem"
-	defaultTLSKeyPath       = "/certs/key.pem"
-	defaultSSL              = "false"
-	defaultSSLCertPath      = "/certs/portainer.crt"
-	defaultSSLKeyPath       = "/certs/portainer.key"
-	defaultSyncInterval     = "60s"
-	defaultSnapshot         = "true"
-	defaultSnapshotInterval = "5m"
-	defaultTemplateFile     = "/templates.json"
+	defaultBindAddress              = ":9000"
+	defaultDataDirectory            = "/data"
+	defaultAssetsDirectory          = "./"
+	defaultNoAuth                   = "false"
+	defaultNoAnalytics              = "false"
+	defaultTLS                      = "false"
+	defaultTLSSkipVerify            = "false"
+	defaultTLSCACertPath            = "/certs/ca.pem"
+	defaultTLSCertPath              = "/certs/cert.pem"
+	defaultTLSKeyPath               = "/certs/key.pem"
+	defaultSSL                      = "false"
+	defaultSSLCertPath              = "/certs/portainer.crt"
+	defaultSSLKeyPath               = "/certs/portainer.key"
+	defaultSyncInterval             = "60s"
+	defaultSnapshot                 = "true"
+	defaultSnapshotInterval         = "5m"
+	defaultImageUpdate              = "false"
+	defaultImageUpdateInterval      = "6h"
+	defaultTemplateFile             = "/templates.json"
+	defaultTemplatesRefreshInterval = "1h"
 )
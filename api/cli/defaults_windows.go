@@ -1,21 +1,24 @@
 package cli
 
 const (
-	defaultBindAddress      = ":9000"
-	defaultDataDirectory    = "C:\\data"
-	defaultAssetsDirectory  = "./"
-	defaultNoAuth           = "false"
-	defaultNoAnalytics      = "false"
-	defaultTLS              = "false"
-	defaultTLSSkipVerify    = "false"
-	defaultTLSCACertPath    = "C:\\certs\\ca.pem"
-	defaultTLSCertPath      = "C:\\certs\\cert.pem"
-	defaultTLSKeyPath       = "C:\\certs\\key.pem"
-	defaultSSL              = "false"
-	defaultSSLCertPath      = "C:\\certs\\portainer.crt"
-	defaultSSLKeyPath       = "C:\\certs\\portainer.key"
-	defaultSyncInterval     = "60s"
-	defaultSnapshot         = "true"
-	defaultSnapshotInterval = "5m"
-	defaultTemplateFile     = "/templates.json"
+	defaultBindAddress              = ":9000"
+	defaultDataDirectory            = "C:\\data"
+	defaultAssetsDirectory          = "./"
+	defaultNoAuth                   = "false"
+	defaultNoAnalytics              = "false"
+	defaultTLS                      = "false"
+	defaultTLSSkipVerify            = "false"
+	defaultTLSCACertPath            = "C:\\certs\\ca.pem"
+	defaultTLSCertPath              = "C:\\certs\\cert.pem"
+	defaultTLSKeyPath               = "C:\\certs\\key.pem"
+	defaultSSL                      = "false"
+	defaultSSLCertPath              = "C:\\certs\\portainer.crt"
+	defaultSSLKeyPath               = "C:\\certs\\portainer.key"
+	defaultSyncInterval             = "60s"
+	defaultSnapshot                 = "true"
+	defaultSnapshotInterval         = "5m"
+	defaultImageUpdate              = "false"
+	defaultImageUpdateInterval      = "6h"
+	defaultTemplateFile             = "/templates.json"
+	defaultTemplatesRefreshInterval = "1h"
 )
@@ -1,9 +1,9 @@
 package main // import "github.com/portainer/portainer"
 
 import (
-	"encoding/json"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/portainer/portainer"
 	"github.com/portainer/portainer/bolt"
@@ -19,6 +19,8 @@ import (
 	"github.com/portainer/portainer/jwt"
 	"github.com/portainer/portainer/ldap"
 	"github.com/portainer/portainer/libcompose"
+	"github.com/portainer/portainer/registry"
+	"github.com/portainer/portainer/templates"
 
 	"log"
 )
@@ -68,12 +70,12 @@ func initStore(dataStorePath string, fileService portainer.FileService) *bolt.St
 	return store
 }
 
-func initComposeStackManager(dataStorePath string) portainer.ComposeStackManager {
-	return libcompose.NewComposeStackManager(dataStorePath)
+func initComposeStackManager(assetsPath string, dataStorePath string) portainer.ComposeStackManager {
+	return exec.NewComposeStackManager(assetsPath, dataStorePath, libcompose.NewComposeStackManager(dataStorePath))
 }
 
-func initSwarmStackManager(assetsPath string, dataStorePath string, signatureService portainer.DigitalSignatureService, fileService portainer.FileService) (portainer.SwarmStackManager, error) {
-	return exec.NewSwarmStackManager(assetsPath, dataStorePath, signatureService, fileService)
+func initSwarmStackManager(assetsPath string, dataStorePath string, signatureService portainer.DigitalSignatureService, fileService portainer.FileService, credentialsService portainer.RegistryCredentialsService) (portainer.SwarmStackManager, error) {
+	return exec.NewSwarmStackManager(assetsPath, dataStorePath, signatureService, fileService, credentialsService)
 }
 
 func initJWTService(authenticationEnabled bool) portainer.JWTService {
@@ -103,6 +105,10 @@ func initGitService() portainer.GitService {
 	return &git.Service{}
 }
 
+func initRegistryService() *registry.Service {
+	return registry.NewService()
+}
+
 func initClientFactory(signatureService portainer.DigitalSignatureService) *docker.ClientFactory {
 	return docker.NewClientFactory(signatureService)
 }
@@ -111,27 +117,187 @@ func initSnapshotter(clientFactory *docker.ClientFactory) portainer.Snapshotter
 	return docker.NewSnapshotter(clientFactory)
 }
 
-func initJobScheduler(endpointService portainer.EndpointService, snapshotter portainer.Snapshotter, flags *portainer.CLIFlags) (portainer.JobScheduler, error) {
-	jobScheduler := cron.NewJobScheduler(endpointService, snapshotter)
+func initImageUpdateChecker(clientFactory *docker.ClientFactory, registryService portainer.RegistryService, dockerHubService portainer.DockerHubService, credentialsService portainer.RegistryCredentialsService, manifestService portainer.RegistryManifestService) portainer.ImageUpdateService {
+	return docker.NewImageUpdateChecker(clientFactory, registryService, dockerHubService, credentialsService, manifestService)
+}
+
+func initScheduleRunner(clientFactory *docker.ClientFactory, endpointService portainer.EndpointService, executionService portainer.ScheduleExecutionService, fileService portainer.FileService) portainer.ScheduleRunner {
+	return docker.NewScheduleRunner(clientFactory, endpointService, executionService, fileService)
+}
+
+func initEndpointCleaner(clientFactory *docker.ClientFactory, endpointService portainer.EndpointService) portainer.EndpointCleaner {
+	return docker.NewEndpointCleaner(clientFactory, endpointService)
+}
+
+func initTemplateSourceService(templateService portainer.TemplateService, settingsService portainer.SettingsService, gitService portainer.GitService) portainer.TemplateSourceService {
+	return templates.NewService(templateService, settingsService, gitService)
+}
+
+func initJobScheduler(endpointService portainer.EndpointService, snapshotter portainer.Snapshotter, imageUpdateService portainer.ImageUpdateService, settingsService portainer.SettingsService, sessionRecordingService portainer.SessionRecordingService, fileService portainer.FileService, templateSourceService portainer.TemplateSourceService, scheduleService portainer.ScheduleService, scheduleRunner portainer.ScheduleRunner, cleanupPolicyService portainer.CleanupPolicyService, cleanupRunService portainer.CleanupRunService, endpointCleaner portainer.EndpointCleaner, stackJobService portainer.StackJobService, flags *portainer.CLIFlags) (portainer.JobScheduler, error) {
+	jobScheduler := cron.NewJobScheduler()
 
 	if *flags.ExternalEndpoints != "" {
 		log.Println("Using external endpoint definition. Endpoint management via the API will be disabled.")
-		err := jobScheduler.ScheduleEndpointSyncJob(*flags.ExternalEndpoints, *flags.SyncInterval)
+		endpointSyncJob := cron.NewEndpointSyncJob(*flags.ExternalEndpoints, endpointService)
+
+		err := endpointSyncJob.Run()
+		if err != nil {
+			return nil, err
+		}
+
+		err = jobScheduler.ScheduleJob(cron.EndpointSyncJobName, "@every "+*flags.SyncInterval, endpointSyncJob)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	if *flags.Snapshot {
-		err := jobScheduler.ScheduleSnapshotJob(*flags.SnapshotInterval)
+		err := jobScheduler.ScheduleJob(cron.EndpointSnapshotJobName, "@every "+*flags.SnapshotInterval, cron.NewEndpointSnapshotJob(endpointService, snapshotter))
 		if err != nil {
 			return nil, err
 		}
+
+		err = jobScheduler.RunJob(cron.EndpointSnapshotJobName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if *flags.ImageUpdate {
+		err := jobScheduler.ScheduleJob(cron.ImageUpdateJobName, "@every "+*flags.ImageUpdateInterval, cron.NewEndpointImageUpdateJob(endpointService, imageUpdateService))
+		if err != nil {
+			return nil, err
+		}
+
+		err = jobScheduler.RunJob(cron.ImageUpdateJobName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := jobScheduler.ScheduleJob(cron.SessionRecordingCleanupJobName, cron.SessionRecordingCleanupSchedule, cron.NewSessionRecordingCleanupJob(settingsService, sessionRecordingService, fileService))
+	if err != nil {
+		return nil, err
+	}
+
+	err = jobScheduler.ScheduleJob(cron.StackJobCleanupJobName, cron.StackJobCleanupSchedule, cron.NewStackJobCleanupJob(stackJobService))
+	if err != nil {
+		return nil, err
+	}
+
+	err = jobScheduler.ScheduleJob(cron.TemplateSourcesRefreshJobName, "@every "+*flags.TemplatesRefreshInterval, cron.NewTemplateSourcesRefreshJob(templateSourceService))
+	if err != nil {
+		return nil, err
+	}
+
+	schedules, err := scheduleService.Schedules()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, schedule := range schedules {
+		err = jobScheduler.ScheduleJob(cron.ScheduleJobName(schedule.ID), schedule.CronExpression, cron.NewScheduleJob(schedule.ID, scheduleService, scheduleRunner))
+		if err != nil {
+			log.Printf("Unable to schedule the job of a schedule, skipping. [name: %v] [cron: %v] [err: %v]", schedule.Name, schedule.CronExpression, err)
+		}
+	}
+
+	policies, err := cleanupPolicyService.CleanupPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, policy := range policies {
+		err = jobScheduler.ScheduleJob(cron.CleanupPolicyJobName(policy.ID), policy.CronExpression, cron.NewCleanupPolicyJob(policy.ID, cleanupPolicyService, cleanupRunService, endpointCleaner))
+		if err != nil {
+			log.Printf("Unable to schedule a cleanup policy, skipping. [name: %v] [cron: %v] [err: %v]", policy.Name, policy.CronExpression, err)
+		}
 	}
 
 	return jobScheduler, nil
 }
 
+// failInterruptedStackJobs marks the stack jobs that were queued or running when Portainer stopped as failed.
+func failInterruptedStackJobs(stackJobService portainer.StackJobService) error {
+	jobs, err := stackJobService.StackJobs()
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if job.Status != portainer.StackJobQueued && job.Status != portainer.StackJobRunning {
+			continue
+		}
+
+		job.Status = portainer.StackJobFailed
+		job.Error = "The job was interrupted by a restart of Portainer"
+		job.FinishedAt = time.Now().Unix()
+
+		err = stackJobService.UpdateStackJob(job.ID, &job)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endInterruptedSessionRecordings sets the end time of the session recordings of the sessions that were
+// in progress when Portainer stopped. Their start time is used as the time of their last activity is unknown.
+func endInterruptedSessionRecordings(sessionRecordingService portainer.SessionRecordingService) error {
+	recordings, err := sessionRecordingService.SessionRecordings()
+	if err != nil {
+		return err
+	}
+
+	for _, recording := range recordings {
+		if recording.EndTime != 0 {
+			continue
+		}
+
+		recording.EndTime = recording.StartTime
+
+		err = sessionRecordingService.UpdateSessionRecording(recording.ID, &recording)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// failInterruptedScheduleExecutions marks the schedule executions that were running when Portainer stopped as failed.
+func failInterruptedScheduleExecutions(scheduleService portainer.ScheduleService, executionService portainer.ScheduleExecutionService) error {
+	schedules, err := scheduleService.Schedules()
+	if err != nil {
+		return err
+	}
+
+	for _, schedule := range schedules {
+		executions, err := executionService.ScheduleExecutionsBySchedule(schedule.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, execution := range executions {
+			if execution.Status != portainer.ScheduleExecutionRunning {
+				continue
+			}
+
+			execution.Status = portainer.ScheduleExecutionFailed
+			execution.Error = "The execution was interrupted by a restart of Portainer"
+			execution.FinishedAt = time.Now().Unix()
+
+			err = executionService.UpdateScheduleExecution(execution.ID, &execution)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func initStatus(endpointManagement, snapshot bool, flags *portainer.CLIFlags) *portainer.Status {
 	return &portainer.Status{
 		Analytics:          !*flags.NoAnalytics,
@@ -177,6 +343,11 @@ func initSettings(settingsService portainer.SettingsService, flags *portainer.CL
 			AllowBindMountsForRegularUsers:     true,
 			AllowPrivilegedModeForRegularUsers: true,
 			SnapshotInterval:                   *flags.SnapshotInterval,
+			SessionRecording: portainer.SessionRecordingSettings{
+				Enabled:       false,
+				RetentionDays: 30,
+			},
+			TemplateSources: []portainer.TemplateSource{},
 		}
 
 		if *flags.Templates != "" {
@@ -233,14 +404,13 @@ func loadTemplatesFromFile(fileService portainer.FileService, templateService po
 }
 
 func unmarshalAndPersistTemplates(templateService portainer.TemplateService, templateData []byte) error {
-	var templates []portainer.Template
-	err := json.Unmarshal(templateData, &templates)
+	parsedTemplates, err := templates.ParseTemplates(templateData)
 	if err != nil {
 		log.Println("Unable to parse templates file. Please review your template definition file.")
 		return err
 	}
 
-	for _, template := range templates {
+	for _, template := range parsedTemplates {
 		err := templateService.CreateTemplate(&template)
 		if err != nil {
 			return err
@@ -274,6 +444,32 @@ func generateAndStoreKeyPair(fileService portainer.FileService, signatureService
 	return fileService.StoreKeyPair(private, public, privateHeader, publicHeader)
 }
 
+func initEncryptionService(fileService portainer.FileService) (portainer.EncryptionService, error) {
+	existingKey, err := fileService.EncryptionKeyFileExists()
+	if err != nil {
+		return nil, err
+	}
+
+	if !existingKey {
+		key, err := crypto.GenerateEncryptionKey()
+		if err != nil {
+			return nil, err
+		}
+
+		err = fileService.StoreEncryptionKey(key, crypto.EncryptionKeyPemHeader)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := fileService.LoadEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.NewAESService(key)
+}
+
 func initKeyPair(fileService portainer.FileService, signatureService portainer.DigitalSignatureService) error {
 	existingKeyPair, err := fileService.KeyPairFilesExist()
 	if err != nil {
@@ -302,18 +498,19 @@ func createTLSSecuredEndpoint(flags *portainer.CLIFlags, endpointService portain
 
 	endpointID := endpointService.GetNextIdentifier()
 	endpoint := &portainer.Endpoint{
-		ID:              portainer.EndpointID(endpointID),
-		Name:            "primary",
-		URL:             *flags.EndpointURL,
-		GroupID:         portainer.EndpointGroupID(1),
-		Type:            portainer.DockerEnvironment,
-		TLSConfig:       tlsConfiguration,
-		AuthorizedUsers: []portainer.UserID{},
-		AuthorizedTeams: []portainer.TeamID{},
-		Extensions:      []portainer.EndpointExtension{},
-		Tags:            []string{},
-		Status:          portainer.EndpointStatusUp,
-		Snapshots:       []portainer.Snapshot{},
+		ID:                 portainer.EndpointID(endpointID),
+		Name:               "primary",
+		URL:                *flags.EndpointURL,
+		GroupID:            portainer.EndpointGroupID(1),
+		Type:               portainer.DockerEnvironment,
+		TLSConfig:          tlsConfiguration,
+		AuthorizedUsers:    []portainer.UserID{},
+		AuthorizedTeams:    []portainer.TeamID{},
+		Extensions:         []portainer.EndpointExtension{},
+		Tags:               []string{},
+		Status:             portainer.EndpointStatusUp,
+		Snapshots:          []portainer.Snapshot{},
+		ComposeStackEngine: portainer.LibComposeEngine,
 	}
 
 	if strings.HasPrefix(endpoint.URL, "tcp://") {
@@ -345,18 +542,19 @@ func createUnsecuredEndpoint(endpointURL string, endpointService portainer.Endpo
 
 	endpointID := endpointService.GetNextIdentifier()
 	endpoint := &portainer.Endpoint{
-		ID:              portainer.EndpointID(endpointID),
-		Name:            "primary",
-		URL:             endpointURL,
-		GroupID:         portainer.EndpointGroupID(1),
-		Type:            portainer.DockerEnvironment,
-		TLSConfig:       portainer.TLSConfiguration{},
-		AuthorizedUsers: []portainer.UserID{},
-		AuthorizedTeams: []portainer.TeamID{},
-		Extensions:      []portainer.EndpointExtension{},
-		Tags:            []string{},
-		Status:          portainer.EndpointStatusUp,
-		Snapshots:       []portainer.Snapshot{},
+		ID:                 portainer.EndpointID(endpointID),
+		Name:               "primary",
+		URL:                endpointURL,
+		GroupID:            portainer.EndpointGroupID(1),
+		Type:               portainer.DockerEnvironment,
+		TLSConfig:          portainer.TLSConfiguration{},
+		AuthorizedUsers:    []portainer.UserID{},
+		AuthorizedTeams:    []portainer.TeamID{},
+		Extensions:         []portainer.EndpointExtension{},
+		Tags:               []string{},
+		Status:             portainer.EndpointStatusUp,
+		Snapshots:          []portainer.Snapshot{},
+		ComposeStackEngine: portainer.LibComposeEngine,
 	}
 
 	return snapshotAndPersistEndpoint(endpoint, endpointService, snapshotter)
@@ -411,6 +609,8 @@ func main() {
 
 	gitService := initGitService()
 
+	registryService := initRegistryService()
+
 	cryptoService := initCryptoService()
 
 	digitalSignatureService := initDigitalSignatureService()
@@ -420,11 +620,39 @@ func main() {
 		log.Fatal(err)
 	}
 
+	encryptionService, err := initEncryptionService(fileService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	clientFactory := initClientFactory(digitalSignatureService)
 
 	snapshotter := initSnapshotter(clientFactory)
 
-	jobScheduler, err := initJobScheduler(store.EndpointService, snapshotter, flags)
+	imageUpdateChecker := initImageUpdateChecker(clientFactory, store.RegistryService, store.DockerHubService, registryService, registryService)
+
+	templateSourceService := initTemplateSourceService(store.TemplateService, store.SettingsService, gitService)
+
+	scheduleRunner := initScheduleRunner(clientFactory, store.EndpointService, store.ScheduleExecutionService, fileService)
+
+	endpointCleaner := initEndpointCleaner(clientFactory, store.EndpointService)
+
+	err = failInterruptedStackJobs(store.StackJobService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = failInterruptedScheduleExecutions(store.ScheduleService, store.ScheduleExecutionService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = endInterruptedSessionRecordings(store.SessionRecordingService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	jobScheduler, err := initJobScheduler(store.EndpointService, snapshotter, imageUpdateChecker, store.SettingsService, store.SessionRecordingService, fileService, templateSourceService, store.ScheduleService, scheduleRunner, store.CleanupPolicyService, store.CleanupRunService, endpointCleaner, store.StackJobService, flags)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -436,12 +664,12 @@ func main() {
 		endpointManagement = false
 	}
 
-	swarmStackManager, err := initSwarmStackManager(*flags.Assets, *flags.Data, digitalSignatureService, fileService)
+	swarmStackManager, err := initSwarmStackManager(*flags.Assets, *flags.Data, digitalSignatureService, fileService, registryService)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	composeStackManager := initComposeStackManager(*flags.Data)
+	composeStackManager := initComposeStackManager(*flags.Assets, *flags.Data)
 
 	err = initTemplates(store.TemplateService, fileService, *flags.Templates, *flags.TemplateFile)
 	if err != nil {
@@ -502,36 +730,51 @@ func main() {
 	}
 
 	var server portainer.Server = &http.Server{
-		Status:                 applicationStatus,
-		BindAddress:            *flags.Addr,
-		AssetsPath:             *flags.Assets,
-		AuthDisabled:           *flags.NoAuth,
-		EndpointManagement:     endpointManagement,
-		UserService:            store.UserService,
-		TeamService:            store.TeamService,
-		TeamMembershipService:  store.TeamMembershipService,
-		EndpointService:        store.EndpointService,
-		EndpointGroupService:   store.EndpointGroupService,
-		ResourceControlService: store.ResourceControlService,
-		SettingsService:        store.SettingsService,
-		RegistryService:        store.RegistryService,
-		DockerHubService:       store.DockerHubService,
-		StackService:           store.StackService,
-		TagService:             store.TagService,
-		TemplateService:        store.TemplateService,
-		SwarmStackManager:      swarmStackManager,
-		ComposeStackManager:    composeStackManager,
-		CryptoService:          cryptoService,
-		JWTService:             jwtService,
-		FileService:            fileService,
-		LDAPService:            ldapService,
-		GitService:             gitService,
-		SignatureService:       digitalSignatureService,
-		JobScheduler:           jobScheduler,
-		Snapshotter:            snapshotter,
-		SSL:                    *flags.SSL,
-		SSLCert:                *flags.SSLCert,
-		SSLKey:                 *flags.SSLKey,
+		Status:                   applicationStatus,
+		BindAddress:              *flags.Addr,
+		AssetsPath:               *flags.Assets,
+		AuthDisabled:             *flags.NoAuth,
+		EndpointManagement:       endpointManagement,
+		UserService:              store.UserService,
+		TeamService:              store.TeamService,
+		TeamMembershipService:    store.TeamMembershipService,
+		EndpointService:          store.EndpointService,
+		EndpointGroupService:     store.EndpointGroupService,
+		EnvironmentSetService:    store.EnvironmentSetService,
+		ResourceControlService:   store.ResourceControlService,
+		ScheduleService:          store.ScheduleService,
+		ScheduleExecutionService: store.ScheduleExecutionService,
+		ScheduleRunner:           scheduleRunner,
+		CleanupPolicyService:     store.CleanupPolicyService,
+		CleanupRunService:        store.CleanupRunService,
+		EndpointCleaner:          endpointCleaner,
+		SettingsService:          store.SettingsService,
+		RegistryService:          store.RegistryService,
+		DockerHubService:         store.DockerHubService,
+		StackService:             store.StackService,
+		StackJobService:          store.StackJobService,
+		TagService:               store.TagService,
+		TemplateService:          store.TemplateService,
+		TemplateSourceService:    templateSourceService,
+		SwarmStackManager:        swarmStackManager,
+		ComposeStackManager:      composeStackManager,
+		CryptoService:            cryptoService,
+		EncryptionService:        encryptionService,
+		JWTService:               jwtService,
+		FileService:              fileService,
+		LDAPService:              ldapService,
+		GitService:               gitService,
+		CredentialsService:       registryService,
+		ConnectivityService:      registryService,
+		ImageUpdateService:       imageUpdateChecker,
+		SessionRecordingService:  store.SessionRecordingService,
+		DockerClientFactory:      clientFactory,
+		SignatureService:         digitalSignatureService,
+		JobScheduler:             jobScheduler,
+		Snapshotter:              snapshotter,
+		SSL:                      *flags.SSL,
+		SSLCert:                  *flags.SSLCert,
+		SSLKey:                   *flags.SSLKey,
 	}
 
 	log.Printf("Starting Portainer %s on %s", portainer.APIVersion, *flags.Addr)
@@ -0,0 +1,91 @@
+package cron
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+// maxCleanupRuns is the number of runs kept in the history of a cleanup policy.
+const maxCleanupRuns = 50
+
+type (
+	cleanupPolicyJob struct {
+		policyID        portainer.CleanupPolicyID
+		policyService   portainer.CleanupPolicyService
+		runService      portainer.CleanupRunService
+		endpointCleaner portainer.EndpointCleaner
+	}
+)
+
+// CleanupPolicyJobName returns the name of the job of a cleanup policy.
+func CleanupPolicyJobName(policyID portainer.CleanupPolicyID) string {
+	return "cleanup_policy_" + strconv.Itoa(int(policyID))
+}
+
+// NewCleanupPolicyJob returns a job removing the unused resources matching a cleanup policy on its endpoints.
+func NewCleanupPolicyJob(policyID portainer.CleanupPolicyID, policyService portainer.CleanupPolicyService, runService portainer.CleanupRunService, endpointCleaner portainer.EndpointCleaner) portainer.Job {
+	return cleanupPolicyJob{
+		policyID:        policyID,
+		policyService:   policyService,
+		runService:      runService,
+		endpointCleaner: endpointCleaner,
+	}
+}
+
+// Run applies the cleanup policy and records the run in the history of the policy,
+// only the last runs of the policy are kept.
+func (job cleanupPolicyJob) Run() error {
+	policy, err := job.policyService.CleanupPolicy(job.policyID)
+	if err != nil {
+		return err
+	}
+
+	run := &portainer.CleanupRun{
+		PolicyID:  policy.ID,
+		StartedAt: time.Now().Unix(),
+		Reports:   []portainer.CleanupReport{},
+	}
+
+	reports, cleanupErr := job.endpointCleaner.Cleanup(policy, false)
+	if cleanupErr != nil {
+		run.Error = cleanupErr.Error()
+	} else {
+		run.Reports = reports
+	}
+
+	for _, report := range run.Reports {
+		run.SpaceReclaimed += report.SpaceReclaimed
+	}
+	run.FinishedAt = time.Now().Unix()
+
+	err = job.runService.CreateCleanupRun(run)
+	if err != nil {
+		return err
+	}
+
+	err = job.pruneRuns()
+	if err != nil {
+		return err
+	}
+
+	return cleanupErr
+}
+
+// pruneRuns removes the oldest runs of the policy to keep the size of its history under maxCleanupRuns.
+func (job cleanupPolicyJob) pruneRuns() error {
+	runs, err := job.runService.CleanupRunsByPolicy(job.policyID)
+	if err != nil {
+		return err
+	}
+
+	for idx := 0; idx < len(runs)-maxCleanupRuns; idx++ {
+		err = job.runService.DeleteCleanupRun(runs[idx].ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
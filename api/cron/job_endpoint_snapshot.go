@@ -13,7 +13,8 @@ type (
 	}
 )
 
-func newEndpointSnapshotJob(endpointService portainer.EndpointService, snapshotter portainer.Snapshotter) endpointSnapshotJob {
+// NewEndpointSnapshotJob returns a job creating a snapshot of each endpoint.
+func NewEndpointSnapshotJob(endpointService portainer.EndpointService, snapshotter portainer.Snapshotter) portainer.Job {
 	return endpointSnapshotJob{
 		endpointService: endpointService,
 		snapshotter:     snapshotter,
@@ -52,9 +53,6 @@ func (job endpointSnapshotJob) Snapshot() error {
 	return nil
 }
 
-func (job endpointSnapshotJob) Run() {
-	err := job.Snapshot()
-	if err != nil {
-		log.Printf("cron error: snapshot job error (err=%s)\n", err)
-	}
+func (job endpointSnapshotJob) Run() error {
+	return job.Snapshot()
 }
@@ -37,7 +37,8 @@ const (
 	ErrEmptyEndpointArray = portainer.Error("External endpoint source is empty")
 )
 
-func newEndpointSyncJob(endpointFilePath string, endpointService portainer.EndpointService) endpointSyncJob {
+// NewEndpointSyncJob returns a job synchronizing the endpoints with the endpoints defined in a file.
+func NewEndpointSyncJob(endpointFilePath string, endpointService portainer.EndpointService) portainer.Job {
 	return endpointSyncJob{
 		endpointService:  endpointService,
 		endpointFilePath: endpointFilePath,
@@ -67,9 +68,10 @@ func convertFileEndpoints(fileEndpoints []fileEndpoint) []portainer.Endpoint {
 
 	for _, e := range fileEndpoints {
 		endpoint := portainer.Endpoint{
-			Name:      e.Name,
-			URL:       e.URL,
-			TLSConfig: portainer.TLSConfiguration{},
+			Name:               e.Name,
+			URL:                e.URL,
+			TLSConfig:          portainer.TLSConfiguration{},
+			ComposeStackEngine: portainer.LibComposeEngine,
 		}
 		if e.TLS {
 			endpoint.TLSConfig.TLS = true
@@ -199,8 +201,7 @@ func (job endpointSyncJob) Sync() error {
 	return nil
 }
 
-func (job endpointSyncJob) Run() {
+func (job endpointSyncJob) Run() error {
 	log.Println("cron: synchronization job started")
-	err := job.Sync()
-	endpointSyncError(err)
+	return job.Sync()
 }
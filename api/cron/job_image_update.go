@@ -0,0 +1,46 @@
+package cron
+
+import (
+	"log"
+
+	"github.com/portainer/portainer"
+)
+
+type (
+	endpointImageUpdateJob struct {
+		endpointService    portainer.EndpointService
+		imageUpdateService portainer.ImageUpdateService
+	}
+)
+
+// NewEndpointImageUpdateJob returns a job detecting the containers and services running an outdated image on each endpoint.
+func NewEndpointImageUpdateJob(endpointService portainer.EndpointService, imageUpdateService portainer.ImageUpdateService) portainer.Job {
+	return endpointImageUpdateJob{
+		endpointService:    endpointService,
+		imageUpdateService: imageUpdateService,
+	}
+}
+
+func (job endpointImageUpdateJob) Check() error {
+	endpoints, err := job.endpointService.Endpoints()
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		if endpoint.Type == portainer.AzureEnvironment || endpoint.Status == portainer.EndpointStatusDown {
+			continue
+		}
+
+		_, err := job.imageUpdateService.CheckEndpoint(&endpoint)
+		if err != nil {
+			log.Printf("cron error: endpoint image update error (endpoint=%s, URL=%s) (err=%s)\n", endpoint.Name, endpoint.URL, err)
+		}
+	}
+
+	return nil
+}
+
+func (job endpointImageUpdateJob) Run() error {
+	return job.Check()
+}
@@ -0,0 +1,41 @@
+package cron
+
+import (
+	"strconv"
+
+	"github.com/portainer/portainer"
+)
+
+type (
+	scheduleJob struct {
+		scheduleID      portainer.ScheduleID
+		scheduleService portainer.ScheduleService
+		scheduleRunner  portainer.ScheduleRunner
+	}
+)
+
+// ScheduleJobName returns the name of the job of a schedule.
+func ScheduleJobName(scheduleID portainer.ScheduleID) string {
+	return "schedule_" + strconv.Itoa(int(scheduleID))
+}
+
+// NewScheduleJob returns a job running the job of a schedule on its endpoints.
+func NewScheduleJob(scheduleID portainer.ScheduleID, scheduleService portainer.ScheduleService, scheduleRunner portainer.ScheduleRunner) portainer.Job {
+	return scheduleJob{
+		scheduleID:      scheduleID,
+		scheduleService: scheduleService,
+		scheduleRunner:  scheduleRunner,
+	}
+}
+
+// Run runs the job of the schedule. The schedule is retrieved from the database
+// so that the job always runs the latest definition of the schedule.
+func (job scheduleJob) Run() error {
+	schedule, err := job.scheduleService.Schedule(job.scheduleID)
+	if err != nil {
+		return err
+	}
+
+	_, err = job.scheduleRunner.Run(schedule)
+	return err
+}
@@ -0,0 +1,70 @@
+package cron
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+type (
+	sessionRecordingCleanupJob struct {
+		settingsService         portainer.SettingsService
+		sessionRecordingService portainer.SessionRecordingService
+		fileService             portainer.FileService
+	}
+)
+
+// NewSessionRecordingCleanupJob returns a job removing the session recordings older than the retention period defined in the settings.
+func NewSessionRecordingCleanupJob(settingsService portainer.SettingsService, sessionRecordingService portainer.SessionRecordingService, fileService portainer.FileService) portainer.Job {
+	return sessionRecordingCleanupJob{
+		settingsService:         settingsService,
+		sessionRecordingService: sessionRecordingService,
+		fileService:             fileService,
+	}
+}
+
+// Cleanup removes the session recordings older than the retention period defined in the settings.
+func (job sessionRecordingCleanupJob) Cleanup() error {
+	settings, err := job.settingsService.Settings()
+	if err != nil {
+		return err
+	}
+
+	if settings.SessionRecording.RetentionDays == 0 {
+		return nil
+	}
+
+	recordings, err := job.sessionRecordingService.SessionRecordings()
+	if err != nil {
+		return err
+	}
+
+	limit := time.Now().AddDate(0, 0, -settings.SessionRecording.RetentionDays).Unix()
+
+	for _, recording := range recordings {
+		// Sessions still in progress do not have an end time yet, the sessions interrupted
+		// by a restart are given one at startup
+		if recording.EndTime == 0 || recording.EndTime > limit {
+			continue
+		}
+
+		err = job.fileService.DeleteSessionRecordingFile(strconv.Itoa(int(recording.ID)))
+		if err != nil {
+			log.Printf("cron error: unable to remove session recording file (recording=%d) (err=%s)\n", recording.ID, err)
+			continue
+		}
+
+		err = job.sessionRecordingService.DeleteSessionRecording(recording.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (job sessionRecordingCleanupJob) Run() error {
+	return job.Cleanup()
+}
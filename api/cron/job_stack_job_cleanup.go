@@ -0,0 +1,51 @@
+package cron
+
+import (
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+// StackJobRetentionPeriod is the period during which the completed stack jobs are kept.
+const StackJobRetentionPeriod = 7 * 24 * time.Hour
+
+type (
+	stackJobCleanupJob struct {
+		stackJobService portainer.StackJobService
+	}
+)
+
+// NewStackJobCleanupJob returns a job removing the stack jobs completed before the retention period.
+func NewStackJobCleanupJob(stackJobService portainer.StackJobService) portainer.Job {
+	return stackJobCleanupJob{
+		stackJobService: stackJobService,
+	}
+}
+
+// Cleanup removes the stack jobs completed before the retention period.
+func (job stackJobCleanupJob) Cleanup() error {
+	jobs, err := job.stackJobService.StackJobs()
+	if err != nil {
+		return err
+	}
+
+	limit := time.Now().Add(-StackJobRetentionPeriod).Unix()
+
+	for _, stackJob := range jobs {
+		// Jobs still queued or running do not have a completion time yet
+		if stackJob.FinishedAt == 0 || stackJob.FinishedAt > limit {
+			continue
+		}
+
+		err = job.stackJobService.DeleteStackJob(stackJob.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (job stackJobCleanupJob) Run() error {
+	return job.Cleanup()
+}
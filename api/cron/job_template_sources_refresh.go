@@ -0,0 +1,22 @@
+package cron
+
+import (
+	"github.com/portainer/portainer"
+)
+
+type (
+	templateSourcesRefreshJob struct {
+		templateSourceService portainer.TemplateSourceService
+	}
+)
+
+// NewTemplateSourcesRefreshJob returns a job importing the templates defined in the template sources.
+func NewTemplateSourcesRefreshJob(templateSourceService portainer.TemplateSourceService) portainer.Job {
+	return templateSourcesRefreshJob{
+		templateSourceService: templateSourceService,
+	}
+}
+
+func (job templateSourcesRefreshJob) Run() error {
+	return job.templateSourceService.RefreshTemplateSources()
+}
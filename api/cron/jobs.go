@@ -0,0 +1,23 @@
+package cron
+
+// Names of the jobs run by Portainer.
+const (
+	// EndpointSyncJobName is the name of the job synchronizing the endpoints with the external endpoints source
+	EndpointSyncJobName = "endpoint_sync"
+	// EndpointSnapshotJobName is the name of the job creating the endpoint snapshots
+	EndpointSnapshotJobName = "endpoint_snapshot"
+	// ImageUpdateJobName is the name of the job detecting the containers and services running an outdated image
+	ImageUpdateJobName = "image_update"
+	// SessionRecordingCleanupJobName is the name of the job removing the expired session recordings
+	SessionRecordingCleanupJobName = "session_recording_cleanup"
+	// StackJobCleanupJobName is the name of the job removing the expired stack jobs
+	StackJobCleanupJobName = "stack_job_cleanup"
+	// TemplateSourcesRefreshJobName is the name of the job importing the templates of the template sources
+	TemplateSourcesRefreshJobName = "template_sources_refresh"
+)
+
+// SessionRecordingCleanupSchedule is the schedule of the job removing the expired session recordings.
+const SessionRecordingCleanupSchedule = "@every 1h"
+
+// StackJobCleanupSchedule is the schedule of the job removing the expired stack jobs.
+const StackJobCleanupSchedule = "@every 1h"
@@ -1,82 +1,257 @@
 package cron
 
 import (
+	"fmt"
 	"log"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/portainer/portainer"
 	"github.com/robfig/cron"
 )
 
-// JobScheduler represents a service for managing crons.
-type JobScheduler struct {
-	cron            *cron.Cron
-	endpointService portainer.EndpointService
-	snapshotter     portainer.Snapshotter
+type (
+	// JobScheduler represents a service running named jobs on a periodic basis.
+	// Each job is run by its own goroutine, jobs can be added, rescheduled and removed
+	// at any time without affecting the other jobs.
+	JobScheduler struct {
+		jobs    map[string]*scheduledJob
+		mutex   sync.Mutex
+		started bool
+	}
 
-	endpointFilePath     string
-	endpointSyncInterval string
-}
+	scheduledJob struct {
+		job      portainer.Job
+		spec     string
+		schedule cron.Schedule
+		// stop is closed to stop the goroutine running the job, it is nil when no goroutine is running the job
+		stop   chan struct{}
+		status portainer.JobStatus
+	}
+)
 
 // NewJobScheduler initializes a new service.
-func NewJobScheduler(endpointService portainer.EndpointService, snapshotter portainer.Snapshotter) *JobScheduler {
+func NewJobScheduler() *JobScheduler {
 	return &JobScheduler{
-		cron:            cron.New(),
-		endpointService: endpointService,
-		snapshotter:     snapshotter,
+		jobs: make(map[string]*scheduledJob),
 	}
 }
 
-// ScheduleEndpointSyncJob schedules a cron job to synchronize the endpoints from a file
-func (scheduler *JobScheduler) ScheduleEndpointSyncJob(endpointFilePath string, interval string) error {
+// ScheduleJob schedules a job under a name. A job already scheduled under the same name
+// is replaced, its status is kept.
+func (scheduler *JobScheduler) ScheduleJob(name, spec string, job portainer.Job) error {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return err
+	}
 
-	scheduler.endpointFilePath = endpointFilePath
-	scheduler.endpointSyncInterval = interval
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
 
-	job := newEndpointSyncJob(endpointFilePath, scheduler.endpointService)
+	entry, ok := scheduler.jobs[name]
+	if !ok {
+		entry = &scheduledJob{status: portainer.JobStatus{Name: name}}
+		scheduler.jobs[name] = entry
+	}
 
-	err := job.Sync()
+	entry.job = job
+	scheduler.reschedule(entry, spec, schedule)
+	return nil
+}
+
+// RescheduleJob updates the schedule of a job. The job is not run when rescheduled.
+func (scheduler *JobScheduler) RescheduleJob(name, spec string) error {
+	schedule, err := cron.ParseStandard(spec)
 	if err != nil {
 		return err
 	}
 
-	return scheduler.cron.AddJob("@every "+interval, job)
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	entry, ok := scheduler.jobs[name]
+	if !ok {
+		return portainer.ErrJobNotFound
+	}
+
+	if entry.spec != spec {
+		scheduler.reschedule(entry, spec, schedule)
+	}
+	return nil
+}
+
+// UnscheduleJob removes a job. A run of the job in progress is not interrupted.
+func (scheduler *JobScheduler) UnscheduleJob(name string) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	entry, ok := scheduler.jobs[name]
+	if !ok {
+		return
+	}
+
+	if entry.stop != nil {
+		close(entry.stop)
+	}
+	delete(scheduler.jobs, name)
 }
 
-// ScheduleSnapshotJob schedules a cron job to create endpoint snapshots
-func (scheduler *JobScheduler) ScheduleSnapshotJob(interval string) error {
-	job := newEndpointSnapshotJob(scheduler.endpointService, scheduler.snapshotter)
-	go job.Snapshot()
+// RunJob runs a job immediately in the background, outside of its schedule.
+func (scheduler *JobScheduler) RunJob(name string) error {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	entry, ok := scheduler.jobs[name]
+	if !ok {
+		return portainer.ErrJobNotFound
+	}
 
-	return scheduler.cron.AddJob("@every "+interval, job)
+	if entry.status.Running {
+		return portainer.ErrJobAlreadyRunning
+	}
+
+	entry.status.Running = true
+	go scheduler.run(entry)
+	return nil
 }
 
-// UpdateSnapshotJob will update the schedules to match the new snapshot interval
-func (scheduler *JobScheduler) UpdateSnapshotJob(interval string) {
-	// TODO: the cron library do not support removing/updating schedules.
-	// As a work-around we need to re-create the cron and reschedule the jobs.
-	// We should update the library.
-	jobs := scheduler.cron.Entries()
-	scheduler.cron.Stop()
-
-	scheduler.cron = cron.New()
-
-	for _, job := range jobs {
-		switch job.Job.(type) {
-		case endpointSnapshotJob:
-			scheduler.ScheduleSnapshotJob(interval)
-		case endpointSyncJob:
-			scheduler.ScheduleEndpointSyncJob(scheduler.endpointFilePath, scheduler.endpointSyncInterval)
-		default:
-			log.Println("Unsupported job")
-		}
+// JobStatus returns the status of a job.
+func (scheduler *JobScheduler) JobStatus(name string) (*portainer.JobStatus, error) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	entry, ok := scheduler.jobs[name]
+	if !ok {
+		return nil, portainer.ErrJobNotFound
+	}
+
+	status := entry.status
+	return &status, nil
+}
+
+// JobStatuses returns the status of all the jobs, sorted by name.
+func (scheduler *JobScheduler) JobStatuses() []portainer.JobStatus {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	statuses := make([]portainer.JobStatus, 0, len(scheduler.jobs))
+	for _, entry := range scheduler.jobs {
+		statuses = append(statuses, entry.status)
 	}
 
-	scheduler.cron.Start()
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
 }
 
-// Start starts the scheduled jobs
+// Start starts the scheduled jobs. Jobs scheduled afterwards are started right away.
 func (scheduler *JobScheduler) Start() {
-	if len(scheduler.cron.Entries()) > 0 {
-		scheduler.cron.Start()
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	scheduler.started = true
+	for _, entry := range scheduler.jobs {
+		if entry.stop == nil {
+			scheduler.startJob(entry)
+		}
 	}
 }
+
+// reschedule replaces the goroutine running a job. The caller must hold the mutex.
+func (scheduler *JobScheduler) reschedule(entry *scheduledJob, spec string, schedule cron.Schedule) {
+	if entry.stop != nil {
+		close(entry.stop)
+		entry.stop = nil
+	}
+
+	entry.spec = spec
+	entry.schedule = schedule
+	entry.status.Schedule = spec
+	entry.status.NextRun = 0
+
+	if scheduler.started {
+		scheduler.startJob(entry)
+	}
+}
+
+// startJob starts the goroutine running a job. The caller must hold the mutex.
+func (scheduler *JobScheduler) startJob(entry *scheduledJob) {
+	stop := make(chan struct{})
+	entry.stop = stop
+	go scheduler.loop(entry, entry.schedule, stop)
+}
+
+func (scheduler *JobScheduler) loop(entry *scheduledJob, schedule cron.Schedule, stop chan struct{}) {
+	for {
+		next := schedule.Next(time.Now())
+
+		scheduler.mutex.Lock()
+		select {
+		case <-stop:
+			scheduler.mutex.Unlock()
+			return
+		default:
+		}
+		entry.status.NextRun = next.Unix()
+		scheduler.mutex.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// A scheduled run is skipped when the previous run of the job is still in progress
+		scheduler.mutex.Lock()
+		running := entry.status.Running
+		entry.status.Running = true
+		scheduler.mutex.Unlock()
+
+		if running {
+			log.Printf("cron: previous run still in progress, skipping scheduled run (job=%s)\n", entry.status.Name)
+			continue
+		}
+
+		scheduler.run(entry)
+	}
+}
+
+// run runs a job marked as running and records the outcome of the run in its status.
+func (scheduler *JobScheduler) run(entry *scheduledJob) {
+	scheduler.mutex.Lock()
+	job := entry.job
+	name := entry.status.Name
+	scheduler.mutex.Unlock()
+
+	start := time.Now()
+	err := runWithRecovery(job)
+	duration := time.Since(start)
+
+	scheduler.mutex.Lock()
+	entry.status.Running = false
+	entry.status.LastRun = start.Unix()
+	entry.status.LastDuration = int64(duration / time.Millisecond)
+	entry.status.LastError = ""
+	if err != nil {
+		entry.status.LastError = err.Error()
+	}
+	scheduler.mutex.Unlock()
+
+	if err != nil {
+		log.Printf("cron error: job error (job=%s) (err=%s)\n", name, err)
+	}
+}
+
+func runWithRecovery(job portainer.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return job.Run()
+}
@@ -0,0 +1,83 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	// EncryptionKeyPemHeader represents the header that is appended to the PEM file when
+	// storing the encryption key.
+	EncryptionKeyPemHeader = "AES KEY"
+	encryptionKeySize      = 32
+)
+
+// AESService is a service used to encrypt sensitive data before storing it in the database.
+// It uses AES-256 in GCM mode, the nonce is stored in front of the encrypted data.
+type AESService struct {
+	aead cipher.AEAD
+}
+
+// NewAESService returns a pointer to an AESService using the specified key.
+func NewAESService(key []byte) (*AESService, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AESService{
+		aead: aead,
+	}, nil
+}
+
+// GenerateEncryptionKey generates a random key that can be used with an AESService.
+func GenerateEncryptionKey() ([]byte, error) {
+	key := make([]byte, encryptionKeySize)
+	_, err := io.ReadFull(rand.Reader, key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Encrypt encrypts data and returns the base64 encoding of the result.
+func (service *AESService) Encrypt(data string) (string, error) {
+	nonce := make([]byte, service.aead.NonceSize())
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	encrypted := service.aead.Seal(nonce, nonce, []byte(data), nil)
+	return base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// Decrypt decrypts data previously encrypted with Encrypt.
+func (service *AESService) Decrypt(data string) (string, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", err
+	}
+
+	nonceSize := service.aead.NonceSize()
+	if len(encrypted) < nonceSize {
+		return "", portainer.ErrInvalidEncryptedData
+	}
+
+	decrypted, err := service.aead.Open(nil, encrypted[:nonceSize], encrypted[nonceSize:], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(decrypted), nil
+}
@@ -0,0 +1,472 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/portainer/portainer"
+)
+
+// buildCacheAPIVersion is the version of the Docker API used to inspect and remove the build cache,
+// these operations are not available in the version of the API supported by Portainer.
+const buildCacheAPIVersion = "1.39"
+
+// stackLabels are the labels identifying the resources deployed as part of a compose or a Swarm stack.
+// These resources are never removed, a stopped stack keeps its containers and its volumes so that it can be started again.
+var stackLabels = []string{"com.docker.compose.project", "com.docker.stack.namespace"}
+
+type (
+	// EndpointCleaner represents a service used to remove the unused resources of the endpoints
+	// targeted by a cleanup policy.
+	EndpointCleaner struct {
+		clientFactory   *ClientFactory
+		endpointService portainer.EndpointService
+	}
+
+	cleanupTarget struct {
+		endpoint *portainer.Endpoint
+		nodeName string
+		err      error
+	}
+)
+
+// NewEndpointCleaner returns a new EndpointCleaner instance
+func NewEndpointCleaner(clientFactory *ClientFactory, endpointService portainer.EndpointService) *EndpointCleaner {
+	return &EndpointCleaner{
+		clientFactory:   clientFactory,
+		endpointService: endpointService,
+	}
+}
+
+// Cleanup removes the resources matching a cleanup policy on the endpoint of the policy or on each endpoint
+// of its endpoint group, and returns a report for each endpoint. The resources of an agent endpoint are removed
+// on every node of its cluster.
+// When dryRun is set, no resource is removed and the reports describe the resources that would be removed.
+// The space reclaimed by the removal of images is an estimation as images can share layers.
+func (cleaner *EndpointCleaner) Cleanup(policy *portainer.CleanupPolicy, dryRun bool) ([]portainer.CleanupReport, error) {
+	endpoints, err := cleaner.policyEndpoints(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]cleanupTarget, 0)
+	for idx := range endpoints {
+		targets = append(targets, cleaner.cleanupTargets(&endpoints[idx])...)
+	}
+
+	reports := make([]portainer.CleanupReport, len(targets))
+	var wg sync.WaitGroup
+	for idx := range targets {
+		wg.Add(1)
+		go func(target cleanupTarget, report *portainer.CleanupReport) {
+			defer wg.Done()
+			*report = cleaner.cleanup(policy, target, dryRun)
+		}(targets[idx], &reports[idx])
+	}
+	wg.Wait()
+
+	return reports, nil
+}
+
+func (cleaner *EndpointCleaner) policyEndpoints(policy *portainer.CleanupPolicy) ([]portainer.Endpoint, error) {
+	if policy.EndpointID != 0 {
+		endpoint, err := cleaner.endpointService.Endpoint(policy.EndpointID)
+		if err == portainer.ErrObjectNotFound {
+			return []portainer.Endpoint{}, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return []portainer.Endpoint{*endpoint}, nil
+	}
+
+	endpoints, err := cleaner.endpointService.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	groupEndpoints := make([]portainer.Endpoint, 0)
+	for _, endpoint := range endpoints {
+		if endpoint.GroupID == policy.EndpointGroupID && endpoint.Type != portainer.AzureEnvironment {
+			groupEndpoints = append(groupEndpoints, endpoint)
+		}
+	}
+	return groupEndpoints, nil
+}
+
+// cleanupTargets returns the targets of a cleanup on an endpoint. The failure to list the nodes of
+// an agent endpoint is reported through the target so that it is recorded in the report.
+func (cleaner *EndpointCleaner) cleanupTargets(endpoint *portainer.Endpoint) []cleanupTarget {
+	if endpoint.Type != portainer.AgentOnDockerEnvironment {
+		return []cleanupTarget{{endpoint: endpoint}}
+	}
+
+	cli, err := cleaner.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return []cleanupTarget{{endpoint: endpoint, err: err}}
+	}
+	defer cli.Close()
+
+	nodes, err := cli.NodeList(context.Background(), types.NodeListOptions{})
+	if err != nil {
+		return []cleanupTarget{{endpoint: endpoint, err: err}}
+	}
+
+	targets := make([]cleanupTarget, 0, len(nodes))
+	for _, node := range nodes {
+		targets = append(targets, cleanupTarget{endpoint: endpoint, nodeName: node.Description.Hostname})
+	}
+	return targets
+}
+
+// cleanup removes the resources matching a policy on a target. Stopped containers are removed first
+// so that the images, the volumes and the networks they were using can be removed during the same cleanup.
+func (cleaner *EndpointCleaner) cleanup(policy *portainer.CleanupPolicy, target cleanupTarget, dryRun bool) portainer.CleanupReport {
+	report := portainer.CleanupReport{
+		EndpointID: target.endpoint.ID,
+		NodeName:   target.nodeName,
+		Containers: []string{},
+		Images:     []string{},
+		Volumes:    []string{},
+		Networks:   []string{},
+		Errors:     []string{},
+	}
+
+	if target.err != nil {
+		report.Errors = append(report.Errors, target.err.Error())
+		return report
+	}
+
+	cli, err := cleaner.clientFactory.CreateStreamingClient(target.endpoint, target.nodeName)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+	defer cli.Close()
+
+	containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{All: true, Size: policy.Containers})
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+
+	if policy.Containers {
+		containers = removeStoppedContainers(cli, containers, dryRun, &report)
+	}
+
+	if policy.Images {
+		removeUnusedImages(cli, policy, containers, dryRun, &report)
+	}
+
+	var usage *types.DiskUsage
+	if policy.Volumes || (policy.BuildCache && dryRun) {
+		usage, err = cleaner.diskUsage(target)
+		if err != nil && policy.BuildCache && dryRun {
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+
+	if policy.Volumes {
+		removeUnusedVolumes(cli, policy, containers, usage, dryRun, &report)
+	}
+
+	if policy.Networks {
+		removeUnusedNetworks(cli, containers, dryRun, &report)
+	}
+
+	if policy.BuildCache {
+		if dryRun {
+			if usage != nil {
+				report.BuildCacheSize = unusedBuildCacheSize(usage)
+			}
+		} else {
+			report.BuildCacheSize, err = cleaner.pruneBuildCache(target)
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+			}
+		}
+		report.SpaceReclaimed += report.BuildCacheSize
+	}
+
+	return report
+}
+
+// removeStoppedContainers removes the stopped containers and returns the remaining containers.
+func removeStoppedContainers(cli *client.Client, containers []types.Container, dryRun bool, report *portainer.CleanupReport) []types.Container {
+	remaining := make([]types.Container, 0, len(containers))
+	for _, container := range containers {
+		if !isRemovableContainer(container) {
+			remaining = append(remaining, container)
+			continue
+		}
+
+		if !dryRun {
+			err := cli.ContainerRemove(context.Background(), container.ID, types.ContainerRemoveOptions{})
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				remaining = append(remaining, container)
+				continue
+			}
+		}
+
+		report.Containers = append(report.Containers, containerName(container))
+		report.SpaceReclaimed += container.SizeRw
+	}
+	return remaining
+}
+
+// removeUnusedImages removes the images created before the retention period of a policy that are not used
+// by any of the containers. Only dangling images are removed unless the policy targets all the unused images.
+func removeUnusedImages(cli *client.Client, policy *portainer.CleanupPolicy, containers []types.Container, dryRun bool, report *portainer.CleanupReport) {
+	images, err := cli.ImageList(context.Background(), types.ImageListOptions{})
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return
+	}
+
+	usedImages := containerImages(containers)
+	createdBefore := time.Now().AddDate(0, 0, -policy.ImageRetentionDays).Unix()
+
+	for _, image := range images {
+		if !isRemovableImage(policy, image, usedImages, createdBefore) {
+			continue
+		}
+
+		if !dryRun {
+			err := removeImage(cli, image)
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+		}
+
+		report.Images = append(report.Images, imageName(image))
+		report.SpaceReclaimed += image.Size
+	}
+}
+
+// removeImage removes an image without forcing the removal, as done by docker image prune.
+// An image referenced by several tags cannot be removed by identifier, each of its tags is removed instead.
+func removeImage(cli *client.Client, image types.ImageSummary) error {
+	references := []string{image.ID}
+	if !isDanglingImage(image) {
+		references = image.RepoTags
+	}
+
+	for _, reference := range references {
+		_, err := cli.ImageRemove(context.Background(), reference, types.ImageRemoveOptions{PruneChildren: true})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeUnusedVolumes removes the volumes that are not mounted by any of the containers, except the volumes
+// of the stacks and the volumes with the exclusion label of the policy. The size of the volumes is retrieved from the disk usage of the
+// Docker engine when available.
+func removeUnusedVolumes(cli *client.Client, policy *portainer.CleanupPolicy, containers []types.Container, usage *types.DiskUsage, dryRun bool, report *portainer.CleanupReport) {
+	volumes, err := cli.VolumeList(context.Background(), filters.Args{})
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return
+	}
+
+	usedVolumes := containerVolumes(containers)
+
+	volumeSizes := make(map[string]int64)
+	if usage != nil {
+		for _, volume := range usage.Volumes {
+			if volume.UsageData != nil && volume.UsageData.Size > 0 {
+				volumeSizes[volume.Name] = volume.UsageData.Size
+			}
+		}
+	}
+
+	for _, volume := range volumes.Volumes {
+		if !isRemovableVolume(policy, volume.Name, volume.Labels, usedVolumes) {
+			continue
+		}
+
+		if !dryRun {
+			err := cli.VolumeRemove(context.Background(), volume.Name, false)
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+		}
+
+		report.Volumes = append(report.Volumes, volume.Name)
+		report.SpaceReclaimed += volumeSizes[volume.Name]
+	}
+}
+
+// removeUnusedNetworks removes the networks that are not used by any of the containers. The predefined networks
+// and the swarm networks, which can be used by the services running on the other nodes, are never removed.
+func removeUnusedNetworks(cli *client.Client, containers []types.Container, dryRun bool, report *portainer.CleanupReport) {
+	networks, err := cli.NetworkList(context.Background(), types.NetworkListOptions{})
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return
+	}
+
+	usedNetworks := make(map[string]bool)
+	for _, container := range containers {
+		if container.NetworkSettings == nil {
+			continue
+		}
+		for _, endpointSettings := range container.NetworkSettings.Networks {
+			if endpointSettings != nil {
+				usedNetworks[endpointSettings.NetworkID] = true
+			}
+		}
+	}
+
+	for _, network := range networks {
+		if usedNetworks[network.ID] || network.Scope == "swarm" || isPredefinedNetwork(network.Name) || isStackResource(network.Labels) {
+			continue
+		}
+
+		if !dryRun {
+			err := cli.NetworkRemove(context.Background(), network.ID)
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+		}
+
+		report.Networks = append(report.Networks, network.Name)
+	}
+}
+
+func (cleaner *EndpointCleaner) diskUsage(target cleanupTarget) (*types.DiskUsage, error) {
+	cli, err := cleaner.clientFactory.CreateVersionedClient(target.endpoint, target.nodeName, buildCacheAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	usage, err := cli.DiskUsage(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+func (cleaner *EndpointCleaner) pruneBuildCache(target cleanupTarget) (int64, error) {
+	cli, err := cleaner.clientFactory.CreateVersionedClient(target.endpoint, target.nodeName, buildCacheAPIVersion)
+	if err != nil {
+		return 0, err
+	}
+	defer cli.Close()
+
+	pruneReport, err := cli.BuildCachePrune(context.Background(), types.BuildCachePruneOptions{All: true})
+	if err != nil {
+		return 0, err
+	}
+	return int64(pruneReport.SpaceReclaimed), nil
+}
+
+func unusedBuildCacheSize(usage *types.DiskUsage) int64 {
+	var size int64
+	for _, record := range usage.BuildCache {
+		if !record.InUse && !record.Shared {
+			size += record.Size
+		}
+	}
+	return size
+}
+
+// isRemovableContainer returns true when a container is stopped and is not part of a stack.
+func isRemovableContainer(container types.Container) bool {
+	if isStackResource(container.Labels) {
+		return false
+	}
+	return container.State == "exited" || container.State == "created" || container.State == "dead"
+}
+
+// isRemovableImage returns true when an image created before createdBefore is not used by any container.
+// Only dangling images are removable unless the policy targets all the unused images.
+func isRemovableImage(policy *portainer.CleanupPolicy, image types.ImageSummary, usedImages map[string]bool, createdBefore int64) bool {
+	if usedImages[image.ID] || image.Created > createdBefore {
+		return false
+	}
+	return policy.UnusedImages || isDanglingImage(image)
+}
+
+// isRemovableVolume returns true when a volume is not used by any container, is not part of a stack
+// and does not have the exclusion label of the policy.
+func isRemovableVolume(policy *portainer.CleanupPolicy, name string, labels map[string]string, usedVolumes map[string]bool) bool {
+	if usedVolumes[name] || isStackResource(labels) {
+		return false
+	}
+
+	if policy.ExcludedVolumeLabel != "" {
+		if _, ok := labels[policy.ExcludedVolumeLabel]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+func isStackResource(labels map[string]string) bool {
+	for _, label := range stackLabels {
+		if labels[label] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// containerImages returns the identifiers of the images used by the containers.
+func containerImages(containers []types.Container) map[string]bool {
+	images := make(map[string]bool)
+	for _, container := range containers {
+		images[container.ImageID] = true
+	}
+	return images
+}
+
+// containerVolumes returns the names of the volumes mounted by the containers.
+func containerVolumes(containers []types.Container) map[string]bool {
+	volumes := make(map[string]bool)
+	for _, container := range containers {
+		for _, mountPoint := range container.Mounts {
+			if mountPoint.Type == mount.TypeVolume {
+				volumes[mountPoint.Name] = true
+			}
+		}
+	}
+	return volumes
+}
+
+func isPredefinedNetwork(name string) bool {
+	switch name {
+	case "bridge", "host", "none", "docker_gwbridge", "nat":
+		return true
+	}
+	return false
+}
+
+func isDanglingImage(image types.ImageSummary) bool {
+	return len(image.RepoTags) == 0 || (len(image.RepoTags) == 1 && image.RepoTags[0] == "<none>:<none>")
+}
+
+func imageName(image types.ImageSummary) string {
+	if isDanglingImage(image) {
+		return image.ID
+	}
+	return image.RepoTags[0]
+}
+
+func containerName(container types.Container) string {
+	if len(container.Names) == 0 {
+		return container.ID
+	}
+	return strings.TrimPrefix(container.Names[0], "/")
+}
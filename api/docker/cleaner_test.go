@@ -0,0 +1,153 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/portainer/portainer"
+)
+
+func TestIsRemovableContainer(t *testing.T) {
+	tests := []struct {
+		name      string
+		container types.Container
+		expected  bool
+	}{
+		{name: "Running container", container: types.Container{State: "running"}, expected: false},
+		{name: "Paused container", container: types.Container{State: "paused"}, expected: false},
+		{name: "Exited container", container: types.Container{State: "exited"}, expected: true},
+		{name: "Created container", container: types.Container{State: "created"}, expected: true},
+		{name: "Dead container", container: types.Container{State: "dead"}, expected: true},
+		{
+			name:      "Container of a stopped compose stack",
+			container: types.Container{State: "exited", Labels: map[string]string{"com.docker.compose.project": "web"}},
+			expected:  false,
+		},
+		{
+			name:      "Container of a stopped swarm stack",
+			container: types.Container{State: "exited", Labels: map[string]string{"com.docker.stack.namespace": "web"}},
+			expected:  false,
+		},
+		{
+			name:      "Container with an empty stack label",
+			container: types.Container{State: "exited", Labels: map[string]string{"com.docker.compose.project": ""}},
+			expected:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := isRemovableContainer(test.container); result != test.expected {
+				t.Errorf("Unexpected result: got %t want %t", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestIsRemovableImage(t *testing.T) {
+	now := time.Now()
+	usedImages := map[string]bool{"sha256:used": true}
+
+	tests := []struct {
+		name     string
+		policy   portainer.CleanupPolicy
+		image    types.ImageSummary
+		expected bool
+	}{
+		{
+			name:     "Dangling image",
+			policy:   portainer.CleanupPolicy{},
+			image:    types.ImageSummary{ID: "sha256:a", Created: now.Unix()},
+			expected: true,
+		},
+		{
+			name:     "Untagged image",
+			policy:   portainer.CleanupPolicy{},
+			image:    types.ImageSummary{ID: "sha256:a", RepoTags: []string{"<none>:<none>"}, Created: now.Unix()},
+			expected: true,
+		},
+		{
+			name:     "Tagged image when only dangling images are removed",
+			policy:   portainer.CleanupPolicy{},
+			image:    types.ImageSummary{ID: "sha256:a", RepoTags: []string{"nginx:latest"}, Created: now.Unix()},
+			expected: false,
+		},
+		{
+			name:     "Tagged image when all the unused images are removed",
+			policy:   portainer.CleanupPolicy{UnusedImages: true},
+			image:    types.ImageSummary{ID: "sha256:a", RepoTags: []string{"nginx:latest"}, Created: now.Unix()},
+			expected: true,
+		},
+		{
+			name:     "Image used by a container",
+			policy:   portainer.CleanupPolicy{UnusedImages: true},
+			image:    types.ImageSummary{ID: "sha256:used", Created: now.AddDate(0, 0, -30).Unix()},
+			expected: false,
+		},
+		{
+			name:     "Image created within the retention period",
+			policy:   portainer.CleanupPolicy{UnusedImages: true, ImageRetentionDays: 7},
+			image:    types.ImageSummary{ID: "sha256:a", RepoTags: []string{"nginx:latest"}, Created: now.AddDate(0, 0, -3).Unix()},
+			expected: false,
+		},
+		{
+			name:     "Image created before the retention period",
+			policy:   portainer.CleanupPolicy{UnusedImages: true, ImageRetentionDays: 7},
+			image:    types.ImageSummary{ID: "sha256:a", RepoTags: []string{"nginx:latest"}, Created: now.AddDate(0, 0, -10).Unix()},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			createdBefore := now.AddDate(0, 0, -test.policy.ImageRetentionDays).Unix()
+			if result := isRemovableImage(&test.policy, test.image, usedImages, createdBefore); result != test.expected {
+				t.Errorf("Unexpected result: got %t want %t", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestIsRemovableVolume(t *testing.T) {
+	usedVolumes := map[string]bool{"used": true}
+
+	tests := []struct {
+		name       string
+		policy     portainer.CleanupPolicy
+		volumeName string
+		labels     map[string]string
+		expected   bool
+	}{
+		{name: "Unused volume", volumeName: "data", expected: true},
+		{name: "Volume mounted by a container", volumeName: "used", expected: false},
+		{
+			name:       "Volume of a stack",
+			volumeName: "web_data",
+			labels:     map[string]string{"com.docker.compose.project": "web"},
+			expected:   false,
+		},
+		{
+			name:       "Volume with the exclusion label",
+			policy:     portainer.CleanupPolicy{ExcludedVolumeLabel: "keep"},
+			volumeName: "data",
+			labels:     map[string]string{"keep": ""},
+			expected:   false,
+		},
+		{
+			name:       "Volume with another label",
+			policy:     portainer.CleanupPolicy{ExcludedVolumeLabel: "keep"},
+			volumeName: "data",
+			labels:     map[string]string{"backup": "true"},
+			expected:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := isRemovableVolume(&test.policy, test.volumeName, test.labels, usedVolumes); result != test.expected {
+				t.Errorf("Unexpected result: got %t want %t", result, test.expected)
+			}
+		})
+	}
+}
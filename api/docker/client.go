@@ -12,6 +12,7 @@ import (
 
 const (
 	unsupportedEnvironmentType = portainer.Error("Environment not supported")
+	clientTimeout              = 10 * time.Second
 )
 
 // ClientFactory is used to create Docker clients
@@ -27,42 +28,60 @@ func NewClientFactory(signatureService portainer.DigitalSignatureService) *Clien
 }
 
 // CreateClient is a generic function to create a Docker client based on
-// a specific endpoint configuration
-func (factory *ClientFactory) CreateClient(endpoint *portainer.Endpoint) (*client.Client, error) {
+// a specific endpoint configuration. The nodeName parameter can be used
+// with an agent enabled endpoint to target a specific node in an agent cluster.
+func (factory *ClientFactory) CreateClient(endpoint *portainer.Endpoint, nodeName string) (*client.Client, error) {
+	return factory.createClient(endpoint, nodeName, clientTimeout, portainer.SupportedDockerAPIVersion)
+}
+
+// CreateStreamingClient creates a Docker client that does not enforce any request timeout.
+// It must be used for long-lived requests such as container logs in follow mode.
+func (factory *ClientFactory) CreateStreamingClient(endpoint *portainer.Endpoint, nodeName string) (*client.Client, error) {
+	return factory.createClient(endpoint, nodeName, 0, portainer.SupportedDockerAPIVersion)
+}
+
+// CreateVersionedClient creates a Docker client that does not enforce any request timeout and
+// uses a specific version of the Docker API. It must only be used for the operations that are not
+// available in the version of the API supported by Portainer.
+func (factory *ClientFactory) CreateVersionedClient(endpoint *portainer.Endpoint, nodeName, version string) (*client.Client, error) {
+	return factory.createClient(endpoint, nodeName, 0, version)
+}
+
+func (factory *ClientFactory) createClient(endpoint *portainer.Endpoint, nodeName string, timeout time.Duration, version string) (*client.Client, error) {
 	if endpoint.Type == portainer.AzureEnvironment {
 		return nil, unsupportedEnvironmentType
 	} else if endpoint.Type == portainer.AgentOnDockerEnvironment {
-		return createAgentClient(endpoint, factory.signatureService)
+		return createAgentClient(endpoint, factory.signatureService, nodeName, timeout, version)
 	}
 
 	if strings.HasPrefix(endpoint.URL, "unix://") || strings.HasPrefix(endpoint.URL, "npipe://") {
-		return createLocalClient(endpoint)
+		return createLocalClient(endpoint, version)
 	}
-	return createTCPClient(endpoint)
+	return createTCPClient(endpoint, timeout, version)
 }
 
-func createLocalClient(endpoint *portainer.Endpoint) (*client.Client, error) {
+func createLocalClient(endpoint *portainer.Endpoint, version string) (*client.Client, error) {
 	return client.NewClientWithOpts(
 		client.WithHost(endpoint.URL),
-		client.WithVersion(portainer.SupportedDockerAPIVersion),
+		client.WithVersion(version),
 	)
 }
 
-func createTCPClient(endpoint *portainer.Endpoint) (*client.Client, error) {
-	httpCli, err := httpClient(endpoint)
+func createTCPClient(endpoint *portainer.Endpoint, timeout time.Duration, version string) (*client.Client, error) {
+	httpCli, err := httpClient(endpoint, timeout)
 	if err != nil {
 		return nil, err
 	}
 
 	return client.NewClientWithOpts(
 		client.WithHost(endpoint.URL),
-		client.WithVersion(portainer.SupportedDockerAPIVersion),
+		client.WithVersion(version),
 		client.WithHTTPClient(httpCli),
 	)
 }
 
-func createAgentClient(endpoint *portainer.Endpoint, signatureService portainer.DigitalSignatureService) (*client.Client, error) {
-	httpCli, err := httpClient(endpoint)
+func createAgentClient(endpoint *portainer.Endpoint, signatureService portainer.DigitalSignatureService, nodeName string, timeout time.Duration, version string) (*client.Client, error) {
+	httpCli, err := httpClient(endpoint, timeout)
 	if err != nil {
 		return nil, err
 	}
@@ -77,15 +96,19 @@ func createAgentClient(endpoint *portainer.Endpoint, signatureService portainer.
 		portainer.PortainerAgentSignatureHeader: signature,
 	}
 
+	if nodeName != "" {
+		headers[portainer.PortainerAgentTargetHeader] = nodeName
+	}
+
 	return client.NewClientWithOpts(
 		client.WithHost(endpoint.URL),
-		client.WithVersion(portainer.SupportedDockerAPIVersion),
+		client.WithVersion(version),
 		client.WithHTTPClient(httpCli),
 		client.WithHTTPHeaders(headers),
 	)
 }
 
-func httpClient(endpoint *portainer.Endpoint) (*http.Client, error) {
+func httpClient(endpoint *portainer.Endpoint, timeout time.Duration) (*http.Client, error) {
 	transport := &http.Transport{}
 
 	if endpoint.TLSConfig.TLS {
@@ -97,7 +120,7 @@ func httpClient(endpoint *portainer.Endpoint) (*http.Client, error) {
 	}
 
 	return &http.Client{
-		Timeout:   time.Second * 10,
+		Timeout:   timeout,
 		Transport: transport,
 	}, nil
 }
@@ -0,0 +1,323 @@
+package docker
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	registrytypes "github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/registry"
+)
+
+const (
+	errImageNotPulledFromRegistry = portainer.Error("No registry digest available for the local image")
+)
+
+type (
+	// ImageUpdateChecker represents a service used to detect containers and services running an outdated image.
+	// The last report computed for each endpoint is kept in memory.
+	ImageUpdateChecker struct {
+		clientFactory      *ClientFactory
+		registryService    portainer.RegistryService
+		dockerHubService   portainer.DockerHubService
+		credentialsService portainer.RegistryCredentialsService
+		manifestService    portainer.RegistryManifestService
+		reports            map[portainer.EndpointID]*portainer.ImageUpdateReport
+		mutex              sync.RWMutex
+	}
+
+	imageUpdateCheck struct {
+		checker        *ImageUpdateChecker
+		registryConfig *registrytypes.ServiceConfig
+		registries     []portainer.Registry
+		dockerHub      *portainer.DockerHub
+		remoteDigests  map[string]remoteDigest
+		localDigests   map[string][]string
+	}
+
+	remoteDigest struct {
+		digest string
+		err    error
+	}
+)
+
+// NewImageUpdateChecker returns a new ImageUpdateChecker instance
+func NewImageUpdateChecker(clientFactory *ClientFactory, registryService portainer.RegistryService, dockerHubService portainer.DockerHubService, credentialsService portainer.RegistryCredentialsService, manifestService portainer.RegistryManifestService) *ImageUpdateChecker {
+	return &ImageUpdateChecker{
+		clientFactory:      clientFactory,
+		registryService:    registryService,
+		dockerHubService:   dockerHubService,
+		credentialsService: credentialsService,
+		manifestService:    manifestService,
+		reports:            make(map[portainer.EndpointID]*portainer.ImageUpdateReport),
+	}
+}
+
+// EndpointReport returns the last image update report computed for an endpoint or nil
+// if the endpoint was not checked yet.
+func (checker *ImageUpdateChecker) EndpointReport(endpointID portainer.EndpointID) *portainer.ImageUpdateReport {
+	checker.mutex.RLock()
+	defer checker.mutex.RUnlock()
+	return checker.reports[endpointID]
+}
+
+// CheckEndpoint compares the digest of the images used by the containers and services of an endpoint
+// with the digest associated to the same tag in the registry and stores the resulting report.
+func (checker *ImageUpdateChecker) CheckEndpoint(endpoint *portainer.Endpoint) (*portainer.ImageUpdateReport, error) {
+	registries, err := checker.registryService.Registries()
+	if err != nil {
+		return nil, err
+	}
+
+	dockerHub, err := checker.dockerHubService.DockerHub()
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := checker.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	info, err := cli.Info(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	check := &imageUpdateCheck{
+		checker:        checker,
+		registryConfig: info.RegistryConfig,
+		registries:     registries,
+		dockerHub:      dockerHub,
+		remoteDigests:  make(map[string]remoteDigest),
+		localDigests:   make(map[string][]string),
+	}
+
+	report := &portainer.ImageUpdateReport{
+		Containers: []portainer.ImageUpdateStatus{},
+		Services:   []portainer.ImageUpdateStatus{},
+	}
+
+	err = check.checkContainers(cli, report)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Swarm.ControlAvailable {
+		err = check.checkServices(cli, report)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report.Time = time.Now().Unix()
+
+	checker.mutex.Lock()
+	checker.reports[endpoint.ID] = report
+	checker.mutex.Unlock()
+
+	return report, nil
+}
+
+func (check *imageUpdateCheck) checkContainers(cli *client.Client, report *portainer.ImageUpdateReport) error {
+	containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{All: true})
+	if err != nil {
+		return err
+	}
+
+	for _, container := range containers {
+		// Containers created from an image ID cannot be matched to a tag
+		if strings.HasPrefix(container.Image, "sha256:") {
+			continue
+		}
+
+		image, localDigests, err := check.imageLocalDigests(cli, container.Image, container.ImageID)
+		if err != nil {
+			return err
+		}
+
+		report.Containers = append(report.Containers, check.imageStatus(container.ID, image, localDigests))
+	}
+
+	return nil
+}
+
+func (check *imageUpdateCheck) checkServices(cli *client.Client, report *portainer.ImageUpdateReport) error {
+	services, err := cli.ServiceList(context.Background(), types.ServiceListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, service := range services {
+		containerSpec := service.Spec.TaskTemplate.ContainerSpec
+		if containerSpec == nil || containerSpec.Image == "" {
+			continue
+		}
+
+		image, localDigests := splitImageDigest(containerSpec.Image)
+		report.Services = append(report.Services, check.imageStatus(service.ID, image, localDigests))
+	}
+
+	return nil
+}
+
+func (check *imageUpdateCheck) imageStatus(resourceID, image string, localDigests []string) portainer.ImageUpdateStatus {
+	status := portainer.ImageUpdateStatus{
+		ID:    resourceID,
+		Image: image,
+	}
+
+	if len(localDigests) == 0 {
+		status.Error = errImageNotPulledFromRegistry.Error()
+		return status
+	}
+	status.LocalDigest = localDigests[0]
+
+	remote := check.remoteDigest(image)
+	if remote.err != nil {
+		status.Error = remote.err.Error()
+		return status
+	}
+	status.RemoteDigest = remote.digest
+
+	status.UpdateAvailable = true
+	for _, digest := range localDigests {
+		if digest == remote.digest {
+			status.LocalDigest = digest
+			status.UpdateAvailable = false
+			break
+		}
+	}
+
+	return status
+}
+
+// imageLocalDigests returns the digests recorded by the engine for the repository of the image when it was pulled.
+// Images pinned to a digest are resolved without inspecting the local image.
+func (check *imageUpdateCheck) imageLocalDigests(cli *client.Client, image, imageID string) (string, []string, error) {
+	if strings.Contains(image, "@") {
+		name, digests := splitImageDigest(image)
+		return name, digests, nil
+	}
+
+	repoDigests, ok := check.localDigests[imageID]
+	if !ok {
+		imageInspect, _, err := cli.ImageInspectWithRaw(context.Background(), imageID)
+		if err != nil && client.IsErrNotFound(err) {
+			return image, nil, nil
+		} else if err != nil {
+			return "", nil, err
+		}
+		repoDigests = imageInspect.RepoDigests
+		check.localDigests[imageID] = repoDigests
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image, nil, nil
+	}
+
+	digests := make([]string, 0)
+	for _, repoDigest := range repoDigests {
+		canonical, err := reference.ParseNormalizedNamed(repoDigest)
+		if err != nil {
+			continue
+		}
+
+		if digested, ok := canonical.(reference.Digested); ok && canonical.Name() == named.Name() {
+			digests = append(digests, digested.Digest().String())
+		}
+	}
+
+	return image, digests, nil
+}
+
+func (check *imageUpdateCheck) remoteDigest(image string) remoteDigest {
+	if remote, ok := check.remoteDigests[image]; ok {
+		return remote
+	}
+
+	var remote remoteDigest
+	credentials, err := check.imageCredentials(image)
+	if err != nil {
+		remote.err = err
+	} else {
+		remote.digest, remote.err = check.checker.manifestService.ImageDigest(image, credentials, isInsecureRegistry(check.registryConfig, image))
+	}
+
+	check.remoteDigests[image] = remote
+	return remote
+}
+
+func (check *imageUpdateCheck) imageCredentials(image string) (*portainer.RegistryCredentials, error) {
+	matchingRegistry, err := registry.MatchImageRegistry(image, check.registries)
+	if err != nil {
+		return nil, err
+	}
+
+	if matchingRegistry != nil {
+		return check.checker.credentialsService.Credentials(matchingRegistry)
+	}
+
+	if registry.IsDockerHubImage(image) && check.dockerHub.Authentication {
+		return &portainer.RegistryCredentials{
+			Username: check.dockerHub.Username,
+			Password: check.dockerHub.Password,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// isInsecureRegistry returns true when the Docker engine is configured to use the registry of the image
+// as an insecure registry, either explicitly or through one of the insecure registry CIDRs.
+func isInsecureRegistry(config *registrytypes.ServiceConfig, image string) bool {
+	if config == nil {
+		return false
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return false
+	}
+
+	domain := reference.Domain(named)
+	if index, ok := config.IndexConfigs[domain]; ok {
+		return !index.Secure
+	}
+
+	host := domain
+	if hostname, _, err := net.SplitHostPort(domain); err == nil {
+		host = hostname
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, cidr := range config.InsecureRegistryCIDRs {
+		if (*net.IPNet)(cidr).Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitImageDigest splits an image reference such as nginx:latest@sha256:... into
+// the tagged reference and its digest.
+func splitImageDigest(image string) (string, []string) {
+	parts := strings.SplitN(image, "@", 2)
+	if len(parts) != 2 {
+		return image, nil
+	}
+	return parts[0], []string{parts[1]}
+}
@@ -0,0 +1,337 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/archive"
+)
+
+const (
+	// defaultScriptImage is the image used to run the scripts of the schedules that do not specify an image.
+	defaultScriptImage = "alpine:latest"
+	// scriptContainerFolder is the folder of the job container where the script of a schedule is copied.
+	scriptContainerFolder = "/tmp"
+	scriptContainerFile   = "portainer_schedule_script.sh"
+	// hostMountPath is the path where the filesystem of the host is mounted inside the job container of a script.
+	hostMountPath = "/host"
+	// maxExecutionOutputSize is the maximum size of the output kept for an execution. Only the end of larger outputs is kept.
+	maxExecutionOutputSize = 64 * 1024
+	// maxScheduleExecutions is the number of executions kept in the history of a schedule.
+	maxScheduleExecutions = 50
+	// jobTimeout is the maximum duration of a job, the job container is removed once it expires.
+	jobTimeout = 1 * time.Hour
+)
+
+type (
+	// ScheduleRunner represents a service used to run the job of a schedule inside a container
+	// created on each of the endpoints of the schedule.
+	ScheduleRunner struct {
+		clientFactory    *ClientFactory
+		endpointService  portainer.EndpointService
+		executionService portainer.ScheduleExecutionService
+		fileService      portainer.FileService
+	}
+
+	scheduleTarget struct {
+		endpoint *portainer.Endpoint
+		nodeName string
+		err      error
+	}
+
+	imagePullMessage struct {
+		Error string `json:"error"`
+	}
+)
+
+// NewScheduleRunner returns a new ScheduleRunner instance
+func NewScheduleRunner(clientFactory *ClientFactory, endpointService portainer.EndpointService, executionService portainer.ScheduleExecutionService, fileService portainer.FileService) *ScheduleRunner {
+	return &ScheduleRunner{
+		clientFactory:    clientFactory,
+		endpointService:  endpointService,
+		executionService: executionService,
+		fileService:      fileService,
+	}
+}
+
+// Run runs the job of a schedule on each of its endpoints and returns the executions once they are all completed.
+// When the AllNodes option of the schedule is set, the job is run on every node of the cluster of an agent endpoint.
+// Each execution is persisted when it starts and updated when it completes, only the last executions of
+// the schedule are kept.
+func (runner *ScheduleRunner) Run(schedule *portainer.Schedule) ([]portainer.ScheduleExecution, error) {
+	var script []byte
+	if schedule.JobType == portainer.ScriptScheduleJob {
+		content, err := runner.fileService.GetFileContent(schedule.ScriptPath)
+		if err != nil {
+			return nil, err
+		}
+		script = content
+	}
+
+	targets := make([]scheduleTarget, 0)
+	for _, endpointID := range schedule.Endpoints {
+		endpoint, err := runner.endpointService.Endpoint(endpointID)
+		if err == portainer.ErrObjectNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		targets = append(targets, runner.scheduleTargets(schedule, endpoint)...)
+	}
+
+	executions := make([]portainer.ScheduleExecution, len(targets))
+	for idx, target := range targets {
+		executions[idx] = portainer.ScheduleExecution{
+			ScheduleID: schedule.ID,
+			EndpointID: target.endpoint.ID,
+			NodeName:   target.nodeName,
+			Status:     portainer.ScheduleExecutionRunning,
+			StartedAt:  time.Now().Unix(),
+		}
+
+		err := runner.executionService.CreateScheduleExecution(&executions[idx])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var wg sync.WaitGroup
+	for idx := range targets {
+		wg.Add(1)
+		go func(target scheduleTarget, execution *portainer.ScheduleExecution) {
+			defer wg.Done()
+			runner.execute(schedule, script, target, execution)
+		}(targets[idx], &executions[idx])
+	}
+	wg.Wait()
+
+	return executions, runner.pruneExecutions(schedule.ID)
+}
+
+// scheduleTargets returns the targets of a schedule on an endpoint. The failure to list the nodes of
+// an agent endpoint is reported through the target so that it is recorded in the execution history.
+func (runner *ScheduleRunner) scheduleTargets(schedule *portainer.Schedule, endpoint *portainer.Endpoint) []scheduleTarget {
+	if !schedule.AllNodes || endpoint.Type != portainer.AgentOnDockerEnvironment {
+		return []scheduleTarget{{endpoint: endpoint}}
+	}
+
+	cli, err := runner.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return []scheduleTarget{{endpoint: endpoint, err: err}}
+	}
+	defer cli.Close()
+
+	nodes, err := cli.NodeList(context.Background(), types.NodeListOptions{})
+	if err != nil {
+		return []scheduleTarget{{endpoint: endpoint, err: err}}
+	}
+
+	targets := make([]scheduleTarget, 0, len(nodes))
+	for _, node := range nodes {
+		targets = append(targets, scheduleTarget{endpoint: endpoint, nodeName: node.Description.Hostname})
+	}
+	return targets
+}
+
+func (runner *ScheduleRunner) execute(schedule *portainer.Schedule, script []byte, target scheduleTarget, execution *portainer.ScheduleExecution) {
+	err := target.err
+	if err == nil {
+		var output []byte
+		output, execution.ExitCode, err = runner.runJob(schedule, script, target)
+		if len(output) > maxExecutionOutputSize {
+			output = output[len(output)-maxExecutionOutputSize:]
+		}
+		execution.Output = string(output)
+	}
+
+	execution.Status = portainer.ScheduleExecutionSucceeded
+	if err != nil {
+		execution.Status = portainer.ScheduleExecutionFailed
+		execution.Error = err.Error()
+	} else if execution.ExitCode != 0 {
+		execution.Status = portainer.ScheduleExecutionFailed
+	}
+	execution.FinishedAt = time.Now().Unix()
+
+	err = runner.executionService.UpdateScheduleExecution(execution.ID, execution)
+	if err != nil {
+		log.Printf("docker error: unable to persist schedule execution (schedule=%d) (err=%s)\n", schedule.ID, err)
+	}
+}
+
+// runJob creates the job container, waits for it to exit and returns its output and its exit code.
+// The container is removed once completed or when it is still running after the job timeout, the output
+// written before the timeout is returned with the error.
+func (runner *ScheduleRunner) runJob(schedule *portainer.Schedule, script []byte, target scheduleTarget) ([]byte, int, error) {
+	cli, err := runner.clientFactory.CreateStreamingClient(target.endpoint, target.nodeName)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cli.Close()
+
+	config, hostConfig := jobContainerConfiguration(schedule)
+
+	err = ensureImage(cli, config.Image)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ctx := context.Background()
+
+	createResponse, err := cli.ContainerCreate(ctx, config, hostConfig, nil, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	defer removeJobContainer(cli, createResponse.ID)
+
+	if schedule.JobType == portainer.ScriptScheduleJob {
+		tarContent, err := archive.TarFileInBuffer(script, scriptContainerFile)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		err = cli.CopyToContainer(ctx, createResponse.ID, scriptContainerFolder, bytes.NewReader(tarContent), types.CopyToContainerOptions{})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	err = cli.ContainerStart(ctx, createResponse.ID, types.ContainerStartOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	exitCode, waitErr := waitForJobContainer(cli, createResponse.ID, jobTimeout)
+	if waitErr != nil && waitErr != portainer.ErrScheduleJobTimeout {
+		return nil, 0, waitErr
+	}
+
+	logs, err := cli.ContainerLogs(ctx, createResponse.ID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return nil, exitCode, err
+	}
+	defer logs.Close()
+
+	var output bytes.Buffer
+	_, err = stdcopy.StdCopy(&output, &output, logs)
+	if err != nil {
+		return nil, exitCode, err
+	}
+
+	return output.Bytes(), exitCode, waitErr
+}
+
+// waitForJobContainer waits for the job container to exit and returns its exit code.
+// ErrScheduleJobTimeout is returned when the container is still running after the timeout.
+func waitForJobContainer(cli *client.Client, containerID string, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	statusCh, errCh := cli.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		if ctx.Err() == context.DeadlineExceeded {
+			return 0, portainer.ErrScheduleJobTimeout
+		}
+		return 0, err
+	case status := <-statusCh:
+		return int(status.StatusCode), nil
+	}
+}
+
+// jobContainerConfiguration returns the configuration of the container running the job of a schedule.
+// The container of a script is privileged and shares the PID and network namespaces of the host, the script
+// is run chrooted in the filesystem of the host. The command of a schedule is run by the shell of the image
+// so that quoting, pipes and variables are interpreted.
+func jobContainerConfiguration(schedule *portainer.Schedule) (*container.Config, *container.HostConfig) {
+	config := &container.Config{
+		Image: schedule.Image,
+	}
+	hostConfig := &container.HostConfig{}
+
+	if schedule.JobType == portainer.ScriptScheduleJob {
+		if config.Image == "" {
+			config.Image = defaultScriptImage
+		}
+
+		scriptPath := path.Join(scriptContainerFolder, scriptContainerFile)
+		config.Cmd = []string{"sh", "-c", "chroot " + hostMountPath + " sh -s < " + scriptPath}
+
+		hostConfig.Privileged = true
+		hostConfig.PidMode = "host"
+		hostConfig.NetworkMode = "host"
+		hostConfig.Binds = []string{"/:" + hostMountPath}
+	} else if schedule.Command != "" {
+		config.Cmd = []string{"sh", "-c", schedule.Command}
+	}
+
+	return config, hostConfig
+}
+
+// ensureImage pulls the image when it is not available on the Docker engine.
+func ensureImage(cli *client.Client, image string) error {
+	_, _, err := cli.ImageInspectWithRaw(context.Background(), image)
+	if err == nil {
+		return nil
+	} else if !client.IsErrNotFound(err) {
+		return err
+	}
+
+	reader, err := cli.ImagePull(context.Background(), image, types.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	decoder := json.NewDecoder(reader)
+	for {
+		var message imagePullMessage
+		err := decoder.Decode(&message)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if message.Error != "" {
+			return errors.New(message.Error)
+		}
+	}
+}
+
+func removeJobContainer(cli *client.Client, containerID string) {
+	err := cli.ContainerRemove(context.Background(), containerID, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
+	if err != nil {
+		log.Printf("docker error: unable to remove schedule job container (container=%s) (err=%s)\n", containerID, err)
+	}
+}
+
+// pruneExecutions removes the oldest executions of a schedule to keep the size of its history under maxScheduleExecutions.
+func (runner *ScheduleRunner) pruneExecutions(scheduleID portainer.ScheduleID) error {
+	executions, err := runner.executionService.ScheduleExecutionsBySchedule(scheduleID)
+	if err != nil {
+		return err
+	}
+
+	for idx := 0; idx < len(executions)-maxScheduleExecutions; idx++ {
+		err = runner.executionService.DeleteScheduleExecution(executions[idx].ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
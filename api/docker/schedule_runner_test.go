@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/portainer/portainer"
+)
+
+func TestJobContainerConfiguration(t *testing.T) {
+	tests := []struct {
+		name               string
+		schedule           portainer.Schedule
+		expectedImage      string
+		expectedCmd        []string
+		expectedPrivileged bool
+		expectedBinds      []string
+	}{
+		{
+			name:          "Image without command",
+			schedule:      portainer.Schedule{JobType: portainer.ContainerScheduleJob, Image: "busybox:latest"},
+			expectedImage: "busybox:latest",
+		},
+		{
+			name:          "Command is run by the shell",
+			schedule:      portainer.Schedule{JobType: portainer.ContainerScheduleJob, Image: "busybox:latest", Command: "echo hello"},
+			expectedImage: "busybox:latest",
+			expectedCmd:   []string{"sh", "-c", "echo hello"},
+		},
+		{
+			name:          "Quoted arguments and pipes are kept",
+			schedule:      portainer.Schedule{JobType: portainer.ContainerScheduleJob, Image: "alpine", Command: `echo "a  b" | wc -c && echo $HOME`},
+			expectedImage: "alpine",
+			expectedCmd:   []string{"sh", "-c", `echo "a  b" | wc -c && echo $HOME`},
+		},
+		{
+			name:               "Script with the default image",
+			schedule:           portainer.Schedule{JobType: portainer.ScriptScheduleJob, Command: "ignored"},
+			expectedImage:      defaultScriptImage,
+			expectedCmd:        []string{"sh", "-c", "chroot /host sh -s < /tmp/portainer_schedule_script.sh"},
+			expectedPrivileged: true,
+			expectedBinds:      []string{"/:/host"},
+		},
+		{
+			name:               "Script with a custom image",
+			schedule:           portainer.Schedule{JobType: portainer.ScriptScheduleJob, Image: "debian:stable"},
+			expectedImage:      "debian:stable",
+			expectedCmd:        []string{"sh", "-c", "chroot /host sh -s < /tmp/portainer_schedule_script.sh"},
+			expectedPrivileged: true,
+			expectedBinds:      []string{"/:/host"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, hostConfig := jobContainerConfiguration(&test.schedule)
+
+			if config.Image != test.expectedImage {
+				t.Errorf("Unexpected image: got %s want %s", config.Image, test.expectedImage)
+			}
+			if len(config.Cmd) != 0 || len(test.expectedCmd) != 0 {
+				if !reflect.DeepEqual([]string(config.Cmd), test.expectedCmd) {
+					t.Errorf("Unexpected command: got %q want %q", config.Cmd, test.expectedCmd)
+				}
+			}
+			if hostConfig.Privileged != test.expectedPrivileged {
+				t.Errorf("Unexpected privileged mode: got %t want %t", hostConfig.Privileged, test.expectedPrivileged)
+			}
+			if !reflect.DeepEqual(hostConfig.Binds, test.expectedBinds) {
+				t.Errorf("Unexpected binds: got %q want %q", hostConfig.Binds, test.expectedBinds)
+			}
+		})
+	}
+}
@@ -35,7 +35,8 @@ const (
 
 // Endpoint errors.
 const (
-	ErrEndpointAccessDenied = Error("Access denied to endpoint")
+	ErrEndpointAccessDenied     = Error("Access denied to endpoint")
+	ErrEndpointTypeNotSupported = Error("Operation not supported on this endpoint type")
 )
 
 // Azure environment errors
@@ -50,7 +51,16 @@ const (
 
 // Registry errors.
 const (
-	ErrRegistryAlreadyExists = Error("A registry is already defined for this URL")
+	ErrRegistryAlreadyExists        = Error("A registry is already defined for this URL")
+	ErrRegistryAccessDenied         = Error("No authorized registry found for host")
+	ErrRegistryInvalidCredentials   = Error("Invalid registry credentials")
+	ErrRegistryTypeNotSupported     = Error("Unsupported registry type")
+	ErrRegistryTokenExchangeFailure = Error("Unable to exchange registry credentials for an access token")
+	ErrRegistryAPINotSupported      = Error("The registry does not support the Docker registry v2 API")
+	ErrRegistryAuthRequired         = Error("The registry requires authentication")
+	ErrRegistryUnsupportedChallenge = Error("Unsupported registry authentication challenge")
+	ErrRegistryManifestNotFound     = Error("Unable to find the image manifest in the registry")
+	ErrImageReferenceNotTagged      = Error("The image reference does not contain a tag")
 )
 
 // Stack errors
@@ -58,6 +68,40 @@ const (
 	ErrStackAlreadyExists              = Error("A stack already exists with this name")
 	ErrComposeFileNotFoundInRepository = Error("Unable to find a Compose file in the repository")
 	ErrStackNotExternal                = Error("Not an external stack")
+	ErrStackTypeMismatch               = Error("The endpoint does not support this type of stack")
+	ErrExternalStackNotFound           = Error("Unable to find a running stack with this name on the endpoint")
+	ErrStackAlreadyActive              = Error("The stack is already running")
+	ErrStackAlreadyInactive            = Error("The stack is already stopped")
+	ErrStackNotConverged               = Error("The services of the stack did not reach their desired state")
+	ErrInvalidComposeProjectName       = Error("The name of a Compose stack must only contain lowercase letters and digits")
+)
+
+// Environment set errors
+const (
+	ErrEnvironmentSetAlreadyExists = Error("An environment set already exists with this name")
+	ErrEnvironmentSetInUse         = Error("The environment set is referenced by at least one stack")
+	ErrEnvironmentSetNotApplicable = Error("The environment set cannot be used on the endpoint of the stack")
+	ErrEnvironmentSetSecretAccess  = Error("Only administrators can use an environment set containing secret variables")
+	ErrEnvironmentSetMaskedValue   = Error("The secret value mask can only be used as the value of an existing secret variable")
+)
+
+// Job scheduler errors
+const (
+	ErrJobNotFound       = Error("Unable to find a scheduled job with this name")
+	ErrJobAlreadyRunning = Error("The job is already running")
+)
+
+// Schedule errors
+const (
+	ErrScheduleNotScript  = Error("The job of the schedule does not run a script")
+	ErrScheduleJobTimeout = Error("The job of the schedule did not complete in time")
+)
+
+// Template errors
+const (
+	ErrTemplateSourceTypeNotSupported = Error("Unsupported template source type")
+	ErrInvalidTemplateSourceFilePath  = Error("Invalid templates file path")
+	ErrTemplateSourceDuplicateTitle   = Error("Several templates of the same type share the same title")
 )
 
 // Tag errors
@@ -71,9 +115,17 @@ const (
 	ErrEndpointExtensionAlreadyAssociated = Error("This extension is already associated to the endpoint")
 )
 
+// Console session errors.
+const (
+	ErrConsoleSessionLimitReached = Error("Maximum number of concurrent console sessions reached")
+	ErrConsoleSessionTerminated   = Error("Console session terminated by an administrator")
+	ErrConsoleSessionIdleTimeout  = Error("Console session closed after reaching the idle timeout")
+)
+
 // Crypto errors.
 const (
-	ErrCryptoHashFailure = Error("Unable to hash data")
+	ErrCryptoHashFailure    = Error("Unable to hash data")
+	ErrInvalidEncryptedData = Error("Invalid encrypted data")
 )
 
 // JWT errors.
@@ -88,6 +140,11 @@ const (
 	ErrUndefinedTLSFileType = Error("Undefined TLS file type")
 )
 
+// Git errors.
+const (
+	ErrUnsupportedRepositoryURL = Error("Unsupported repository URL. Only http, https and ssh URLs are supported")
+)
+
 // Error represents an application error.
 type Error string
 
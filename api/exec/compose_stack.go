@@ -0,0 +1,80 @@
+package exec
+
+import (
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/portainer/portainer"
+)
+
+var projectNameRe = regexp.MustCompile("[^a-z0-9]+")
+
+// ComposeStackManager represents a service for managing Compose stacks using the compose subcommand
+// of the Docker CLI. Stacks deployed on endpoints that are not configured to use the Docker CLI are
+// managed by the legacy stack manager.
+type ComposeStackManager struct {
+	binaryPath    string
+	dataPath      string
+	legacyManager portainer.ComposeStackManager
+}
+
+// NewComposeStackManager initializes a new ComposeStackManager service.
+func NewComposeStackManager(binaryPath, dataPath string, legacyManager portainer.ComposeStackManager) *ComposeStackManager {
+	return &ComposeStackManager{
+		binaryPath:    binaryPath,
+		dataPath:      dataPath,
+		legacyManager: legacyManager,
+	}
+}
+
+// Up executes the docker compose up command.
+// The output of the command is copied to the output writer when specified.
+func (manager *ComposeStackManager) Up(stack *portainer.Stack, endpoint *portainer.Endpoint, output io.Writer) error {
+	if endpoint.ComposeStackEngine != portainer.DockerComposeEngine {
+		return manager.legacyManager.Up(stack, endpoint, output)
+	}
+
+	command, args := manager.prepareComposeCommandAndArgs(stack, endpoint)
+	args = append(args, "up", "--detach", "--remove-orphans")
+	return runCommandAndCaptureStdErr(command, args, stackEnv(stack), stack.ProjectPath, output)
+}
+
+// Down executes the docker compose down command.
+// The output of the command is copied to the output writer when specified.
+func (manager *ComposeStackManager) Down(stack *portainer.Stack, endpoint *portainer.Endpoint, output io.Writer) error {
+	if endpoint.ComposeStackEngine != portainer.DockerComposeEngine {
+		return manager.legacyManager.Down(stack, endpoint, output)
+	}
+
+	command, args := manager.prepareComposeCommandAndArgs(stack, endpoint)
+	args = append(args, "down", "--remove-orphans")
+	return runCommandAndCaptureStdErr(command, args, stackEnv(stack), stack.ProjectPath, output)
+}
+
+// prepareComposeCommandAndArgs returns the arguments shared by the docker compose commands. The project
+// name is normalized the same way libcompose does so that stacks keep their containers when the
+// deployment tool of an endpoint is changed. Registry credentials are read from the configuration
+// file of the Docker CLI.
+func (manager *ComposeStackManager) prepareComposeCommandAndArgs(stack *portainer.Stack, endpoint *portainer.Endpoint) (string, []string) {
+	command, args := prepareDockerCommandAndArgs(manager.binaryPath, manager.dataPath, endpoint)
+
+	projectName := projectNameRe.ReplaceAllString(strings.ToLower(stack.Name), "")
+	args = append(args, "compose", "--project-name", projectName, "--project-directory", stack.ProjectPath)
+
+	args = append(args, "--file", path.Join(stack.ProjectPath, stack.EntryPoint))
+	for _, file := range stack.AdditionalFiles {
+		args = append(args, "--file", path.Join(stack.ProjectPath, file))
+	}
+
+	return command, args
+}
+
+func stackEnv(stack *portainer.Stack) []string {
+	env := make([]string, 0)
+	for _, envvar := range stack.Env {
+		env = append(env, envvar.Name+"="+envvar.Value)
+	}
+	return env
+}
@@ -3,6 +3,8 @@ package exec
 import (
 	"bytes"
 	"encoding/json"
+	"io"
+	"log"
 	"os"
 	"os/exec"
 	"path"
@@ -13,20 +15,22 @@ import (
 
 // SwarmStackManager represents a service for managing stacks.
 type SwarmStackManager struct {
-	binaryPath       string
-	dataPath         string
-	signatureService portainer.DigitalSignatureService
-	fileService      portainer.FileService
+	binaryPath         string
+	dataPath           string
+	signatureService   portainer.DigitalSignatureService
+	fileService        portainer.FileService
+	credentialsService portainer.RegistryCredentialsService
 }
 
 // NewSwarmStackManager initializes a new SwarmStackManager service.
 // It also updates the configuration of the Docker CLI binary.
-func NewSwarmStackManager(binaryPath, dataPath string, signatureService portainer.DigitalSignatureService, fileService portainer.FileService) (*SwarmStackManager, error) {
+func NewSwarmStackManager(binaryPath, dataPath string, signatureService portainer.DigitalSignatureService, fileService portainer.FileService, credentialsService portainer.RegistryCredentialsService) (*SwarmStackManager, error) {
 	manager := &SwarmStackManager{
-		binaryPath:       binaryPath,
-		dataPath:         dataPath,
-		signatureService: signatureService,
-		fileService:      fileService,
+		binaryPath:         binaryPath,
+		dataPath:           dataPath,
+		signatureService:   signatureService,
+		fileService:        fileService,
+		credentialsService: credentialsService,
 	}
 
 	err := manager.updateDockerCLIConfiguration(dataPath)
@@ -38,18 +42,26 @@ func NewSwarmStackManager(binaryPath, dataPath string, signatureService portaine
 }
 
 // Login executes the docker login command against a list of registries (including DockerHub).
+// Registry credentials are resolved through the credentials service so that token-based
+// registries are logged in with a valid token.
 func (manager *SwarmStackManager) Login(dockerhub *portainer.DockerHub, registries []portainer.Registry, endpoint *portainer.Endpoint) {
 	command, args := prepareDockerCommandAndArgs(manager.binaryPath, manager.dataPath, endpoint)
 	for _, registry := range registries {
 		if registry.Authentication {
-			registryArgs := append(args, "login", "--username", registry.Username, "--password", registry.Password, registry.URL)
-			runCommandAndCaptureStdErr(command, registryArgs, nil, "")
+			credentials, err := manager.credentialsService.Credentials(&registry)
+			if err != nil {
+				log.Printf("stack manager error: unable to retrieve registry credentials (registry=%s) (err=%s)\n", registry.Name, err)
+				continue
+			}
+
+			registryArgs := append(args, "login", "--username", credentials.Username, "--password", credentials.Password, registry.URL)
+			runCommandAndCaptureStdErr(command, registryArgs, nil, "", nil)
 		}
 	}
 
 	if dockerhub.Authentication {
 		dockerhubArgs := append(args, "login", "--username", dockerhub.Username, "--password", dockerhub.Password)
-		runCommandAndCaptureStdErr(command, dockerhubArgs, nil, "")
+		runCommandAndCaptureStdErr(command, dockerhubArgs, nil, "", nil)
 	}
 }
 
@@ -57,42 +69,49 @@ func (manager *SwarmStackManager) Login(dockerhub *portainer.DockerHub, registri
 func (manager *SwarmStackManager) Logout(endpoint *portainer.Endpoint) error {
 	command, args := prepareDockerCommandAndArgs(manager.binaryPath, manager.dataPath, endpoint)
 	args = append(args, "logout")
-	return runCommandAndCaptureStdErr(command, args, nil, "")
+	return runCommandAndCaptureStdErr(command, args, nil, "", nil)
 }
 
 // Deploy executes the docker stack deploy command.
-func (manager *SwarmStackManager) Deploy(stack *portainer.Stack, prune bool, endpoint *portainer.Endpoint) error {
+// The output of the command is copied to the output writer when specified.
+func (manager *SwarmStackManager) Deploy(stack *portainer.Stack, prune bool, endpoint *portainer.Endpoint, output io.Writer) error {
 	stackFilePath := path.Join(stack.ProjectPath, stack.EntryPoint)
 	command, args := prepareDockerCommandAndArgs(manager.binaryPath, manager.dataPath, endpoint)
 
+	args = append(args, "stack", "deploy", "--with-registry-auth")
 	if prune {
-		args = append(args, "stack", "deploy", "--prune", "--with-registry-auth", "--compose-file", stackFilePath, stack.Name)
-	} else {
-		args = append(args, "stack", "deploy", "--with-registry-auth", "--compose-file", stackFilePath, stack.Name)
+		args = append(args, "--prune")
 	}
 
-	env := make([]string, 0)
-	for _, envvar := range stack.Env {
-		env = append(env, envvar.Name+"="+envvar.Value)
+	args = append(args, "--compose-file", stackFilePath)
+	for _, file := range stack.AdditionalFiles {
+		args = append(args, "--compose-file", path.Join(stack.ProjectPath, file))
 	}
+	args = append(args, stack.Name)
 
 	stackFolder := path.Dir(stackFilePath)
-	return runCommandAndCaptureStdErr(command, args, env, stackFolder)
+	return runCommandAndCaptureStdErr(command, args, stackEnv(stack), stackFolder, output)
 }
 
 // Remove executes the docker stack rm command.
-func (manager *SwarmStackManager) Remove(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+// The output of the command is copied to the output writer when specified.
+func (manager *SwarmStackManager) Remove(stack *portainer.Stack, endpoint *portainer.Endpoint, output io.Writer) error {
 	command, args := prepareDockerCommandAndArgs(manager.binaryPath, manager.dataPath, endpoint)
 	args = append(args, "stack", "rm", stack.Name)
-	return runCommandAndCaptureStdErr(command, args, nil, "")
+	return runCommandAndCaptureStdErr(command, args, nil, "", output)
 }
 
-func runCommandAndCaptureStdErr(command string, args []string, env []string, workingDir string) error {
+func runCommandAndCaptureStdErr(command string, args []string, env []string, workingDir string, output io.Writer) error {
 	var stderr bytes.Buffer
 	cmd := exec.Command(command, args...)
 	cmd.Stderr = &stderr
 	cmd.Dir = workingDir
 
+	if output != nil {
+		cmd.Stdout = output
+		cmd.Stderr = io.MultiWriter(&stderr, output)
+	}
+
 	if env != nil {
 		cmd.Env = os.Environ()
 		cmd.Env = append(cmd.Env, env...)
@@ -11,6 +11,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"path/filepath"
 )
 
 const (
@@ -32,6 +33,16 @@ const (
 	PrivateKeyFile = "portainer.key"
 	// PublicKeyFile represents the name on disk of the file containing the public key.
 	PublicKeyFile = "portainer.pub"
+	// EncryptionKeyFile represents the name on disk of the file containing the key used to encrypt sensitive data.
+	EncryptionKeyFile = "portainer.aes"
+	// SessionRecordingStorePath represents the subfolder where session recordings are stored in the file store folder.
+	SessionRecordingStorePath = "recordings"
+	// SessionRecordingFileExtension represents the extension of a session recording file.
+	SessionRecordingFileExtension = ".cast"
+	// ScheduleStorePath represents the subfolder where schedule files are stored in the file store folder.
+	ScheduleStorePath = "schedules"
+	// ScheduleScriptFileName represents the name on disk of the script run by a schedule.
+	ScheduleScriptFileName = "script.sh"
 )
 
 // Service represents a service for managing files and directories.
@@ -63,6 +74,16 @@ func NewService(dataStorePath, fileStorePath string) (*Service, error) {
 		return nil, err
 	}
 
+	err = service.createDirectoryInStore(SessionRecordingStorePath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.createDirectoryInStore(ScheduleStorePath)
+	if err != nil {
+		return nil, err
+	}
+
 	return service, nil
 }
 
@@ -77,6 +98,21 @@ func (service *Service) GetStackProjectPath(stackIdentifier string) string {
 	return path.Join(service.fileStorePath, ComposeStorePath, stackIdentifier)
 }
 
+// GetSessionRecordingPath returns the absolute path on the FS of the file used to store
+// a session recording based on its identifier.
+func (service *Service) GetSessionRecordingPath(recordingIdentifier string) string {
+	return path.Join(service.fileStorePath, SessionRecordingStorePath, recordingIdentifier+SessionRecordingFileExtension)
+}
+
+// DeleteSessionRecordingFile deletes the file associated to a session recording.
+func (service *Service) DeleteSessionRecordingFile(recordingIdentifier string) error {
+	err := os.Remove(service.GetSessionRecordingPath(recordingIdentifier))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 // StoreStackFileFromBytes creates a subfolder in the ComposeStorePath and stores a new file from bytes.
 // It returns the path to the folder where the file is stored.
 func (service *Service) StoreStackFileFromBytes(stackIdentifier, fileName string, data []byte) (string, error) {
@@ -97,6 +133,84 @@ func (service *Service) StoreStackFileFromBytes(stackIdentifier, fileName string
 	return path.Join(service.fileStorePath, stackStorePath), nil
 }
 
+// DeleteStackFile deletes a file of the stack project folder. No error is returned if the file does not exist.
+func (service *Service) DeleteStackFile(stackIdentifier, fileName string) error {
+	err := os.Remove(path.Join(service.fileStorePath, ComposeStorePath, stackIdentifier, fileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// GetScheduleFolder returns the absolute path on the FS of the folder used to store
+// the files of a schedule based on its identifier.
+func (service *Service) GetScheduleFolder(scheduleIdentifier string) string {
+	return path.Join(service.fileStorePath, ScheduleStorePath, scheduleIdentifier)
+}
+
+// StoreScheduleScriptFromBytes creates a subfolder in the ScheduleStorePath and stores the script of a schedule from bytes.
+// It returns the path to the script.
+func (service *Service) StoreScheduleScriptFromBytes(scheduleIdentifier string, data []byte) (string, error) {
+	scheduleStorePath := path.Join(ScheduleStorePath, scheduleIdentifier)
+	err := service.createDirectoryInStore(scheduleStorePath)
+	if err != nil {
+		return "", err
+	}
+
+	scriptFilePath := path.Join(scheduleStorePath, ScheduleScriptFileName)
+	r := bytes.NewReader(data)
+
+	err = service.createFileInStore(scriptFilePath, r)
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(service.fileStorePath, scriptFilePath), nil
+}
+
+// CopyStackProject copies the content of a stack project folder into a new subfolder of the ComposeStorePath.
+// It returns the path to the new folder.
+func (service *Service) CopyStackProject(projectPath, stackIdentifier string) (string, error) {
+	stackStorePath := path.Join(ComposeStorePath, stackIdentifier)
+	err := service.createDirectoryInStore(stackStorePath)
+	if err != nil {
+		return "", err
+	}
+
+	err = filepath.Walk(projectPath, func(sourcePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(projectPath, sourcePath)
+		if err != nil || relativePath == "." {
+			return err
+		}
+
+		destinationPath := path.Join(stackStorePath, filepath.ToSlash(relativePath))
+		if info.IsDir() {
+			return service.createDirectoryInStore(destinationPath)
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(sourcePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		return service.createFileInStore(destinationPath, file)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(service.fileStorePath, stackStorePath), nil
+}
+
 // StoreTLSFileFromBytes creates a folder in the TLSStorePath and stores a new file from bytes.
 // It returns the path to the newly created file.
 func (service *Service) StoreTLSFileFromBytes(folder string, fileType portainer.TLSFileType, data []byte) (string, error) {
@@ -265,6 +379,21 @@ func (service *Service) LoadKeyPair() ([]byte, []byte, error) {
 	return privateKey, publicKey, nil
 }
 
+// EncryptionKeyFileExists checks for the existence of the encryption key file.
+func (service *Service) EncryptionKeyFileExists() (bool, error) {
+	return service.FileExists(path.Join(service.dataStorePath, EncryptionKeyFile))
+}
+
+// StoreEncryptionKey stores the specified encryption key as a PEM file on disk.
+func (service *Service) StoreEncryptionKey(key []byte, pemHeader string) error {
+	return service.createPEMFileInStore(key, pemHeader, EncryptionKeyFile)
+}
+
+// LoadEncryptionKey retrieves the content of the encryption key file on disk.
+func (service *Service) LoadEncryptionKey() ([]byte, error) {
+	return service.getContentFromPEMFile(EncryptionKeyFile)
+}
+
 // createDirectoryInStore creates a new directory in the file store
 func (service *Service) createDirectoryInStore(name string) error {
 	path := path.Join(service.fileStorePath, name)
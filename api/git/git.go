@@ -4,6 +4,7 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/portainer/portainer"
 	"gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 )
@@ -33,6 +34,10 @@ func (service *Service) ClonePrivateRepositoryWithBasicAuth(repositoryURL, refer
 }
 
 func cloneRepository(repositoryURL, referenceName string, destination string) error {
+	if !isSupportedRepositoryURL(repositoryURL) {
+		return portainer.ErrUnsupportedRepositoryURL
+	}
+
 	options := &git.CloneOptions{
 		URL: repositoryURL,
 	}
@@ -44,3 +49,18 @@ func cloneRepository(repositoryURL, referenceName string, destination string) er
 	_, err := git.PlainClone(destination, false, options)
 	return err
 }
+
+// isSupportedRepositoryURL ensures that a repository is cloned over the network. Local transports
+// such as file:// would give access to the filesystem of the Portainer instance.
+func isSupportedRepositoryURL(repositoryURL string) bool {
+	parsedURL, err := url.Parse(repositoryURL)
+	if err != nil || parsedURL.Host == "" {
+		return false
+	}
+
+	switch strings.ToLower(parsedURL.Scheme) {
+	case "http", "https", "ssh":
+		return true
+	}
+	return false
+}
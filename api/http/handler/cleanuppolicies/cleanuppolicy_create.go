@@ -0,0 +1,118 @@
+package cleanuppolicies
+
+import (
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/cron"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+	robfigcron "github.com/robfig/cron"
+)
+
+type cleanupPolicyCreatePayload struct {
+	Name                string
+	CronExpression      string
+	EndpointID          int
+	EndpointGroupID     int
+	Containers          bool
+	Images              bool
+	UnusedImages        bool
+	ImageRetentionDays  int
+	Volumes             bool
+	ExcludedVolumeLabel string
+	Networks            bool
+	BuildCache          bool
+}
+
+func (payload *cleanupPolicyCreatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Name) {
+		return portainer.Error("Invalid cleanup policy name")
+	}
+	if !isValidCronExpression(payload.CronExpression) {
+		return portainer.Error("Invalid cron expression. Must use the standard format: minute, hour, day of month, month and day of week")
+	}
+	if (payload.EndpointID == 0) == (payload.EndpointGroupID == 0) {
+		return portainer.Error("Invalid target. Either an endpoint or an endpoint group must be specified")
+	}
+	if !payload.Containers && !payload.Images && !payload.Volumes && !payload.Networks && !payload.BuildCache {
+		return portainer.Error("Invalid cleanup policy. At least one type of resource must be removed")
+	}
+	if payload.ImageRetentionDays < 0 {
+		return portainer.Error("Invalid image retention period")
+	}
+	return nil
+}
+
+// POST request on /api/cleanup_policies
+func (handler *Handler) cleanupPolicyCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload cleanupPolicyCreatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	policy := &portainer.CleanupPolicy{
+		Name:                payload.Name,
+		CronExpression:      payload.CronExpression,
+		EndpointID:          portainer.EndpointID(payload.EndpointID),
+		EndpointGroupID:     portainer.EndpointGroupID(payload.EndpointGroupID),
+		Containers:          payload.Containers,
+		Images:              payload.Images,
+		UnusedImages:        payload.UnusedImages,
+		ImageRetentionDays:  payload.ImageRetentionDays,
+		Volumes:             payload.Volumes,
+		ExcludedVolumeLabel: payload.ExcludedVolumeLabel,
+		Networks:            payload.Networks,
+		BuildCache:          payload.BuildCache,
+	}
+
+	targetError := handler.validateTarget(policy)
+	if targetError != nil {
+		return targetError
+	}
+
+	err = handler.CleanupPolicyService.CreateCleanupPolicy(policy)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the cleanup policy inside the database", err}
+	}
+
+	err = handler.JobScheduler.ScheduleJob(cron.CleanupPolicyJobName(policy.ID), policy.CronExpression, cron.NewCleanupPolicyJob(policy.ID, handler.CleanupPolicyService, handler.CleanupRunService, handler.EndpointCleaner))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to schedule the cleanup policy", err}
+	}
+
+	return response.JSON(w, policy)
+}
+
+// validateTarget ensures that the endpoint or the endpoint group targeted by a policy exists.
+func (handler *Handler) validateTarget(policy *portainer.CleanupPolicy) *httperror.HandlerError {
+	if policy.EndpointGroupID != 0 {
+		_, err := handler.EndpointGroupService.EndpointGroup(policy.EndpointGroupID)
+		if err == portainer.ErrObjectNotFound {
+			return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint group with the specified identifier inside the database", err}
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint group with the specified identifier inside the database", err}
+		}
+		return nil
+	}
+
+	endpoint, err := handler.EndpointService.Endpoint(policy.EndpointID)
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	if endpoint.Type == portainer.AzureEnvironment {
+		return &httperror.HandlerError{http.StatusBadRequest, "Cleanup policies cannot be applied to the endpoint " + endpoint.Name, portainer.ErrEndpointTypeNotSupported}
+	}
+	return nil
+}
+
+func isValidCronExpression(expression string) bool {
+	_, err := robfigcron.ParseStandard(expression)
+	return err == nil
+}
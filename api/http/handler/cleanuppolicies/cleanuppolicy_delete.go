@@ -0,0 +1,48 @@
+package cleanuppolicies
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/cron"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// DELETE request on /api/cleanup_policies/:id
+// The run history of the policy is removed as well.
+func (handler *Handler) cleanupPolicyDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	policyID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid cleanup policy identifier route variable", err}
+	}
+
+	policy, err := handler.CleanupPolicyService.CleanupPolicy(portainer.CleanupPolicyID(policyID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a cleanup policy with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a cleanup policy with the specified identifier inside the database", err}
+	}
+
+	handler.JobScheduler.UnscheduleJob(cron.CleanupPolicyJobName(policy.ID))
+
+	err = handler.CleanupPolicyService.DeleteCleanupPolicy(policy.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the cleanup policy from the database", err}
+	}
+
+	runs, err := handler.CleanupRunService.CleanupRunsByPolicy(policy.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the runs of the cleanup policy from the database", err}
+	}
+
+	for _, run := range runs {
+		err = handler.CleanupRunService.DeleteCleanupRun(run.ID)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the runs of the cleanup policy from the database", err}
+		}
+	}
+
+	return response.Empty(w)
+}
@@ -0,0 +1,45 @@
+package cleanuppolicies
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+type cleanupPolicyDryRunResponse struct {
+	SpaceReclaimed int64                     `json:"SpaceReclaimed"`
+	Reports        []portainer.CleanupReport `json:"Reports"`
+}
+
+// POST request on /api/cleanup_policies/:id/dryrun
+// Returns the resources that would be removed by the policy on each of its endpoints, nothing is removed.
+func (handler *Handler) cleanupPolicyDryRun(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	policyID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid cleanup policy identifier route variable", err}
+	}
+
+	policy, err := handler.CleanupPolicyService.CleanupPolicy(portainer.CleanupPolicyID(policyID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a cleanup policy with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a cleanup policy with the specified identifier inside the database", err}
+	}
+
+	reports, err := handler.EndpointCleaner.Cleanup(policy, true)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to evaluate the cleanup policy", err}
+	}
+
+	dryRun := cleanupPolicyDryRunResponse{
+		Reports: reports,
+	}
+	for _, report := range reports {
+		dryRun.SpaceReclaimed += report.SpaceReclaimed
+	}
+
+	return response.JSON(w, dryRun)
+}
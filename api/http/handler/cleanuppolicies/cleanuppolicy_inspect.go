@@ -0,0 +1,27 @@
+package cleanuppolicies
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// GET request on /api/cleanup_policies/:id
+func (handler *Handler) cleanupPolicyInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	policyID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid cleanup policy identifier route variable", err}
+	}
+
+	policy, err := handler.CleanupPolicyService.CleanupPolicy(portainer.CleanupPolicyID(policyID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a cleanup policy with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a cleanup policy with the specified identifier inside the database", err}
+	}
+
+	return response.JSON(w, policy)
+}
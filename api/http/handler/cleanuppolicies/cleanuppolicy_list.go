@@ -0,0 +1,18 @@
+package cleanuppolicies
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/response"
+)
+
+// GET request on /api/cleanup_policies
+func (handler *Handler) cleanupPolicyList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	policies, err := handler.CleanupPolicyService.CleanupPolicies()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve cleanup policies from the database", err}
+	}
+
+	return response.JSON(w, policies)
+}
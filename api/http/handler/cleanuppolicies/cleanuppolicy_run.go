@@ -0,0 +1,36 @@
+package cleanuppolicies
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/cron"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// POST request on /api/cleanup_policies/:id/run
+// The policy is applied in the background, the outcome is available in the run history of the policy.
+func (handler *Handler) cleanupPolicyRun(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	policyID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid cleanup policy identifier route variable", err}
+	}
+
+	policy, err := handler.CleanupPolicyService.CleanupPolicy(portainer.CleanupPolicyID(policyID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a cleanup policy with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a cleanup policy with the specified identifier inside the database", err}
+	}
+
+	err = handler.JobScheduler.RunJob(cron.CleanupPolicyJobName(policy.ID))
+	if err == portainer.ErrJobAlreadyRunning {
+		return &httperror.HandlerError{http.StatusConflict, "The cleanup policy is already running", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to run the cleanup policy", err}
+	}
+
+	return response.Empty(w)
+}
@@ -0,0 +1,33 @@
+package cleanuppolicies
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// GET request on /api/cleanup_policies/:id/runs
+// The runs are returned oldest first, only the last runs of a policy are kept.
+func (handler *Handler) cleanupPolicyRunList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	policyID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid cleanup policy identifier route variable", err}
+	}
+
+	_, err = handler.CleanupPolicyService.CleanupPolicy(portainer.CleanupPolicyID(policyID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a cleanup policy with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a cleanup policy with the specified identifier inside the database", err}
+	}
+
+	runs, err := handler.CleanupRunService.CleanupRunsByPolicy(portainer.CleanupPolicyID(policyID))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the runs of the cleanup policy from the database", err}
+	}
+
+	return response.JSON(w, runs)
+}
@@ -0,0 +1,117 @@
+package cleanuppolicies
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/cron"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+type cleanupPolicyUpdatePayload struct {
+	Name                string
+	CronExpression      string
+	Containers          *bool
+	Images              *bool
+	UnusedImages        *bool
+	ImageRetentionDays  *int
+	Volumes             *bool
+	ExcludedVolumeLabel *string
+	Networks            *bool
+	BuildCache          *bool
+}
+
+func (payload *cleanupPolicyUpdatePayload) Validate(r *http.Request) error {
+	if payload.CronExpression != "" && !isValidCronExpression(payload.CronExpression) {
+		return portainer.Error("Invalid cron expression. Must use the standard format: minute, hour, day of month, month and day of week")
+	}
+	if payload.ImageRetentionDays != nil && *payload.ImageRetentionDays < 0 {
+		return portainer.Error("Invalid image retention period")
+	}
+	return nil
+}
+
+// PUT request on /api/cleanup_policies/:id
+// The endpoint or the endpoint group targeted by a policy cannot be changed.
+func (handler *Handler) cleanupPolicyUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	policyID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid cleanup policy identifier route variable", err}
+	}
+
+	var payload cleanupPolicyUpdatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	policy, err := handler.CleanupPolicyService.CleanupPolicy(portainer.CleanupPolicyID(policyID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a cleanup policy with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a cleanup policy with the specified identifier inside the database", err}
+	}
+
+	if payload.Name != "" {
+		policy.Name = payload.Name
+	}
+
+	if payload.Containers != nil {
+		policy.Containers = *payload.Containers
+	}
+
+	if payload.Images != nil {
+		policy.Images = *payload.Images
+	}
+
+	if payload.UnusedImages != nil {
+		policy.UnusedImages = *payload.UnusedImages
+	}
+
+	if payload.ImageRetentionDays != nil {
+		policy.ImageRetentionDays = *payload.ImageRetentionDays
+	}
+
+	if payload.Volumes != nil {
+		policy.Volumes = *payload.Volumes
+	}
+
+	if payload.ExcludedVolumeLabel != nil {
+		policy.ExcludedVolumeLabel = *payload.ExcludedVolumeLabel
+	}
+
+	if payload.Networks != nil {
+		policy.Networks = *payload.Networks
+	}
+
+	if payload.BuildCache != nil {
+		policy.BuildCache = *payload.BuildCache
+	}
+
+	if !policy.Containers && !policy.Images && !policy.Volumes && !policy.Networks && !policy.BuildCache {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", portainer.Error("Invalid cleanup policy. At least one type of resource must be removed")}
+	}
+
+	rescheduleJob := payload.CronExpression != "" && payload.CronExpression != policy.CronExpression
+	if rescheduleJob {
+		policy.CronExpression = payload.CronExpression
+	}
+
+	err = handler.CleanupPolicyService.UpdateCleanupPolicy(policy.ID, policy)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the cleanup policy changes inside the database", err}
+	}
+
+	// The job of a policy always applies its latest definition, it only needs
+	// to be rescheduled when the cron expression changes.
+	if rescheduleJob {
+		err = handler.JobScheduler.RescheduleJob(cron.CleanupPolicyJobName(policy.ID), policy.CronExpression)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to schedule the cleanup policy", err}
+		}
+	}
+
+	return response.JSON(w, policy)
+}
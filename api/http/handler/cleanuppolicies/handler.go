@@ -0,0 +1,46 @@
+package cleanuppolicies
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/security"
+)
+
+// Handler is the HTTP handler used to handle cleanup policy operations.
+type Handler struct {
+	*mux.Router
+	CleanupPolicyService portainer.CleanupPolicyService
+	CleanupRunService    portainer.CleanupRunService
+	EndpointCleaner      portainer.EndpointCleaner
+	EndpointService      portainer.EndpointService
+	EndpointGroupService portainer.EndpointGroupService
+	JobScheduler         portainer.JobScheduler
+}
+
+// NewHandler creates a handler to manage cleanup policy operations.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/cleanup_policies",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.cleanupPolicyCreate))).Methods(http.MethodPost)
+	h.Handle("/cleanup_policies",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.cleanupPolicyList))).Methods(http.MethodGet)
+	h.Handle("/cleanup_policies/{id}",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.cleanupPolicyInspect))).Methods(http.MethodGet)
+	h.Handle("/cleanup_policies/{id}",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.cleanupPolicyUpdate))).Methods(http.MethodPut)
+	h.Handle("/cleanup_policies/{id}",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.cleanupPolicyDelete))).Methods(http.MethodDelete)
+	h.Handle("/cleanup_policies/{id}/dryrun",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.cleanupPolicyDryRun))).Methods(http.MethodPost)
+	h.Handle("/cleanup_policies/{id}/run",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.cleanupPolicyRun))).Methods(http.MethodPost)
+	h.Handle("/cleanup_policies/{id}/runs",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.cleanupPolicyRunList))).Methods(http.MethodGet)
+
+	return h
+}
@@ -0,0 +1,41 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// GET request on /api/endpoints/:id/image_updates?refresh=<refresh>
+func (handler *Handler) endpointImageUpdates(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	refresh, _ := request.RetrieveBooleanQueryParameter(r, "refresh", true)
+
+	endpoint, err := handler.EndpointService.Endpoint(portainer.EndpointID(endpointID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	if endpoint.Type == portainer.AzureEnvironment {
+		return &httperror.HandlerError{http.StatusBadRequest, "Image update detection is not supported on Azure endpoints", portainer.ErrEndpointTypeNotSupported}
+	}
+
+	report := handler.ImageUpdateService.EndpointReport(endpoint.ID)
+	if report == nil || refresh {
+		report, err = handler.ImageUpdateService.CheckEndpoint(endpoint)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to check the images used on the endpoint", err}
+		}
+	}
+
+	return response.JSON(w, report)
+}
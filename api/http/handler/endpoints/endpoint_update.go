@@ -23,9 +23,13 @@ type endpointUpdatePayload struct {
 	AzureTenantID          string
 	AzureAuthenticationKey string
 	Tags                   []string
+	ComposeStackEngine     int
 }
 
 func (payload *endpointUpdatePayload) Validate(r *http.Request) error {
+	if payload.ComposeStackEngine != 0 && payload.ComposeStackEngine != int(portainer.LibComposeEngine) && payload.ComposeStackEngine != int(portainer.DockerComposeEngine) {
+		return portainer.Error("Invalid Compose stack engine value. Value must be one of: 1 (libcompose) or 2 (Docker CLI)")
+	}
 	return nil
 }
 
@@ -73,6 +77,10 @@ func (handler *Handler) endpointUpdate(w http.ResponseWriter, r *http.Request) *
 		endpoint.Tags = payload.Tags
 	}
 
+	if payload.ComposeStackEngine != 0 {
+		endpoint.ComposeStackEngine = portainer.ComposeStackEngine(payload.ComposeStackEngine)
+	}
+
 	if endpoint.Type == portainer.AzureEnvironment {
 		credentials := endpoint.AzureCredentials
 		if payload.AzureApplicationID != "" {
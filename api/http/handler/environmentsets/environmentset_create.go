@@ -0,0 +1,109 @@
+package environmentsets
+
+import (
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+type environmentSetCreatePayload struct {
+	Name            string
+	Scope           int
+	EndpointGroupID int
+	EndpointID      int
+	Variables       []portainer.EnvironmentVariable
+}
+
+func (payload *environmentSetCreatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Name) {
+		return portainer.Error("Invalid environment set name")
+	}
+	if payload.Scope != int(portainer.GlobalEnvironmentSet) && payload.Scope != int(portainer.EndpointGroupEnvironmentSet) && payload.Scope != int(portainer.EndpointEnvironmentSet) {
+		return portainer.Error("Invalid scope value. Value must be one of: 1 (global), 2 (endpoint group) or 3 (endpoint)")
+	}
+	if payload.Scope == int(portainer.EndpointGroupEnvironmentSet) && payload.EndpointGroupID == 0 {
+		return portainer.Error("Invalid endpoint group identifier")
+	}
+	if payload.Scope == int(portainer.EndpointEnvironmentSet) && payload.EndpointID == 0 {
+		return portainer.Error("Invalid endpoint identifier")
+	}
+	return validateVariables(payload.Variables)
+}
+
+// POST request on /api/environment_sets
+func (handler *Handler) environmentSetCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload environmentSetCreatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	set := &portainer.EnvironmentSet{
+		Name:  payload.Name,
+		Scope: portainer.EnvironmentSetScope(payload.Scope),
+	}
+
+	switch set.Scope {
+	case portainer.EndpointGroupEnvironmentSet:
+		set.EndpointGroupID = portainer.EndpointGroupID(payload.EndpointGroupID)
+		_, err = handler.EndpointGroupService.EndpointGroup(set.EndpointGroupID)
+		if err == portainer.ErrObjectNotFound {
+			return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint group with the specified identifier inside the database", err}
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint group with the specified identifier inside the database", err}
+		}
+	case portainer.EndpointEnvironmentSet:
+		set.EndpointID = portainer.EndpointID(payload.EndpointID)
+		_, err = handler.EndpointService.Endpoint(set.EndpointID)
+		if err == portainer.ErrObjectNotFound {
+			return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+		}
+	}
+
+	isUnique, err := handler.isUniqueEnvironmentSetName(set)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve environment sets from the database", err}
+	}
+	if !isUnique {
+		return &httperror.HandlerError{http.StatusConflict, "An environment set with this name already exists in this scope", portainer.ErrEnvironmentSetAlreadyExists}
+	}
+
+	set.Variables, err = handler.encryptVariables(payload.Variables, nil)
+	if err == portainer.ErrEnvironmentSetMaskedValue {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid variable value", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to encrypt secret variables", err}
+	}
+
+	err = handler.EnvironmentSetService.CreateEnvironmentSet(set)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the environment set inside the database", err}
+	}
+
+	maskSecretValues(set)
+	return response.JSON(w, set)
+}
+
+// isUniqueEnvironmentSetName returns false when another environment set with the same name
+// is defined in the same scope.
+func (handler *Handler) isUniqueEnvironmentSetName(set *portainer.EnvironmentSet) (bool, error) {
+	sets, err := handler.EnvironmentSetService.EnvironmentSets()
+	if err != nil {
+		return false, err
+	}
+
+	for _, existingSet := range sets {
+		if existingSet.ID != set.ID && existingSet.Name == set.Name && existingSet.Scope == set.Scope &&
+			existingSet.EndpointGroupID == set.EndpointGroupID && existingSet.EndpointID == set.EndpointID {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
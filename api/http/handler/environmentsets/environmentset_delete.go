@@ -0,0 +1,46 @@
+package environmentsets
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// DELETE request on /api/environment_sets/:id
+// An environment set referenced by a stack cannot be removed.
+func (handler *Handler) environmentSetDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	setID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid environment set identifier route variable", err}
+	}
+
+	_, err = handler.EnvironmentSetService.EnvironmentSet(portainer.EnvironmentSetID(setID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an environment set with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an environment set with the specified identifier inside the database", err}
+	}
+
+	stacks, err := handler.StackService.Stacks()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stacks from the database", err}
+	}
+
+	for _, stack := range stacks {
+		for _, id := range stack.EnvironmentSets {
+			if id == portainer.EnvironmentSetID(setID) {
+				return &httperror.HandlerError{http.StatusConflict, "The environment set is referenced by the stack " + stack.Name, portainer.ErrEnvironmentSetInUse}
+			}
+		}
+	}
+
+	err = handler.EnvironmentSetService.DeleteEnvironmentSet(portainer.EnvironmentSetID(setID))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the environment set from the database", err}
+	}
+
+	return response.Empty(w)
+}
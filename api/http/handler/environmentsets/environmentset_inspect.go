@@ -0,0 +1,28 @@
+package environmentsets
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// GET request on /api/environment_sets/:id
+func (handler *Handler) environmentSetInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	setID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid environment set identifier route variable", err}
+	}
+
+	set, err := handler.EnvironmentSetService.EnvironmentSet(portainer.EnvironmentSetID(setID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an environment set with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an environment set with the specified identifier inside the database", err}
+	}
+
+	maskSecretValues(set)
+	return response.JSON(w, set)
+}
@@ -0,0 +1,39 @@
+package environmentsets
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/response"
+	"github.com/portainer/portainer/http/security"
+)
+
+// GET request on /api/environment_sets
+func (handler *Handler) environmentSetList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	sets, err := handler.EnvironmentSetService.EnvironmentSets()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve environment sets from the database", err}
+	}
+
+	endpoints, err := handler.EndpointService.Endpoints()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoints from the database", err}
+	}
+
+	endpointGroups, err := handler.EndpointGroupService.EndpointGroups()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoint groups from the database", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	filteredSets := security.FilterEnvironmentSets(sets, endpoints, endpointGroups, securityContext)
+	for idx := range filteredSets {
+		maskSecretValues(&filteredSets[idx])
+	}
+
+	return response.JSON(w, filteredSets)
+}
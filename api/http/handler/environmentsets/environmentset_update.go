@@ -0,0 +1,71 @@
+package environmentsets
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+type environmentSetUpdatePayload struct {
+	Name      string
+	Variables []portainer.EnvironmentVariable
+}
+
+func (payload *environmentSetUpdatePayload) Validate(r *http.Request) error {
+	return validateVariables(payload.Variables)
+}
+
+// PUT request on /api/environment_sets/:id
+// The variables of the environment set are replaced by the variables of the payload. A secret variable
+// sent with the secret value mask as value keeps its current value.
+func (handler *Handler) environmentSetUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	setID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid environment set identifier route variable", err}
+	}
+
+	var payload environmentSetUpdatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	set, err := handler.EnvironmentSetService.EnvironmentSet(portainer.EnvironmentSetID(setID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an environment set with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an environment set with the specified identifier inside the database", err}
+	}
+
+	if payload.Name != "" {
+		set.Name = payload.Name
+
+		isUnique, err := handler.isUniqueEnvironmentSetName(set)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve environment sets from the database", err}
+		}
+		if !isUnique {
+			return &httperror.HandlerError{http.StatusConflict, "An environment set with this name already exists in this scope", portainer.ErrEnvironmentSetAlreadyExists}
+		}
+	}
+
+	if payload.Variables != nil {
+		set.Variables, err = handler.encryptVariables(payload.Variables, set.Variables)
+		if err == portainer.ErrEnvironmentSetMaskedValue {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid variable value", err}
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to encrypt secret variables", err}
+		}
+	}
+
+	err = handler.EnvironmentSetService.UpdateEnvironmentSet(set.ID, set)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the environment set changes inside the database", err}
+	}
+
+	maskSecretValues(set)
+	return response.JSON(w, set)
+}
@@ -0,0 +1,39 @@
+package environmentsets
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/security"
+)
+
+// Handler is the HTTP handler used to handle environment set operations.
+type Handler struct {
+	*mux.Router
+	EnvironmentSetService portainer.EnvironmentSetService
+	EndpointService       portainer.EndpointService
+	EndpointGroupService  portainer.EndpointGroupService
+	StackService          portainer.StackService
+	EncryptionService     portainer.EncryptionService
+}
+
+// NewHandler creates a handler to manage environment set operations.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/environment_sets",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.environmentSetCreate))).Methods(http.MethodPost)
+	h.Handle("/environment_sets",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.environmentSetList))).Methods(http.MethodGet)
+	h.Handle("/environment_sets/{id}",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.environmentSetInspect))).Methods(http.MethodGet)
+	h.Handle("/environment_sets/{id}",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.environmentSetUpdate))).Methods(http.MethodPut)
+	h.Handle("/environment_sets/{id}",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.environmentSetDelete))).Methods(http.MethodDelete)
+
+	return h
+}
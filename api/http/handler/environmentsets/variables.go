@@ -0,0 +1,73 @@
+package environmentsets
+
+import (
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/portainer/portainer"
+)
+
+// validateVariables ensures that the variables have a valid and unique name.
+func validateVariables(variables []portainer.EnvironmentVariable) error {
+	names := make(map[string]bool)
+	for _, variable := range variables {
+		if govalidator.IsNull(variable.Name) || strings.ContainsAny(variable.Name, "= \t\n") {
+			return portainer.Error("Invalid variable name: " + variable.Name)
+		}
+		if names[variable.Name] {
+			return portainer.Error("Duplicate variable name: " + variable.Name)
+		}
+		names[variable.Name] = true
+	}
+	return nil
+}
+
+// encryptVariables encrypts the value of the secret variables. When the value of a variable is the
+// secret value mask, the value of the secret variable with the same name in previousVariables is kept,
+// it is decrypted when the variable is no longer secret. The secret value mask cannot be used as the value
+// of a variable that was not a secret variable.
+func (handler *Handler) encryptVariables(variables, previousVariables []portainer.EnvironmentVariable) ([]portainer.EnvironmentVariable, error) {
+	previousSecrets := make(map[string]string)
+	for _, variable := range previousVariables {
+		if variable.Secret {
+			previousSecrets[variable.Name] = variable.Value
+		}
+	}
+
+	encryptedVariables := make([]portainer.EnvironmentVariable, 0, len(variables))
+	for _, variable := range variables {
+		if variable.Value == portainer.SecretValueMask {
+			previousValue, ok := previousSecrets[variable.Name]
+			if !ok {
+				return nil, portainer.ErrEnvironmentSetMaskedValue
+			}
+
+			variable.Value = previousValue
+			if !variable.Secret {
+				decryptedValue, err := handler.EncryptionService.Decrypt(previousValue)
+				if err != nil {
+					return nil, err
+				}
+				variable.Value = decryptedValue
+			}
+		} else if variable.Secret {
+			encryptedValue, err := handler.EncryptionService.Encrypt(variable.Value)
+			if err != nil {
+				return nil, err
+			}
+			variable.Value = encryptedValue
+		}
+		encryptedVariables = append(encryptedVariables, variable)
+	}
+
+	return encryptedVariables, nil
+}
+
+// maskSecretValues replaces the value of the secret variables of an environment set with the secret value mask.
+func maskSecretValues(set *portainer.EnvironmentSet) {
+	for idx := range set.Variables {
+		if set.Variables[idx].Secret {
+			set.Variables[idx].Value = portainer.SecretValueMask
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package environmentsets
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/portainer/portainer"
+)
+
+type testEncryptionService struct{}
+
+func (service testEncryptionService) Encrypt(data string) (string, error) {
+	return "encrypted:" + data, nil
+}
+
+func (service testEncryptionService) Decrypt(data string) (string, error) {
+	return strings.TrimPrefix(data, "encrypted:"), nil
+}
+
+func TestEncryptVariables(t *testing.T) {
+	previousVariables := []portainer.EnvironmentVariable{
+		{Name: "PASSWORD", Value: "encrypted:secret", Secret: true},
+		{Name: "USER", Value: "admin"},
+	}
+
+	tests := []struct {
+		name          string
+		variables     []portainer.EnvironmentVariable
+		expected      []portainer.EnvironmentVariable
+		expectedError error
+	}{
+		{
+			name:      "New values",
+			variables: []portainer.EnvironmentVariable{{Name: "PASSWORD", Value: "other", Secret: true}, {Name: "USER", Value: "root"}},
+			expected:  []portainer.EnvironmentVariable{{Name: "PASSWORD", Value: "encrypted:other", Secret: true}, {Name: "USER", Value: "root"}},
+		},
+		{
+			name:      "Masked secret is kept",
+			variables: []portainer.EnvironmentVariable{{Name: "PASSWORD", Value: portainer.SecretValueMask, Secret: true}},
+			expected:  []portainer.EnvironmentVariable{{Name: "PASSWORD", Value: "encrypted:secret", Secret: true}},
+		},
+		{
+			name:      "Masked secret turned into a plain variable is decrypted",
+			variables: []portainer.EnvironmentVariable{{Name: "PASSWORD", Value: portainer.SecretValueMask}},
+			expected:  []portainer.EnvironmentVariable{{Name: "PASSWORD", Value: "secret"}},
+		},
+		{
+			name:          "Masked value of a renamed secret",
+			variables:     []portainer.EnvironmentVariable{{Name: "DB_PASSWORD", Value: portainer.SecretValueMask, Secret: true}},
+			expectedError: portainer.ErrEnvironmentSetMaskedValue,
+		},
+		{
+			name:          "Masked value of a plain variable",
+			variables:     []portainer.EnvironmentVariable{{Name: "USER", Value: portainer.SecretValueMask}},
+			expectedError: portainer.ErrEnvironmentSetMaskedValue,
+		},
+	}
+
+	handler := &Handler{EncryptionService: testEncryptionService{}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			variables, err := handler.encryptVariables(test.variables, previousVariables)
+			if err != test.expectedError {
+				t.Fatalf("Unexpected error: got %v want %v", err, test.expectedError)
+			}
+
+			if err == nil && !reflect.DeepEqual(variables, test.expected) {
+				t.Errorf("Unexpected variables: got %v want %v", variables, test.expected)
+			}
+		})
+	}
+}
@@ -5,13 +5,18 @@ import (
 	"strings"
 
 	"github.com/portainer/portainer/http/handler/auth"
+	"github.com/portainer/portainer/http/handler/cleanuppolicies"
 	"github.com/portainer/portainer/http/handler/dockerhub"
 	"github.com/portainer/portainer/http/handler/endpointgroups"
 	"github.com/portainer/portainer/http/handler/endpointproxy"
 	"github.com/portainer/portainer/http/handler/endpoints"
+	"github.com/portainer/portainer/http/handler/environmentsets"
 	"github.com/portainer/portainer/http/handler/file"
+	"github.com/portainer/portainer/http/handler/jobs"
 	"github.com/portainer/portainer/http/handler/registries"
 	"github.com/portainer/portainer/http/handler/resourcecontrols"
+	"github.com/portainer/portainer/http/handler/schedules"
+	"github.com/portainer/portainer/http/handler/sessionrecordings"
 	"github.com/portainer/portainer/http/handler/settings"
 	"github.com/portainer/portainer/http/handler/stacks"
 	"github.com/portainer/portainer/http/handler/status"
@@ -28,23 +33,28 @@ import (
 type Handler struct {
 	AuthHandler *auth.Handler
 
-	DockerHubHandler       *dockerhub.Handler
-	EndpointGroupHandler   *endpointgroups.Handler
-	EndpointHandler        *endpoints.Handler
-	EndpointProxyHandler   *endpointproxy.Handler
-	FileHandler            *file.Handler
-	RegistryHandler        *registries.Handler
-	ResourceControlHandler *resourcecontrols.Handler
-	SettingsHandler        *settings.Handler
-	StackHandler           *stacks.Handler
-	StatusHandler          *status.Handler
-	TagHandler             *tags.Handler
-	TeamMembershipHandler  *teammemberships.Handler
-	TeamHandler            *teams.Handler
-	TemplatesHandler       *templates.Handler
-	UploadHandler          *upload.Handler
-	UserHandler            *users.Handler
-	WebSocketHandler       *websocket.Handler
+	CleanupPolicyHandler    *cleanuppolicies.Handler
+	DockerHubHandler        *dockerhub.Handler
+	EndpointGroupHandler    *endpointgroups.Handler
+	EndpointHandler         *endpoints.Handler
+	EndpointProxyHandler    *endpointproxy.Handler
+	EnvironmentSetHandler   *environmentsets.Handler
+	FileHandler             *file.Handler
+	JobHandler              *jobs.Handler
+	RegistryHandler         *registries.Handler
+	ResourceControlHandler  *resourcecontrols.Handler
+	ScheduleHandler         *schedules.Handler
+	SessionRecordingHandler *sessionrecordings.Handler
+	SettingsHandler         *settings.Handler
+	StackHandler            *stacks.Handler
+	StatusHandler           *status.Handler
+	TagHandler              *tags.Handler
+	TeamMembershipHandler   *teammemberships.Handler
+	TeamHandler             *teams.Handler
+	TemplatesHandler        *templates.Handler
+	UploadHandler           *upload.Handler
+	UserHandler             *users.Handler
+	WebSocketHandler        *websocket.Handler
 }
 
 // ServeHTTP delegates a request to the appropriate subhandler.
@@ -52,6 +62,8 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch {
 	case strings.HasPrefix(r.URL.Path, "/api/auth"):
 		http.StripPrefix("/api", h.AuthHandler).ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/cleanup_policies"):
+		http.StripPrefix("/api", h.CleanupPolicyHandler).ServeHTTP(w, r)
 	case strings.HasPrefix(r.URL.Path, "/api/dockerhub"):
 		http.StripPrefix("/api", h.DockerHubHandler).ServeHTTP(w, r)
 	case strings.HasPrefix(r.URL.Path, "/api/endpoint_groups"):
@@ -67,10 +79,18 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		default:
 			http.StripPrefix("/api", h.EndpointHandler).ServeHTTP(w, r)
 		}
+	case strings.HasPrefix(r.URL.Path, "/api/environment_sets"):
+		http.StripPrefix("/api", h.EnvironmentSetHandler).ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/jobs"):
+		http.StripPrefix("/api", h.JobHandler).ServeHTTP(w, r)
 	case strings.HasPrefix(r.URL.Path, "/api/registries"):
 		http.StripPrefix("/api", h.RegistryHandler).ServeHTTP(w, r)
 	case strings.HasPrefix(r.URL.Path, "/api/resource_controls"):
 		http.StripPrefix("/api", h.ResourceControlHandler).ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/schedules"):
+		http.StripPrefix("/api", h.ScheduleHandler).ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/session_recordings"):
+		http.StripPrefix("/api", h.SessionRecordingHandler).ServeHTTP(w, r)
 	case strings.HasPrefix(r.URL.Path, "/api/settings"):
 		http.StripPrefix("/api", h.SettingsHandler).ServeHTTP(w, r)
 	case strings.HasPrefix(r.URL.Path, "/api/stacks"):
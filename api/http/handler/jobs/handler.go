@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/security"
+)
+
+// Handler is the HTTP handler used to handle scheduled job operations.
+type Handler struct {
+	*mux.Router
+	JobScheduler portainer.JobScheduler
+}
+
+// NewHandler creates a handler to manage scheduled job operations.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/jobs",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.jobList))).Methods(http.MethodGet)
+	h.Handle("/jobs/{name}",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.jobInspect))).Methods(http.MethodGet)
+	h.Handle("/jobs/{name}/run",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.jobRun))).Methods(http.MethodPost)
+
+	return h
+}
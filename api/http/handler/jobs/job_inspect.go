@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// GET request on /api/jobs/:name
+func (handler *Handler) jobInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid job name route variable", err}
+	}
+
+	status, err := handler.JobScheduler.JobStatus(name)
+	if err == portainer.ErrJobNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a scheduled job with the specified name", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the status of the job", err}
+	}
+
+	return response.JSON(w, status)
+}
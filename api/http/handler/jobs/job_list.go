@@ -0,0 +1,13 @@
+package jobs
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/response"
+)
+
+// GET request on /api/jobs
+func (handler *Handler) jobList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return response.JSON(w, handler.JobScheduler.JobStatuses())
+}
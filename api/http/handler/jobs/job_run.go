@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// POST request on /api/jobs/:name/run
+// The job is run in the background, the outcome of the run is available in the status of the job.
+func (handler *Handler) jobRun(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid job name route variable", err}
+	}
+
+	err = handler.JobScheduler.RunJob(name)
+	if err == portainer.ErrJobNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a scheduled job with the specified name", err}
+	} else if err == portainer.ErrJobAlreadyRunning {
+		return &httperror.HandlerError{http.StatusConflict, "The job is already running", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to run the job", err}
+	}
+
+	return response.Empty(w)
+}
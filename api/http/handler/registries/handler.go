@@ -17,7 +17,8 @@ func hideFields(registry *portainer.Registry) {
 // Handler is the HTTP handler used to handle registry operations.
 type Handler struct {
 	*mux.Router
-	RegistryService portainer.RegistryService
+	RegistryService     portainer.RegistryService
+	ConnectivityService portainer.RegistryConnectivityService
 }
 
 // NewHandler creates a handler to manage registry operations.
@@ -28,12 +29,16 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 
 	h.Handle("/registries",
 		bouncer.AdministratorAccess(httperror.LoggerHandler(h.registryCreate))).Methods(http.MethodPost)
+	h.Handle("/registries/test",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.registryCheck))).Methods(http.MethodPost)
 	h.Handle("/registries",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.registryList))).Methods(http.MethodGet)
 	h.Handle("/registries/{id}",
 		bouncer.AdministratorAccess(httperror.LoggerHandler(h.registryInspect))).Methods(http.MethodGet)
 	h.Handle("/registries/{id}",
 		bouncer.AdministratorAccess(httperror.LoggerHandler(h.registryUpdate))).Methods(http.MethodPut)
+	h.Handle("/registries/{id}/test",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.registryCheckExisting))).Methods(http.MethodPost)
 	h.Handle("/registries/{id}/access",
 		bouncer.AdministratorAccess(httperror.LoggerHandler(h.registryUpdateAccess))).Methods(http.MethodPut)
 	h.Handle("/registries/{id}",
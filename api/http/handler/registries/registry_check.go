@@ -0,0 +1,77 @@
+package registries
+
+import (
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+type registryCheckPayload struct {
+	Type           int
+	URL            string
+	Authentication bool
+	Username       string
+	Password       string
+	Ecr            portainer.EcrRegistryData
+	Azure          portainer.AzureRegistryData
+	TLSSkipVerify  bool
+}
+
+func (payload *registryCheckPayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.URL) {
+		return portainer.Error("Invalid registry URL")
+	}
+	if payload.Authentication && (govalidator.IsNull(payload.Username) || govalidator.IsNull(payload.Password)) {
+		return portainer.Error("Invalid credentials. Username and password must be specified when authentication is enabled")
+	}
+	if payload.Type == 0 {
+		payload.Type = int(portainer.CustomRegistry)
+	}
+	return validateRegistryType(portainer.RegistryType(payload.Type), payload.URL, payload.Authentication, &payload.Ecr, &payload.Azure)
+}
+
+// POST request on /api/registries/test
+func (handler *Handler) registryCheck(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload registryCheckPayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	registry := &portainer.Registry{
+		Type:           portainer.RegistryType(payload.Type),
+		URL:            payload.URL,
+		Authentication: payload.Authentication,
+		Username:       payload.Username,
+		Password:       payload.Password,
+		Ecr:            payload.Ecr,
+		Azure:          payload.Azure,
+	}
+
+	report := handler.ConnectivityService.TestConnectivity(registry, payload.TLSSkipVerify)
+	return response.JSON(w, report)
+}
+
+// POST request on /api/registries/:id/test?tlsSkipVerify=<tlsSkipVerify>
+func (handler *Handler) registryCheckExisting(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	registryID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid registry identifier route variable", err}
+	}
+
+	tlsSkipVerify, _ := request.RetrieveBooleanQueryParameter(r, "tlsSkipVerify", true)
+
+	registry, err := handler.RegistryService.Registry(portainer.RegistryID(registryID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a registry with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a registry with the specified identifier inside the database", err}
+	}
+
+	report := handler.ConnectivityService.TestConnectivity(registry, tlsSkipVerify)
+	return response.JSON(w, report)
+}
@@ -8,14 +8,19 @@ import (
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/request"
 	"github.com/portainer/portainer/http/response"
+	"github.com/portainer/portainer/registry"
 )
 
 type registryCreatePayload struct {
 	Name           string
+	Type           int
 	URL            string
 	Authentication bool
 	Username       string
 	Password       string
+	Ecr            portainer.EcrRegistryData
+	Azure          portainer.AzureRegistryData
+	Mirrors        []string
 }
 
 func (payload *registryCreatePayload) Validate(r *http.Request) error {
@@ -28,7 +33,42 @@ func (payload *registryCreatePayload) Validate(r *http.Request) error {
 	if payload.Authentication && (govalidator.IsNull(payload.Username) || govalidator.IsNull(payload.Password)) {
 		return portainer.Error("Invalid credentials. Username and password must be specified when authentication is enabled")
 	}
-	return nil
+	for _, mirror := range payload.Mirrors {
+		if govalidator.IsNull(mirror) {
+			return portainer.Error("Invalid registry mirror URL")
+		}
+	}
+	if payload.Type == 0 {
+		payload.Type = int(portainer.CustomRegistry)
+	}
+	return validateRegistryType(portainer.RegistryType(payload.Type), payload.URL, payload.Authentication, &payload.Ecr, &payload.Azure)
+}
+
+func validateRegistryType(registryType portainer.RegistryType, URL string, authentication bool, ecr *portainer.EcrRegistryData, azure *portainer.AzureRegistryData) error {
+	switch registryType {
+	case portainer.QuayRegistry, portainer.CustomRegistry, portainer.GitlabRegistry:
+		return nil
+	case portainer.AzureRegistry:
+		if !authentication {
+			return portainer.Error("Invalid credentials. Authentication must be enabled for an Azure registry")
+		}
+		if govalidator.IsNull(azure.TenantID) {
+			return portainer.Error("Invalid Azure tenant ID")
+		}
+		return nil
+	case portainer.EcrRegistry:
+		if !authentication {
+			return portainer.Error("Invalid credentials. Authentication must be enabled for an ECR registry")
+		}
+		if govalidator.IsNull(ecr.Region) {
+			ecr.Region = registry.EcrRegionFromURL(URL)
+		}
+		if govalidator.IsNull(ecr.Region) {
+			return portainer.Error("Invalid AWS region. The region must be specified when it cannot be determined from the registry URL")
+		}
+		return nil
+	}
+	return portainer.Error("Invalid registry type. Value must be one of: 1 (Quay.io), 2 (Azure container registry), 3 (custom registry), 4 (Gitlab registry) or 5 (AWS ECR)")
 }
 
 func (handler *Handler) registryCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
@@ -48,21 +88,29 @@ func (handler *Handler) registryCreate(w http.ResponseWriter, r *http.Request) *
 		}
 	}
 
-	registry := &portainer.Registry{
+	if payload.Mirrors == nil {
+		payload.Mirrors = []string{}
+	}
+
+	newRegistry := &portainer.Registry{
+		Type:            portainer.RegistryType(payload.Type),
 		Name:            payload.Name,
 		URL:             payload.URL,
 		Authentication:  payload.Authentication,
 		Username:        payload.Username,
 		Password:        payload.Password,
+		Ecr:             payload.Ecr,
+		Azure:           payload.Azure,
+		Mirrors:         payload.Mirrors,
 		AuthorizedUsers: []portainer.UserID{},
 		AuthorizedTeams: []portainer.TeamID{},
 	}
 
-	err = handler.RegistryService.CreateRegistry(registry)
+	err = handler.RegistryService.CreateRegistry(newRegistry)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the registry inside the database", err}
 	}
 
-	hideFields(registry)
-	return response.JSON(w, registry)
+	hideFields(newRegistry)
+	return response.JSON(w, newRegistry)
 }
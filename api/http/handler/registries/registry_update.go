@@ -12,16 +12,25 @@ import (
 
 type registryUpdatePayload struct {
 	Name           string
+	Type           *int
 	URL            string
 	Authentication bool
 	Username       string
 	Password       string
+	Ecr            *portainer.EcrRegistryData
+	Azure          *portainer.AzureRegistryData
+	Mirrors        []string
 }
 
 func (payload *registryUpdatePayload) Validate(r *http.Request) error {
 	if payload.Authentication && (govalidator.IsNull(payload.Username) || govalidator.IsNull(payload.Password)) {
 		return portainer.Error("Invalid credentials. Username and password must be specified when authentication is enabled")
 	}
+	for _, mirror := range payload.Mirrors {
+		if govalidator.IsNull(mirror) {
+			return portainer.Error("Invalid registry mirror URL")
+		}
+	}
 	return nil
 }
 
@@ -63,6 +72,22 @@ func (handler *Handler) registryUpdate(w http.ResponseWriter, r *http.Request) *
 		registry.URL = payload.URL
 	}
 
+	if payload.Type != nil {
+		registry.Type = portainer.RegistryType(*payload.Type)
+	}
+
+	if payload.Ecr != nil {
+		registry.Ecr = *payload.Ecr
+	}
+
+	if payload.Azure != nil {
+		registry.Azure = *payload.Azure
+	}
+
+	if payload.Mirrors != nil {
+		registry.Mirrors = payload.Mirrors
+	}
+
 	if payload.Authentication {
 		registry.Authentication = true
 		registry.Username = payload.Username
@@ -73,6 +98,11 @@ func (handler *Handler) registryUpdate(w http.ResponseWriter, r *http.Request) *
 		registry.Password = ""
 	}
 
+	err = validateRegistryType(registry.Type, registry.URL, registry.Authentication, &registry.Ecr, &registry.Azure)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
 	err = handler.RegistryService.UpdateRegistry(registry.ID, registry)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist registry changes inside the database", err}
@@ -13,6 +13,7 @@ import (
 type Handler struct {
 	*mux.Router
 	ResourceControlService portainer.ResourceControlService
+	StackService           portainer.StackService
 }
 
 // NewHandler creates a handler to manage resource control operations.
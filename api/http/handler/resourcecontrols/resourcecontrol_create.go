@@ -6,6 +6,7 @@ import (
 	"github.com/asaskevich/govalidator"
 	"github.com/portainer/portainer"
 	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/proxy"
 	"github.com/portainer/portainer/http/request"
 	"github.com/portainer/portainer/http/response"
 	"github.com/portainer/portainer/http/security"
@@ -18,6 +19,7 @@ type resourceControlCreatePayload struct {
 	Users              []int
 	Teams              []int
 	SubResourceIDs     []string
+	EndpointID         int
 }
 
 func (payload *resourceControlCreatePayload) Validate(r *http.Request) error {
@@ -63,6 +65,14 @@ func (handler *Handler) resourceControlCreate(w http.ResponseWriter, r *http.Req
 		return &httperror.HandlerError{http.StatusBadRequest, "Invalid type value. Value must be one of: container, service, volume, network, secret, stack or config", portainer.ErrInvalidResourceControlType}
 	}
 
+	if resourceControlType == portainer.StackResourceControl {
+		resourceID, err := handler.stackResourceControlID(payload.ResourceID, portainer.EndpointID(payload.EndpointID))
+		if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid stack resource control declaration", err}
+		}
+		payload.ResourceID = resourceID
+	}
+
 	rc, err := handler.ResourceControlService.ResourceControlByResourceID(payload.ResourceID)
 	if err != nil && err != portainer.ErrObjectNotFound {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve resource controls from the database", err}
@@ -114,3 +124,41 @@ func (handler *Handler) resourceControlCreate(w http.ResponseWriter, r *http.Req
 
 	return response.JSON(w, resourceControl)
 }
+
+// stackResourceControlID returns the identifier used for the resource control of a stack. The resource identifier
+// can either be the name of a stack or the identifier returned by proxy.StackResourceControlID.
+// The endpoint identifier is required when the name is used by stacks associated to different endpoints
+// or when the stack is not managed by Portainer.
+// The resource identifier is resolved as a stack name first, it is only used as a resource control identifier
+// when no stack is using this name so that a stack named after the identifier of another stack cannot be mistaken for it.
+func (handler *Handler) stackResourceControlID(resourceID string, endpointID portainer.EndpointID) (string, error) {
+	stacks, err := handler.StackService.Stacks()
+	if err != nil {
+		return "", err
+	}
+
+	isResourceControlID := false
+	var matchingStacks []portainer.Stack
+	for _, stack := range stacks {
+		if proxy.StackResourceControlID(&stack) == resourceID {
+			isResourceControlID = true
+		}
+
+		if stack.Name == resourceID && (endpointID == 0 || stack.EndpointID == endpointID) {
+			matchingStacks = append(matchingStacks, stack)
+		}
+	}
+
+	if len(matchingStacks) == 1 {
+		return proxy.StackResourceControlID(&matchingStacks[0]), nil
+	} else if len(matchingStacks) > 1 {
+		return "", portainer.Error("Multiple stacks are using this name. Must specify EndpointID")
+	} else if isResourceControlID {
+		return resourceID, nil
+	} else if endpointID == 0 {
+		return "", portainer.Error("Unable to find a stack with this name. Must specify EndpointID")
+	}
+
+	externalStack := &portainer.Stack{Name: resourceID, EndpointID: endpointID}
+	return proxy.StackResourceControlID(externalStack), nil
+}
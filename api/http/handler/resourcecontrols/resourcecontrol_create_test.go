@@ -0,0 +1,58 @@
+package resourcecontrols
+
+import (
+	"testing"
+
+	"github.com/portainer/portainer"
+)
+
+type testStackService struct {
+	portainer.StackService
+	stacks []portainer.Stack
+}
+
+func (service *testStackService) Stacks() ([]portainer.Stack, error) {
+	return service.stacks, nil
+}
+
+func TestStackResourceControlID(t *testing.T) {
+	stacks := []portainer.Stack{
+		{ID: 1, Name: "foo", EndpointID: 1},
+		{ID: 2, Name: "foo_1", EndpointID: 3},
+		{ID: 3, Name: "web", EndpointID: 1},
+		{ID: 4, Name: "web", EndpointID: 2},
+		{ID: 5, Name: "api", EndpointID: 1, Type: portainer.DockerSwarmStack, SwarmID: "swarm1"},
+	}
+
+	tests := []struct {
+		name          string
+		resourceID    string
+		endpointID    portainer.EndpointID
+		expected      string
+		expectedError bool
+	}{
+		{name: "Stack name", resourceID: "foo", endpointID: 1, expected: "foo_1"},
+		{name: "Stack named after the identifier of another stack", resourceID: "foo_1", endpointID: 3, expected: "foo_1_3"},
+		{name: "Resource control identifier", resourceID: "web_2", endpointID: 2, expected: "web_2"},
+		{name: "Swarm stack resource control identifier", resourceID: "api_swarm1", expected: "api_swarm1"},
+		{name: "Stack name used on several endpoints", resourceID: "web", expectedError: true},
+		{name: "Stack name used on several endpoints with an endpoint", resourceID: "web", endpointID: 2, expected: "web_2"},
+		{name: "External stack", resourceID: "external", endpointID: 2, expected: "external_2"},
+		{name: "External stack without endpoint", resourceID: "external", expectedError: true},
+	}
+
+	handler := &Handler{StackService: &testStackService{stacks: stacks}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resourceID, err := handler.stackResourceControlID(test.resourceID, test.endpointID)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("Unexpected error: got %v, expected an error: %t", err, test.expectedError)
+			}
+
+			if resourceID != test.expected {
+				t.Errorf("Unexpected resource identifier: got %s want %s", resourceID, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package schedules
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/cron"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+	robfigcron "github.com/robfig/cron"
+)
+
+type scheduleCreatePayload struct {
+	Name           string
+	CronExpression string
+	JobType        int
+	Image          string
+	Command        string
+	ScriptContent  string
+	Endpoints      []portainer.EndpointID
+	AllNodes       bool
+}
+
+func (payload *scheduleCreatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Name) {
+		return portainer.Error("Invalid schedule name")
+	}
+	if !isValidCronExpression(payload.CronExpression) {
+		return portainer.Error("Invalid cron expression. Must use the standard format: minute, hour, day of month, month and day of week")
+	}
+	if payload.JobType != int(portainer.ContainerScheduleJob) && payload.JobType != int(portainer.ScriptScheduleJob) {
+		return portainer.Error("Invalid job type value. Value must be one of: 1 (container) or 2 (script)")
+	}
+	if payload.JobType == int(portainer.ContainerScheduleJob) && govalidator.IsNull(payload.Image) {
+		return portainer.Error("Invalid image. An image is required to run a container job")
+	}
+	if payload.JobType == int(portainer.ScriptScheduleJob) && govalidator.IsNull(payload.ScriptContent) {
+		return portainer.Error("Invalid script content")
+	}
+	if len(payload.Endpoints) == 0 {
+		return portainer.Error("Invalid endpoints. At least one endpoint must be specified")
+	}
+	return nil
+}
+
+// POST request on /api/schedules
+func (handler *Handler) scheduleCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload scheduleCreatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	endpointError := handler.validateEndpoints(payload.Endpoints)
+	if endpointError != nil {
+		return endpointError
+	}
+
+	schedule := &portainer.Schedule{
+		ID:             portainer.ScheduleID(handler.ScheduleService.GetNextIdentifier()),
+		Name:           payload.Name,
+		CronExpression: payload.CronExpression,
+		JobType:        portainer.ScheduleJobType(payload.JobType),
+		Image:          payload.Image,
+		Command:        payload.Command,
+		Endpoints:      payload.Endpoints,
+		AllNodes:       payload.AllNodes,
+		Created:        time.Now().Unix(),
+	}
+
+	if schedule.JobType == portainer.ScriptScheduleJob {
+		schedule.ScriptPath, err = handler.FileService.StoreScheduleScriptFromBytes(strconv.Itoa(int(schedule.ID)), []byte(payload.ScriptContent))
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the script of the schedule on disk", err}
+		}
+	}
+
+	err = handler.ScheduleService.CreateSchedule(schedule)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the schedule inside the database", err}
+	}
+
+	err = handler.JobScheduler.ScheduleJob(cron.ScheduleJobName(schedule.ID), schedule.CronExpression, cron.NewScheduleJob(schedule.ID, handler.ScheduleService, handler.ScheduleRunner))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to schedule the job", err}
+	}
+
+	return response.JSON(w, schedule)
+}
+
+// validateEndpoints ensures that the endpoints exist and can run the job of a schedule.
+func (handler *Handler) validateEndpoints(endpointIDs []portainer.EndpointID) *httperror.HandlerError {
+	for _, endpointID := range endpointIDs {
+		endpoint, err := handler.EndpointService.Endpoint(endpointID)
+		if err == portainer.ErrObjectNotFound {
+			return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+		}
+
+		if endpoint.Type == portainer.AzureEnvironment {
+			return &httperror.HandlerError{http.StatusBadRequest, "Scheduled jobs cannot be run on the endpoint " + endpoint.Name, portainer.ErrEndpointTypeNotSupported}
+		}
+	}
+	return nil
+}
+
+func isValidCronExpression(expression string) bool {
+	_, err := robfigcron.ParseStandard(expression)
+	return err == nil
+}
@@ -0,0 +1,54 @@
+package schedules
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/cron"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// DELETE request on /api/schedules/:id
+// The execution history and the script of the schedule are removed as well.
+func (handler *Handler) scheduleDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	scheduleID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid schedule identifier route variable", err}
+	}
+
+	schedule, err := handler.ScheduleService.Schedule(portainer.ScheduleID(scheduleID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a schedule with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a schedule with the specified identifier inside the database", err}
+	}
+
+	handler.JobScheduler.UnscheduleJob(cron.ScheduleJobName(schedule.ID))
+
+	err = handler.ScheduleService.DeleteSchedule(schedule.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the schedule from the database", err}
+	}
+
+	executions, err := handler.ScheduleExecutionService.ScheduleExecutionsBySchedule(schedule.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the executions of the schedule from the database", err}
+	}
+
+	for _, execution := range executions {
+		err = handler.ScheduleExecutionService.DeleteScheduleExecution(execution.ID)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the executions of the schedule from the database", err}
+		}
+	}
+
+	err = handler.FileService.RemoveDirectory(handler.FileService.GetScheduleFolder(strconv.Itoa(int(schedule.ID))))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the files of the schedule from disk", err}
+	}
+
+	return response.Empty(w)
+}
@@ -0,0 +1,36 @@
+package schedules
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// POST request on /api/schedules/:id/run
+// The job is run in the background, its executions are available in the execution history of the schedule.
+func (handler *Handler) scheduleRun(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	scheduleID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid schedule identifier route variable", err}
+	}
+
+	schedule, err := handler.ScheduleService.Schedule(portainer.ScheduleID(scheduleID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a schedule with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a schedule with the specified identifier inside the database", err}
+	}
+
+	go func() {
+		_, err := handler.ScheduleRunner.Run(schedule)
+		if err != nil {
+			log.Printf("http error: Unable to run the job of a schedule (schedule=%d) (err=%s)\n", schedule.ID, err)
+		}
+	}()
+
+	return response.Empty(w)
+}
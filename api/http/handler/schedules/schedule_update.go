@@ -0,0 +1,110 @@
+package schedules
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/cron"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+type scheduleUpdatePayload struct {
+	Name           string
+	CronExpression string
+	Image          string
+	Command        *string
+	ScriptContent  string
+	Endpoints      []portainer.EndpointID
+	AllNodes       *bool
+}
+
+func (payload *scheduleUpdatePayload) Validate(r *http.Request) error {
+	if payload.CronExpression != "" && !isValidCronExpression(payload.CronExpression) {
+		return portainer.Error("Invalid cron expression. Must use the standard format: minute, hour, day of month, month and day of week")
+	}
+	if payload.Endpoints != nil && len(payload.Endpoints) == 0 {
+		return portainer.Error("Invalid endpoints. At least one endpoint must be specified")
+	}
+	return nil
+}
+
+// PUT request on /api/schedules/:id
+// The job type of a schedule cannot be changed.
+func (handler *Handler) scheduleUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	scheduleID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid schedule identifier route variable", err}
+	}
+
+	var payload scheduleUpdatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	schedule, err := handler.ScheduleService.Schedule(portainer.ScheduleID(scheduleID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a schedule with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a schedule with the specified identifier inside the database", err}
+	}
+
+	if payload.Name != "" {
+		schedule.Name = payload.Name
+	}
+
+	if payload.Image != "" {
+		schedule.Image = payload.Image
+	}
+
+	if payload.Command != nil {
+		schedule.Command = *payload.Command
+	}
+
+	if payload.AllNodes != nil {
+		schedule.AllNodes = *payload.AllNodes
+	}
+
+	if payload.Endpoints != nil {
+		endpointError := handler.validateEndpoints(payload.Endpoints)
+		if endpointError != nil {
+			return endpointError
+		}
+		schedule.Endpoints = payload.Endpoints
+	}
+
+	if payload.ScriptContent != "" {
+		if schedule.JobType != portainer.ScriptScheduleJob {
+			return &httperror.HandlerError{http.StatusBadRequest, "The job of the schedule does not run a script", portainer.ErrScheduleNotScript}
+		}
+
+		_, err = handler.FileService.StoreScheduleScriptFromBytes(strconv.Itoa(int(schedule.ID)), []byte(payload.ScriptContent))
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the script of the schedule on disk", err}
+		}
+	}
+
+	rescheduleJob := payload.CronExpression != "" && payload.CronExpression != schedule.CronExpression
+	if rescheduleJob {
+		schedule.CronExpression = payload.CronExpression
+	}
+
+	err = handler.ScheduleService.UpdateSchedule(schedule.ID, schedule)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the schedule changes inside the database", err}
+	}
+
+	// The job of a schedule always runs its latest definition, it only needs
+	// to be rescheduled when the cron expression changes.
+	if rescheduleJob {
+		err = handler.JobScheduler.RescheduleJob(cron.ScheduleJobName(schedule.ID), schedule.CronExpression)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to schedule the job", err}
+		}
+	}
+
+	return response.JSON(w, schedule)
+}
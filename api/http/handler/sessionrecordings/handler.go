@@ -0,0 +1,34 @@
+package sessionrecordings
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/security"
+)
+
+// Handler is the HTTP handler used to handle session recording operations.
+type Handler struct {
+	*mux.Router
+	SessionRecordingService portainer.SessionRecordingService
+	FileService             portainer.FileService
+}
+
+// NewHandler creates a handler to manage session recording operations.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/session_recordings",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.sessionRecordingList))).Methods(http.MethodGet)
+	h.Handle("/session_recordings/{id}",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.sessionRecordingInspect))).Methods(http.MethodGet)
+	h.Handle("/session_recordings/{id}/file",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.sessionRecordingFile))).Methods(http.MethodGet)
+	h.Handle("/session_recordings/{id}",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.sessionRecordingDelete))).Methods(http.MethodDelete)
+
+	return h
+}
@@ -0,0 +1,38 @@
+package sessionrecordings
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// DELETE request on /api/session_recordings/:id
+func (handler *Handler) sessionRecordingDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	recordingID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid session recording identifier route variable", err}
+	}
+
+	recording, err := handler.SessionRecordingService.SessionRecording(portainer.SessionRecordingID(recordingID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a session recording with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a session recording with the specified identifier inside the database", err}
+	}
+
+	err = handler.FileService.DeleteSessionRecordingFile(strconv.Itoa(int(recording.ID)))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the session recording file from disk", err}
+	}
+
+	err = handler.SessionRecordingService.DeleteSessionRecording(recording.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the session recording from the database", err}
+	}
+
+	return response.Empty(w)
+}
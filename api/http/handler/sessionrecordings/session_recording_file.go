@@ -0,0 +1,41 @@
+package sessionrecordings
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+)
+
+// GET request on /api/session_recordings/:id/file
+// The recording is returned in the asciicast v2 format.
+func (handler *Handler) sessionRecordingFile(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	recordingID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid session recording identifier route variable", err}
+	}
+
+	recording, err := handler.SessionRecordingService.SessionRecording(portainer.SessionRecordingID(recordingID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a session recording with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a session recording with the specified identifier inside the database", err}
+	}
+
+	recordingIdentifier := strconv.Itoa(int(recording.ID))
+	recordingPath := handler.FileService.GetSessionRecordingPath(recordingIdentifier)
+
+	exists, err := handler.FileService.FileExists(recordingPath)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve session recording file from disk", err}
+	} else if !exists {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find the session recording file on disk", portainer.ErrObjectNotFound}
+	}
+
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Header().Set("Content-Disposition", "attachment; filename=session-"+recordingIdentifier+".cast")
+	http.ServeFile(w, r, recordingPath)
+	return nil
+}
@@ -0,0 +1,34 @@
+package sessionrecordings
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// GET request on /api/session_recordings?endpointId=<endpointID>&userId=<userID>
+func (handler *Handler) sessionRecordingList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, _ := request.RetrieveNumericQueryParameter(r, "endpointId", true)
+	userID, _ := request.RetrieveNumericQueryParameter(r, "userId", true)
+
+	recordings, err := handler.SessionRecordingService.SessionRecordings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve session recordings from the database", err}
+	}
+
+	filteredRecordings := make([]portainer.SessionRecording, 0)
+	for _, recording := range recordings {
+		if endpointID != 0 && recording.EndpointID != portainer.EndpointID(endpointID) {
+			continue
+		}
+		if userID != 0 && recording.UserID != portainer.UserID(userID) {
+			continue
+		}
+		filteredRecordings = append(filteredRecordings, recording)
+	}
+
+	return response.JSON(w, filteredRecordings)
+}
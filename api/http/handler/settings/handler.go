@@ -11,6 +11,9 @@ import (
 
 func hideFields(settings *portainer.Settings) {
 	settings.LDAPSettings.Password = ""
+	for idx := range settings.TemplateSources {
+		settings.TemplateSources[idx].RepositoryPassword = ""
+	}
 }
 
 // Handler is the HTTP handler used to handle settings operations.
@@ -2,9 +2,11 @@ package settings
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/asaskevich/govalidator"
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/cron"
 	"github.com/portainer/portainer/filesystem"
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/request"
@@ -19,6 +21,9 @@ type settingsUpdatePayload struct {
 	AllowBindMountsForRegularUsers     *bool
 	AllowPrivilegedModeForRegularUsers *bool
 	SnapshotInterval                   *string
+	SessionRecording                   *portainer.SessionRecordingSettings
+	ConsoleSessions                    *portainer.ConsoleSessionSettings
+	TemplateSources                    []portainer.TemplateSource
 }
 
 func (payload *settingsUpdatePayload) Validate(r *http.Request) error {
@@ -28,6 +33,40 @@ func (payload *settingsUpdatePayload) Validate(r *http.Request) error {
 	if payload.LogoURL != nil && *payload.LogoURL != "" && !govalidator.IsURL(*payload.LogoURL) {
 		return portainer.Error("Invalid logo URL. Must correspond to a valid URL format")
 	}
+	if payload.SnapshotInterval != nil {
+		snapshotInterval, err := time.ParseDuration(*payload.SnapshotInterval)
+		if err != nil || snapshotInterval <= 0 {
+			return portainer.Error("Invalid snapshot interval. Must correspond to a valid positive duration such as 5m")
+		}
+	}
+	if payload.SessionRecording != nil && payload.SessionRecording.RetentionDays < 0 {
+		return portainer.Error("Invalid session recording retention period. Value must be greater than or equal to 0")
+	}
+	if payload.ConsoleSessions != nil {
+		if payload.ConsoleSessions.MaxSessionsPerUser < 0 {
+			return portainer.Error("Invalid maximum number of console sessions per user. Value must be greater than or equal to 0")
+		}
+		if payload.ConsoleSessions.IdleTimeout != "" {
+			idleTimeout, err := time.ParseDuration(payload.ConsoleSessions.IdleTimeout)
+			if err != nil || idleTimeout <= 0 {
+				return portainer.Error("Invalid console session idle timeout. Must correspond to a valid positive duration such as 30m")
+			}
+		}
+	}
+	for _, source := range payload.TemplateSources {
+		if govalidator.IsNull(source.Name) {
+			return portainer.Error("Invalid template source name")
+		}
+		if source.Type != portainer.URLTemplateSource && source.Type != portainer.GitTemplateSource {
+			return portainer.Error("Invalid template source type. Value must be one of: 1 (URL) or 2 (Git repository)")
+		}
+		if govalidator.IsNull(source.URL) || !govalidator.IsURL(source.URL) {
+			return portainer.Error("Invalid template source URL. Must correspond to a valid URL format")
+		}
+		if source.Type == portainer.GitTemplateSource && source.RepositoryAuthentication && govalidator.IsNull(source.RepositoryUsername) {
+			return portainer.Error("Invalid template source repository credentials. Username is mandatory when repository authentication is enabled")
+		}
+	}
 	return nil
 }
 
@@ -75,7 +114,24 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 
 	if payload.SnapshotInterval != nil && *payload.SnapshotInterval != settings.SnapshotInterval {
 		settings.SnapshotInterval = *payload.SnapshotInterval
-		handler.JobScheduler.UpdateSnapshotJob(settings.SnapshotInterval)
+
+		// The snapshot job is not scheduled when snapshots are disabled
+		err = handler.JobScheduler.RescheduleJob(cron.EndpointSnapshotJobName, "@every "+settings.SnapshotInterval)
+		if err != nil && err != portainer.ErrJobNotFound {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to reschedule the snapshot job", err}
+		}
+	}
+
+	if payload.SessionRecording != nil {
+		settings.SessionRecording = *payload.SessionRecording
+	}
+
+	if payload.ConsoleSessions != nil {
+		settings.ConsoleSessions = *payload.ConsoleSessions
+	}
+
+	if payload.TemplateSources != nil {
+		settings.TemplateSources = mergeTemplateSources(settings.TemplateSources, payload.TemplateSources)
 	}
 
 	tlsError := handler.updateTLS(settings)
@@ -88,9 +144,50 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist settings changes inside the database", err}
 	}
 
+	hideFields(settings)
 	return response.JSON(w, settings)
 }
 
+// mergeTemplateSources returns the template sources defined in the payload. Existing sources are matched using their identifier,
+// their repository password is preserved when not specified as well as the outcome of their last refresh.
+// New sources are associated to a new identifier.
+func mergeTemplateSources(existingSources, sources []portainer.TemplateSource) []portainer.TemplateSource {
+	var lastID portainer.TemplateSourceID
+	existing := make(map[portainer.TemplateSourceID]portainer.TemplateSource)
+	for _, source := range existingSources {
+		existing[source.ID] = source
+		if source.ID > lastID {
+			lastID = source.ID
+		}
+	}
+
+	mergedSources := make([]portainer.TemplateSource, 0, len(sources))
+	for _, source := range sources {
+		existingSource, ok := existing[source.ID]
+		if ok && source.ID != 0 {
+			if source.RepositoryPassword == "" {
+				source.RepositoryPassword = existingSource.RepositoryPassword
+			}
+			source.LastRefresh = existingSource.LastRefresh
+			source.LastError = existingSource.LastError
+		} else {
+			lastID++
+			source.ID = lastID
+			source.LastRefresh = 0
+			source.LastError = ""
+		}
+
+		if source.Type != portainer.GitTemplateSource || !source.RepositoryAuthentication {
+			source.RepositoryUsername = ""
+			source.RepositoryPassword = ""
+		}
+
+		mergedSources = append(mergedSources, source)
+	}
+
+	return mergedSources
+}
+
 func (handler *Handler) updateTLS(settings *portainer.Settings) *httperror.HandlerError {
 	if (settings.LDAPSettings.TLSConfig.TLS || settings.LDAPSettings.StartTLS) && !settings.LDAPSettings.TLSConfig.TLSSkipVerify {
 		caCertPath, _ := handler.FileService.GetPathForTLSFile(filesystem.LDAPStorePath, portainer.TLSFileCA)
@@ -0,0 +1,157 @@
+package stacks
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/portainer/portainer"
+)
+
+// stackFilePayload represents the name and the content of an additional Compose file.
+type stackFilePayload struct {
+	Name    string
+	Content string
+}
+
+// validateAdditionalFiles ensures that the additional files are stored directly inside the stack project folder
+// and that their names are unique and different from the entry point.
+func validateAdditionalFiles(files []stackFilePayload, entryPoint string) error {
+	names := make(map[string]bool)
+	names[entryPoint] = true
+
+	for _, file := range files {
+		if govalidator.IsNull(file.Name) || file.Name != path.Base(file.Name) || strings.ContainsAny(file.Name, `/\`) || file.Name == ".." || file.Name == "." {
+			return portainer.Error("Invalid additional file name: " + file.Name)
+		}
+		if names[file.Name] {
+			return portainer.Error("Duplicate additional file name: " + file.Name)
+		}
+		if govalidator.IsNull(file.Content) {
+			return portainer.Error("Invalid additional file content: " + file.Name)
+		}
+		names[file.Name] = true
+	}
+
+	return nil
+}
+
+// validateAdditionalFilePaths ensures that the paths of the additional files taken from a git repository
+// are relative to the repository.
+func validateAdditionalFilePaths(filePaths []string) error {
+	for _, filePath := range filePaths {
+		cleanPath := path.Clean(filePath)
+		if govalidator.IsNull(filePath) || path.IsAbs(cleanPath) || cleanPath == ".." || strings.HasPrefix(cleanPath, "../") {
+			return portainer.Error("Invalid additional file path: " + filePath)
+		}
+	}
+	return nil
+}
+
+// storeAdditionalFiles stores the additional files in the stack project folder and returns their names.
+func (handler *Handler) storeAdditionalFiles(stackFolder string, files []stackFilePayload) ([]string, error) {
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		_, err := handler.FileService.StoreStackFileFromBytes(stackFolder, file.Name, []byte(file.Content))
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, file.Name)
+	}
+	return names, nil
+}
+
+// updateAdditionalFiles stores the content of the specified additional files in the stack project folder and
+// replaces the additional files of the stack with them, in the specified order. It returns the files that are no
+// longer associated to the stack, they are kept on disk until removeUnusedAdditionalFiles is called.
+// Nothing is done when no additional file is specified, an empty list removes every additional file.
+// The additional files are expected to be validated using validateAdditionalFiles.
+func (handler *Handler) updateAdditionalFiles(stack *portainer.Stack, stackFolder string, files []stackFilePayload) ([]string, error) {
+	if files == nil {
+		return nil, nil
+	}
+
+	names, err := handler.storeAdditionalFiles(stackFolder, files)
+	if err != nil {
+		return nil, err
+	}
+
+	droppedFiles := make([]string, 0)
+	for _, file := range stack.AdditionalFiles {
+		if !containsFile(names, file) {
+			droppedFiles = append(droppedFiles, file)
+		}
+	}
+
+	stack.AdditionalFiles = names
+	return droppedFiles, nil
+}
+
+// removeUnusedAdditionalFiles removes from the stack project folder the specified files that are not
+// associated to the stack anymore. Errors are logged.
+func (handler *Handler) removeUnusedAdditionalFiles(stack *portainer.Stack, files []string) {
+	stackFolder := strconv.Itoa(int(stack.ID))
+	for _, file := range files {
+		if containsFile(stack.AdditionalFiles, file) || file != path.Base(file) {
+			continue
+		}
+
+		err := handler.FileService.DeleteStackFile(stackFolder, file)
+		if err != nil {
+			log.Printf("http error: Unable to remove additional file of stack (stack=%d, file=%s) (err=%s)\n", stack.ID, file, err)
+		}
+	}
+}
+
+func containsFile(files []string, name string) bool {
+	for _, file := range files {
+		if file == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAdditionalFilesExist ensures that the additional files are available in the stack project folder.
+func (handler *Handler) checkAdditionalFilesExist(stack *portainer.Stack) error {
+	for _, file := range stack.AdditionalFiles {
+		exists, err := handler.FileService.FileExists(filepath.Join(stack.ProjectPath, filepath.FromSlash(file)))
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return portainer.Error("Unable to find the additional file in the repository: " + file)
+		}
+	}
+	return nil
+}
+
+// retrieveAdditionalFilesFromMultiPartForm returns the additional files uploaded using the AdditionalFiles form field.
+func retrieveAdditionalFilesFromMultiPartForm(r *http.Request) ([]stackFilePayload, error) {
+	files := make([]stackFilePayload, 0)
+	if r.MultipartForm == nil {
+		return files, nil
+	}
+
+	for _, header := range r.MultipartForm.File["AdditionalFiles"] {
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := ioutil.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, stackFilePayload{Name: header.Filename, Content: string(content)})
+	}
+
+	return files, nil
+}
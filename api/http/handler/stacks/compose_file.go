@@ -0,0 +1,144 @@
+package stacks
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	swarmComposeFileVersion   = "3.5"
+	composeComposeFileVersion = "2"
+)
+
+var plainYAMLKeyRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_./-]*$`)
+
+// composeFileSections represents the order in which the top-level sections of a Compose file are written.
+var composeFileSections = []string{"version", "services", "networks", "volumes", "secrets", "configs"}
+
+// marshalComposeFile encodes a Compose file definition as YAML. Top-level sections are written in the usual
+// Compose file order, keys of nested mappings are sorted and every string value is double-quoted. The dollar signs
+// of string values are escaped so that the values are not interpolated when the Compose file is deployed.
+func marshalComposeFile(definition map[string]interface{}) []byte {
+	buffer := &bytes.Buffer{}
+
+	for _, section := range composeFileSections {
+		value, ok := definition[section]
+		if !ok {
+			continue
+		}
+
+		if mapping, ok := value.(map[string]interface{}); ok && len(mapping) == 0 {
+			continue
+		}
+
+		writeYAMLEntry(buffer, section, value, 0)
+	}
+
+	return buffer.Bytes()
+}
+
+func writeYAMLEntry(buffer *bytes.Buffer, key string, value interface{}, indent int) {
+	buffer.WriteString(strings.Repeat(" ", indent) + yamlKey(key) + ":")
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			buffer.WriteString(" {}\n")
+			return
+		}
+		buffer.WriteString("\n")
+		writeYAMLMapping(buffer, v, indent+2)
+	case []interface{}:
+		if len(v) == 0 {
+			buffer.WriteString(" []\n")
+			return
+		}
+		buffer.WriteString("\n")
+		writeYAMLSequence(buffer, v, indent+2)
+	default:
+		buffer.WriteString(" " + yamlScalar(v) + "\n")
+	}
+}
+
+func writeYAMLMapping(buffer *bytes.Buffer, mapping map[string]interface{}, indent int) {
+	keys := make([]string, 0, len(mapping))
+	for key := range mapping {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		writeYAMLEntry(buffer, key, mapping[key], indent)
+	}
+}
+
+func writeYAMLSequence(buffer *bytes.Buffer, sequence []interface{}, indent int) {
+	for _, item := range sequence {
+		mapping, ok := item.(map[string]interface{})
+		if !ok || len(mapping) == 0 {
+			buffer.WriteString(strings.Repeat(" ", indent) + "- " + yamlScalar(item) + "\n")
+			continue
+		}
+
+		// The first entry of a mapping inside a sequence is written on the same line as the dash
+		itemBuffer := &bytes.Buffer{}
+		writeYAMLMapping(itemBuffer, mapping, indent+2)
+		buffer.WriteString(strings.Repeat(" ", indent) + "- ")
+		buffer.Write(itemBuffer.Bytes()[indent+2:])
+	}
+}
+
+func yamlKey(key string) string {
+	if plainYAMLKeyRe.MatchString(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+func yamlScalar(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(strings.Replace(v, "$", "$$", -1))
+	case map[string]interface{}:
+		return "{}"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// stringSequence converts a slice of strings to a sequence that can be used inside a Compose file definition.
+func stringSequence(values []string) []interface{} {
+	sequence := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		sequence = append(sequence, value)
+	}
+	return sequence
+}
+
+// stringMapping converts a map of strings to a mapping that can be used inside a Compose file definition.
+// Entries with a key starting with one of the ignored prefixes are discarded.
+func stringMapping(values map[string]string, ignoredPrefixes ...string) map[string]interface{} {
+	mapping := make(map[string]interface{})
+
+	for key, value := range values {
+		ignored := false
+		for _, prefix := range ignoredPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				ignored = true
+				break
+			}
+		}
+
+		if !ignored {
+			mapping[key] = value
+		}
+	}
+
+	return mapping
+}
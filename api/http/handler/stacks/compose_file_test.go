@@ -0,0 +1,122 @@
+package stacks
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMarshalComposeFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition map[string]interface{}
+		expected   string
+	}{
+		{
+			name:       "Empty definition",
+			definition: map[string]interface{}{},
+			expected:   "",
+		},
+		{
+			name: "Sections in Compose file order",
+			definition: map[string]interface{}{
+				"volumes":  map[string]interface{}{"data": map[string]interface{}{}},
+				"services": map[string]interface{}{"web": map[string]interface{}{"image": "nginx:latest"}},
+				"version":  "3.5",
+			},
+			expected: "version: \"3.5\"\n" +
+				"services:\n" +
+				"  web:\n" +
+				"    image: \"nginx:latest\"\n" +
+				"volumes:\n" +
+				"  data: {}\n",
+		},
+		{
+			name: "Empty sections are omitted",
+			definition: map[string]interface{}{
+				"version":  "2",
+				"networks": map[string]interface{}{},
+			},
+			expected: "version: \"2\"\n",
+		},
+		{
+			name: "Dollar signs are escaped",
+			definition: map[string]interface{}{
+				"services": map[string]interface{}{
+					"web": map[string]interface{}{
+						"command":     []interface{}{"sh", "-c", "echo $HOME ${PATH}"},
+						"environment": map[string]interface{}{"PASSWORD": "pa$$word"},
+					},
+				},
+			},
+			expected: "services:\n" +
+				"  web:\n" +
+				"    command:\n" +
+				"      - \"sh\"\n" +
+				"      - \"-c\"\n" +
+				"      - \"echo $$HOME $${PATH}\"\n" +
+				"    environment:\n" +
+				"      PASSWORD: \"pa$$$$word\"\n",
+		},
+		{
+			name: "Mappings inside sequences and quoted keys",
+			definition: map[string]interface{}{
+				"services": map[string]interface{}{
+					"web": map[string]interface{}{
+						"labels":  map[string]interface{}{"my label": "value"},
+						"ports":   []interface{}{map[string]interface{}{"target": 80, "published": 8080}},
+						"volumes": []interface{}{},
+						"init":    true,
+						"user":    nil,
+					},
+				},
+			},
+			expected: "services:\n" +
+				"  web:\n" +
+				"    init: true\n" +
+				"    labels:\n" +
+				"      \"my label\": \"value\"\n" +
+				"    ports:\n" +
+				"      - published: 8080\n" +
+				"        target: 80\n" +
+				"    user: null\n" +
+				"    volumes: []\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := string(marshalComposeFile(test.definition))
+			if result != test.expected {
+				t.Errorf("Unexpected Compose file, got:\n%s\nwant:\n%s", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestYAMLScalarRoundTrip(t *testing.T) {
+	tests := []string{
+		"",
+		"nginx:latest",
+		"$HOME",
+		"${VARIABLE:-default}",
+		"pa$$word",
+		"line\nbreak \"quoted\" \\ backslash",
+		"unicode é ✓",
+	}
+
+	for _, value := range tests {
+		t.Run(value, func(t *testing.T) {
+			unquoted, err := strconv.Unquote(yamlScalar(value))
+			if err != nil {
+				t.Fatalf("Unable to unquote scalar: %s", err)
+			}
+
+			// Compose replaces $$ with $ when interpolating the file
+			result := strings.Replace(unquoted, "$$", "$", -1)
+			if result != value {
+				t.Errorf("Value did not round-trip, got %q want %q", result, value)
+			}
+		})
+	}
+}
@@ -1,16 +1,16 @@
 package stacks
 
 import (
+	"io"
+	"log"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/asaskevich/govalidator"
 	"github.com/portainer/portainer"
 	"github.com/portainer/portainer/filesystem"
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/request"
-	"github.com/portainer/portainer/http/response"
 	"github.com/portainer/portainer/http/security"
 )
 
@@ -18,6 +18,8 @@ type composeStackFromFileContentPayload struct {
 	Name             string
 	StackFileContent string
 	Env              []portainer.Pair
+	EnvironmentSets  []portainer.EnvironmentSetID
+	AdditionalFiles  []stackFilePayload
 }
 
 func (payload *composeStackFromFileContentPayload) Validate(r *http.Request) error {
@@ -27,7 +29,7 @@ func (payload *composeStackFromFileContentPayload) Validate(r *http.Request) err
 	if govalidator.IsNull(payload.StackFileContent) {
 		return portainer.Error("Invalid stack file content")
 	}
-	return nil
+	return validateAdditionalFiles(payload.AdditionalFiles, filesystem.ComposeFileDefaultName)
 }
 
 func (handler *Handler) createComposeStackFromFileContent(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint) *httperror.HandlerError {
@@ -37,25 +39,30 @@ func (handler *Handler) createComposeStackFromFileContent(w http.ResponseWriter,
 		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
 	}
 
-	stacks, err := handler.StackService.Stacks()
+	isUnique, err := handler.isUniqueStackName(0, payload.Name, endpoint.ID, "")
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stacks from the database", err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to check for stack name uniqueness inside the database", err}
+	}
+	if !isUnique {
+		return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
 	}
 
-	for _, stack := range stacks {
-		if strings.EqualFold(stack.Name, payload.Name) {
-			return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
-		}
+	validationError := handler.validateEnvironmentSets(r, payload.EnvironmentSets, nil, endpoint)
+	if validationError != nil {
+		return validationError
 	}
 
 	stackID := handler.StackService.GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:         portainer.StackID(stackID),
-		Name:       payload.Name,
-		Type:       portainer.DockerComposeStack,
-		EndpointID: endpoint.ID,
-		EntryPoint: filesystem.ComposeFileDefaultName,
-		Env:        payload.Env,
+		ID:              portainer.StackID(stackID),
+		Name:            payload.Name,
+		Type:            portainer.DockerComposeStack,
+		EndpointID:      endpoint.ID,
+		EntryPoint:      filesystem.ComposeFileDefaultName,
+		Env:             payload.Env,
+		EnvironmentSets: environmentSetsOrEmpty(payload.EnvironmentSets),
+		AdditionalFiles: []string{},
+		Status:          portainer.StackStatusActive,
 	}
 
 	stackFolder := strconv.Itoa(int(stack.ID))
@@ -68,23 +75,19 @@ func (handler *Handler) createComposeStackFromFileContent(w http.ResponseWriter,
 	doCleanUp := true
 	defer handler.cleanUp(stack, &doCleanUp)
 
-	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
-	if configErr != nil {
-		return configErr
-	}
-
-	err = handler.deployComposeStack(config)
+	stack.AdditionalFiles, err = handler.storeAdditionalFiles(stackFolder, payload.AdditionalFiles)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist additional Compose files on disk", err}
 	}
 
-	err = handler.StackService.CreateStack(stack)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
+	if configErr != nil {
+		return configErr
 	}
 
-	doCleanUp = false
-	return response.JSON(w, stack)
+	return handler.createAndDeployStack(w, r, stack, endpoint, &doCleanUp, nil, func(output io.Writer) error {
+		return handler.deployComposeStack(config, output)
+	})
 }
 
 type composeStackFromGitRepositoryPayload struct {
@@ -96,6 +99,8 @@ type composeStackFromGitRepositoryPayload struct {
 	RepositoryPassword          string
 	ComposeFilePathInRepository string
 	Env                         []portainer.Pair
+	EnvironmentSets             []portainer.EnvironmentSetID
+	AdditionalFiles             []string
 }
 
 func (payload *composeStackFromGitRepositoryPayload) Validate(r *http.Request) error {
@@ -111,7 +116,10 @@ func (payload *composeStackFromGitRepositoryPayload) Validate(r *http.Request) e
 	if govalidator.IsNull(payload.ComposeFilePathInRepository) {
 		payload.ComposeFilePathInRepository = filesystem.ComposeFileDefaultName
 	}
-	return nil
+	if payload.AdditionalFiles == nil {
+		payload.AdditionalFiles = []string{}
+	}
+	return validateAdditionalFilePaths(payload.AdditionalFiles)
 }
 
 func (handler *Handler) createComposeStackFromGitRepository(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint) *httperror.HandlerError {
@@ -121,25 +129,30 @@ func (handler *Handler) createComposeStackFromGitRepository(w http.ResponseWrite
 		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
 	}
 
-	stacks, err := handler.StackService.Stacks()
+	isUnique, err := handler.isUniqueStackName(0, payload.Name, endpoint.ID, "")
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stacks from the database", err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to check for stack name uniqueness inside the database", err}
+	}
+	if !isUnique {
+		return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
 	}
 
-	for _, stack := range stacks {
-		if strings.EqualFold(stack.Name, payload.Name) {
-			return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
-		}
+	validationError := handler.validateEnvironmentSets(r, payload.EnvironmentSets, nil, endpoint)
+	if validationError != nil {
+		return validationError
 	}
 
 	stackID := handler.StackService.GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:         portainer.StackID(stackID),
-		Name:       payload.Name,
-		Type:       portainer.DockerComposeStack,
-		EndpointID: endpoint.ID,
-		EntryPoint: payload.ComposeFilePathInRepository,
-		Env:        payload.Env,
+		ID:              portainer.StackID(stackID),
+		Name:            payload.Name,
+		Type:            portainer.DockerComposeStack,
+		EndpointID:      endpoint.ID,
+		EntryPoint:      payload.ComposeFilePathInRepository,
+		Env:             payload.Env,
+		EnvironmentSets: environmentSetsOrEmpty(payload.EnvironmentSets),
+		AdditionalFiles: payload.AdditionalFiles,
+		Status:          portainer.StackStatusActive,
 	}
 
 	projectPath := handler.FileService.GetStackProjectPath(strconv.Itoa(int(stack.ID)))
@@ -162,29 +175,27 @@ func (handler *Handler) createComposeStackFromGitRepository(w http.ResponseWrite
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to clone git repository", err}
 	}
 
-	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
-	if configErr != nil {
-		return configErr
-	}
-
-	err = handler.deployComposeStack(config)
+	err = handler.checkAdditionalFilesExist(stack)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid additional files", err}
 	}
 
-	err = handler.StackService.CreateStack(stack)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
+	if configErr != nil {
+		return configErr
 	}
 
-	doCleanUp = false
-	return response.JSON(w, stack)
+	return handler.createAndDeployStack(w, r, stack, endpoint, &doCleanUp, nil, func(output io.Writer) error {
+		return handler.deployComposeStack(config, output)
+	})
 }
 
 type composeStackFromFileUploadPayload struct {
 	Name             string
 	StackFileContent []byte
 	Env              []portainer.Pair
+	EnvironmentSets  []portainer.EnvironmentSetID
+	AdditionalFiles  []stackFilePayload
 }
 
 func (payload *composeStackFromFileUploadPayload) Validate(r *http.Request) error {
@@ -206,7 +217,20 @@ func (payload *composeStackFromFileUploadPayload) Validate(r *http.Request) erro
 		return portainer.Error("Invalid Env parameter")
 	}
 	payload.Env = env
-	return nil
+
+	var environmentSets []portainer.EnvironmentSetID
+	err = request.RetrieveMultiPartFormJSONValue(r, "EnvironmentSets", &environmentSets, true)
+	if err != nil {
+		return portainer.Error("Invalid EnvironmentSets parameter")
+	}
+	payload.EnvironmentSets = environmentSets
+
+	additionalFiles, err := retrieveAdditionalFilesFromMultiPartForm(r)
+	if err != nil {
+		return portainer.Error("Invalid additional files. Ensure that the additional files are uploaded correctly")
+	}
+	payload.AdditionalFiles = additionalFiles
+	return validateAdditionalFiles(payload.AdditionalFiles, filesystem.ComposeFileDefaultName)
 }
 
 func (handler *Handler) createComposeStackFromFileUpload(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint) *httperror.HandlerError {
@@ -216,25 +240,30 @@ func (handler *Handler) createComposeStackFromFileUpload(w http.ResponseWriter,
 		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
 	}
 
-	stacks, err := handler.StackService.Stacks()
+	isUnique, err := handler.isUniqueStackName(0, payload.Name, endpoint.ID, "")
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stacks from the database", err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to check for stack name uniqueness inside the database", err}
+	}
+	if !isUnique {
+		return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
 	}
 
-	for _, stack := range stacks {
-		if strings.EqualFold(stack.Name, payload.Name) {
-			return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
-		}
+	validationError := handler.validateEnvironmentSets(r, payload.EnvironmentSets, nil, endpoint)
+	if validationError != nil {
+		return validationError
 	}
 
 	stackID := handler.StackService.GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:         portainer.StackID(stackID),
-		Name:       payload.Name,
-		Type:       portainer.DockerComposeStack,
-		EndpointID: endpoint.ID,
-		EntryPoint: filesystem.ComposeFileDefaultName,
-		Env:        payload.Env,
+		ID:              portainer.StackID(stackID),
+		Name:            payload.Name,
+		Type:            portainer.DockerComposeStack,
+		EndpointID:      endpoint.ID,
+		EntryPoint:      filesystem.ComposeFileDefaultName,
+		Env:             payload.Env,
+		EnvironmentSets: environmentSetsOrEmpty(payload.EnvironmentSets),
+		AdditionalFiles: []string{},
+		Status:          portainer.StackStatusActive,
 	}
 
 	stackFolder := strconv.Itoa(int(stack.ID))
@@ -247,23 +276,19 @@ func (handler *Handler) createComposeStackFromFileUpload(w http.ResponseWriter,
 	doCleanUp := true
 	defer handler.cleanUp(stack, &doCleanUp)
 
-	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
-	if configErr != nil {
-		return configErr
-	}
-
-	err = handler.deployComposeStack(config)
+	stack.AdditionalFiles, err = handler.storeAdditionalFiles(stackFolder, payload.AdditionalFiles)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist additional Compose files on disk", err}
 	}
 
-	err = handler.StackService.CreateStack(stack)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
+	if configErr != nil {
+		return configErr
 	}
 
-	doCleanUp = false
-	return response.JSON(w, stack)
+	return handler.createAndDeployStack(w, r, stack, endpoint, &doCleanUp, nil, func(output io.Writer) error {
+		return handler.deployComposeStack(config, output)
+	})
 }
 
 type composeStackDeploymentConfig struct {
@@ -279,42 +304,61 @@ func (handler *Handler) createComposeDeployConfig(r *http.Request, stack *portai
 		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
 	}
 
-	dockerhub, err := handler.DockerHubService.DockerHub()
-	if err != nil {
-		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve DockerHub details from the database", err}
+	dockerhub, registries, handlerErr := handler.deploymentRegistries(securityContext)
+	if handlerErr != nil {
+		return nil, handlerErr
 	}
 
-	registries, err := handler.RegistryService.Registries()
-	if err != nil {
-		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve registries from the database", err}
-	}
-	filteredRegistries := security.FilterRegistries(registries, securityContext)
-
 	config := &composeStackDeploymentConfig{
 		stack:      stack,
 		endpoint:   endpoint,
 		dockerhub:  dockerhub,
-		registries: filteredRegistries,
+		registries: registries,
 	}
 
 	return config, nil
 }
 
+// deploymentRegistries returns the Docker Hub details and the registries accessible to the user, they are
+// used to pull the images of a stack.
+func (handler *Handler) deploymentRegistries(securityContext *security.RestrictedRequestContext) (*portainer.DockerHub, []portainer.Registry, *httperror.HandlerError) {
+	dockerhub, err := handler.DockerHubService.DockerHub()
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve DockerHub details from the database", err}
+	}
+
+	registries, err := handler.RegistryService.Registries()
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve registries from the database", err}
+	}
+
+	return dockerhub, security.FilterRegistries(registries, securityContext), nil
+}
+
 // TODO: libcompose uses credentials store into a config.json file to pull images from
 // private registries. Right now the only solution is to re-use the embedded Docker binary
 // to login/logout, which will generate the required data in the config.json file and then
-// clean it. Hence the use of the mutex.
+// clean it. Hence the use of the mutex. The compose subcommand of the Docker CLI relies on the same file.
 // We should contribute to libcompose to support authentication without using the config.json file.
-func (handler *Handler) deployComposeStack(config *composeStackDeploymentConfig) error {
+func (handler *Handler) deployComposeStack(config *composeStackDeploymentConfig, output io.Writer) error {
 	handler.stackCreationMutex.Lock()
 	defer handler.stackCreationMutex.Unlock()
 
-	handler.SwarmStackManager.Login(config.dockerhub, config.registries, config.endpoint)
-
-	err := handler.ComposeStackManager.Up(config.stack, config.endpoint)
+	stack, err := handler.deploymentStack(config.stack, config.endpoint)
 	if err != nil {
 		return err
 	}
 
-	return handler.SwarmStackManager.Logout(config.endpoint)
+	handler.SwarmStackManager.Login(config.dockerhub, config.registries, config.endpoint)
+	defer handler.logout(config.endpoint)
+
+	return handler.ComposeStackManager.Up(stack, config.endpoint, output)
+}
+
+// logout removes the registry credentials stored during the deployment of a stack. Errors are logged.
+func (handler *Handler) logout(endpoint *portainer.Endpoint) {
+	err := handler.SwarmStackManager.Logout(endpoint)
+	if err != nil {
+		log.Printf("http error: Unable to remove the registry credentials after a stack deployment (err=%s)\n", err)
+	}
 }
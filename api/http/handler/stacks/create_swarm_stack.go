@@ -1,16 +1,15 @@
 package stacks
 
 import (
+	"io"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/asaskevich/govalidator"
 	"github.com/portainer/portainer"
 	"github.com/portainer/portainer/filesystem"
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/request"
-	"github.com/portainer/portainer/http/response"
 	"github.com/portainer/portainer/http/security"
 )
 
@@ -19,6 +18,8 @@ type swarmStackFromFileContentPayload struct {
 	SwarmID          string
 	StackFileContent string
 	Env              []portainer.Pair
+	EnvironmentSets  []portainer.EnvironmentSetID
+	AdditionalFiles  []stackFilePayload
 }
 
 func (payload *swarmStackFromFileContentPayload) Validate(r *http.Request) error {
@@ -31,7 +32,7 @@ func (payload *swarmStackFromFileContentPayload) Validate(r *http.Request) error
 	if govalidator.IsNull(payload.StackFileContent) {
 		return portainer.Error("Invalid stack file content")
 	}
-	return nil
+	return validateAdditionalFiles(payload.AdditionalFiles, filesystem.ComposeFileDefaultName)
 }
 
 func (handler *Handler) createSwarmStackFromFileContent(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint) *httperror.HandlerError {
@@ -41,26 +42,31 @@ func (handler *Handler) createSwarmStackFromFileContent(w http.ResponseWriter, r
 		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
 	}
 
-	stacks, err := handler.StackService.Stacks()
+	isUnique, err := handler.isUniqueStackName(0, payload.Name, endpoint.ID, payload.SwarmID)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stacks from the database", err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to check for stack name uniqueness inside the database", err}
+	}
+	if !isUnique {
+		return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
 	}
 
-	for _, stack := range stacks {
-		if strings.EqualFold(stack.Name, payload.Name) {
-			return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
-		}
+	validationError := handler.validateEnvironmentSets(r, payload.EnvironmentSets, nil, endpoint)
+	if validationError != nil {
+		return validationError
 	}
 
 	stackID := handler.StackService.GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:         portainer.StackID(stackID),
-		Name:       payload.Name,
-		Type:       portainer.DockerSwarmStack,
-		SwarmID:    payload.SwarmID,
-		EndpointID: endpoint.ID,
-		EntryPoint: filesystem.ComposeFileDefaultName,
-		Env:        payload.Env,
+		ID:              portainer.StackID(stackID),
+		Name:            payload.Name,
+		Type:            portainer.DockerSwarmStack,
+		SwarmID:         payload.SwarmID,
+		EndpointID:      endpoint.ID,
+		EntryPoint:      filesystem.ComposeFileDefaultName,
+		Env:             payload.Env,
+		EnvironmentSets: environmentSetsOrEmpty(payload.EnvironmentSets),
+		AdditionalFiles: []string{},
+		Status:          portainer.StackStatusActive,
 	}
 
 	stackFolder := strconv.Itoa(int(stack.ID))
@@ -73,35 +79,33 @@ func (handler *Handler) createSwarmStackFromFileContent(w http.ResponseWriter, r
 	doCleanUp := true
 	defer handler.cleanUp(stack, &doCleanUp)
 
-	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
-	if configErr != nil {
-		return configErr
-	}
-
-	err = handler.deploySwarmStack(config)
+	stack.AdditionalFiles, err = handler.storeAdditionalFiles(stackFolder, payload.AdditionalFiles)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist additional Compose files on disk", err}
 	}
 
-	err = handler.StackService.CreateStack(stack)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
+	if configErr != nil {
+		return configErr
 	}
 
-	doCleanUp = false
-	return response.JSON(w, stack)
+	return handler.createAndDeployStack(w, r, stack, endpoint, &doCleanUp, nil, func(output io.Writer) error {
+		return handler.deploySwarmStack(config, output)
+	})
 }
 
 type swarmStackFromGitRepositoryPayload struct {
 	Name                        string
 	SwarmID                     string
 	Env                         []portainer.Pair
+	EnvironmentSets             []portainer.EnvironmentSetID
 	RepositoryURL               string
 	RepositoryReferenceName     string
 	RepositoryAuthentication    bool
 	RepositoryUsername          string
 	RepositoryPassword          string
 	ComposeFilePathInRepository string
+	AdditionalFiles             []string
 }
 
 func (payload *swarmStackFromGitRepositoryPayload) Validate(r *http.Request) error {
@@ -120,7 +124,10 @@ func (payload *swarmStackFromGitRepositoryPayload) Validate(r *http.Request) err
 	if govalidator.IsNull(payload.ComposeFilePathInRepository) {
 		payload.ComposeFilePathInRepository = filesystem.ComposeFileDefaultName
 	}
-	return nil
+	if payload.AdditionalFiles == nil {
+		payload.AdditionalFiles = []string{}
+	}
+	return validateAdditionalFilePaths(payload.AdditionalFiles)
 }
 
 func (handler *Handler) createSwarmStackFromGitRepository(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint) *httperror.HandlerError {
@@ -130,26 +137,31 @@ func (handler *Handler) createSwarmStackFromGitRepository(w http.ResponseWriter,
 		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
 	}
 
-	stacks, err := handler.StackService.Stacks()
+	isUnique, err := handler.isUniqueStackName(0, payload.Name, endpoint.ID, payload.SwarmID)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stacks from the database", err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to check for stack name uniqueness inside the database", err}
+	}
+	if !isUnique {
+		return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
 	}
 
-	for _, stack := range stacks {
-		if strings.EqualFold(stack.Name, payload.Name) {
-			return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
-		}
+	validationError := handler.validateEnvironmentSets(r, payload.EnvironmentSets, nil, endpoint)
+	if validationError != nil {
+		return validationError
 	}
 
 	stackID := handler.StackService.GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:         portainer.StackID(stackID),
-		Name:       payload.Name,
-		Type:       portainer.DockerSwarmStack,
-		SwarmID:    payload.SwarmID,
-		EndpointID: endpoint.ID,
-		EntryPoint: payload.ComposeFilePathInRepository,
-		Env:        payload.Env,
+		ID:              portainer.StackID(stackID),
+		Name:            payload.Name,
+		Type:            portainer.DockerSwarmStack,
+		SwarmID:         payload.SwarmID,
+		EndpointID:      endpoint.ID,
+		EntryPoint:      payload.ComposeFilePathInRepository,
+		Env:             payload.Env,
+		EnvironmentSets: environmentSetsOrEmpty(payload.EnvironmentSets),
+		AdditionalFiles: payload.AdditionalFiles,
+		Status:          portainer.StackStatusActive,
 	}
 
 	projectPath := handler.FileService.GetStackProjectPath(strconv.Itoa(int(stack.ID)))
@@ -172,23 +184,19 @@ func (handler *Handler) createSwarmStackFromGitRepository(w http.ResponseWriter,
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to clone git repository", err}
 	}
 
-	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
-	if configErr != nil {
-		return configErr
-	}
-
-	err = handler.deploySwarmStack(config)
+	err = handler.checkAdditionalFilesExist(stack)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid additional files", err}
 	}
 
-	err = handler.StackService.CreateStack(stack)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
+	if configErr != nil {
+		return configErr
 	}
 
-	doCleanUp = false
-	return response.JSON(w, stack)
+	return handler.createAndDeployStack(w, r, stack, endpoint, &doCleanUp, nil, func(output io.Writer) error {
+		return handler.deploySwarmStack(config, output)
+	})
 }
 
 type swarmStackFromFileUploadPayload struct {
@@ -196,6 +204,8 @@ type swarmStackFromFileUploadPayload struct {
 	SwarmID          string
 	StackFileContent []byte
 	Env              []portainer.Pair
+	EnvironmentSets  []portainer.EnvironmentSetID
+	AdditionalFiles  []stackFilePayload
 }
 
 func (payload *swarmStackFromFileUploadPayload) Validate(r *http.Request) error {
@@ -223,7 +233,20 @@ func (payload *swarmStackFromFileUploadPayload) Validate(r *http.Request) error
 		return portainer.Error("Invalid Env parameter")
 	}
 	payload.Env = env
-	return nil
+
+	var environmentSets []portainer.EnvironmentSetID
+	err = request.RetrieveMultiPartFormJSONValue(r, "EnvironmentSets", &environmentSets, true)
+	if err != nil {
+		return portainer.Error("Invalid EnvironmentSets parameter")
+	}
+	payload.EnvironmentSets = environmentSets
+
+	additionalFiles, err := retrieveAdditionalFilesFromMultiPartForm(r)
+	if err != nil {
+		return portainer.Error("Invalid additional files. Ensure that the additional files are uploaded correctly")
+	}
+	payload.AdditionalFiles = additionalFiles
+	return validateAdditionalFiles(payload.AdditionalFiles, filesystem.ComposeFileDefaultName)
 }
 
 func (handler *Handler) createSwarmStackFromFileUpload(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint) *httperror.HandlerError {
@@ -233,26 +256,31 @@ func (handler *Handler) createSwarmStackFromFileUpload(w http.ResponseWriter, r
 		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
 	}
 
-	stacks, err := handler.StackService.Stacks()
+	isUnique, err := handler.isUniqueStackName(0, payload.Name, endpoint.ID, payload.SwarmID)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stacks from the database", err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to check for stack name uniqueness inside the database", err}
+	}
+	if !isUnique {
+		return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
 	}
 
-	for _, stack := range stacks {
-		if strings.EqualFold(stack.Name, payload.Name) {
-			return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
-		}
+	validationError := handler.validateEnvironmentSets(r, payload.EnvironmentSets, nil, endpoint)
+	if validationError != nil {
+		return validationError
 	}
 
 	stackID := handler.StackService.GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:         portainer.StackID(stackID),
-		Name:       payload.Name,
-		Type:       portainer.DockerSwarmStack,
-		SwarmID:    payload.SwarmID,
-		EndpointID: endpoint.ID,
-		EntryPoint: filesystem.ComposeFileDefaultName,
-		Env:        payload.Env,
+		ID:              portainer.StackID(stackID),
+		Name:            payload.Name,
+		Type:            portainer.DockerSwarmStack,
+		SwarmID:         payload.SwarmID,
+		EndpointID:      endpoint.ID,
+		EntryPoint:      filesystem.ComposeFileDefaultName,
+		Env:             payload.Env,
+		EnvironmentSets: environmentSetsOrEmpty(payload.EnvironmentSets),
+		AdditionalFiles: []string{},
+		Status:          portainer.StackStatusActive,
 	}
 
 	stackFolder := strconv.Itoa(int(stack.ID))
@@ -265,23 +293,19 @@ func (handler *Handler) createSwarmStackFromFileUpload(w http.ResponseWriter, r
 	doCleanUp := true
 	defer handler.cleanUp(stack, &doCleanUp)
 
-	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
-	if configErr != nil {
-		return configErr
-	}
-
-	err = handler.deploySwarmStack(config)
+	stack.AdditionalFiles, err = handler.storeAdditionalFiles(stackFolder, payload.AdditionalFiles)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist additional Compose files on disk", err}
 	}
 
-	err = handler.StackService.CreateStack(stack)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
+	if configErr != nil {
+		return configErr
 	}
 
-	doCleanUp = false
-	return response.JSON(w, stack)
+	return handler.createAndDeployStack(w, r, stack, endpoint, &doCleanUp, nil, func(output io.Writer) error {
+		return handler.deploySwarmStack(config, output)
+	})
 }
 
 type swarmStackDeploymentConfig struct {
@@ -298,43 +322,33 @@ func (handler *Handler) createSwarmDeployConfig(r *http.Request, stack *portaine
 		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
 	}
 
-	dockerhub, err := handler.DockerHubService.DockerHub()
-	if err != nil {
-		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve DockerHub details from the database", err}
-	}
-
-	registries, err := handler.RegistryService.Registries()
-	if err != nil {
-		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve registries from the database", err}
+	dockerhub, registries, handlerErr := handler.deploymentRegistries(securityContext)
+	if handlerErr != nil {
+		return nil, handlerErr
 	}
-	filteredRegistries := security.FilterRegistries(registries, securityContext)
 
 	config := &swarmStackDeploymentConfig{
 		stack:      stack,
 		endpoint:   endpoint,
 		dockerhub:  dockerhub,
-		registries: filteredRegistries,
+		registries: registries,
 		prune:      prune,
 	}
 
 	return config, nil
 }
 
-func (handler *Handler) deploySwarmStack(config *swarmStackDeploymentConfig) error {
+func (handler *Handler) deploySwarmStack(config *swarmStackDeploymentConfig, output io.Writer) error {
 	handler.stackCreationMutex.Lock()
 	defer handler.stackCreationMutex.Unlock()
 
-	handler.SwarmStackManager.Login(config.dockerhub, config.registries, config.endpoint)
-
-	err := handler.SwarmStackManager.Deploy(config.stack, config.prune, config.endpoint)
+	stack, err := handler.deploymentStack(config.stack, config.endpoint)
 	if err != nil {
 		return err
 	}
 
-	err = handler.SwarmStackManager.Logout(config.endpoint)
-	if err != nil {
-		return err
-	}
+	handler.SwarmStackManager.Login(config.dockerhub, config.registries, config.endpoint)
+	defer handler.logout(config.endpoint)
 
-	return nil
+	return handler.SwarmStackManager.Deploy(stack, config.prune, config.endpoint, output)
 }
@@ -0,0 +1,85 @@
+package stacks
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+)
+
+// TemplateStackPayload represents a stack created from the git repository of a stack template.
+type TemplateStackPayload struct {
+	Name            string
+	Type            portainer.StackType
+	SwarmID         string
+	RepositoryURL   string
+	EntryPoint      string
+	Env             []portainer.Pair
+	EnvironmentSets []portainer.EnvironmentSetID
+	// ResourceControl is optional, it is associated to the stack once the stack is persisted
+	ResourceControl *portainer.ResourceControl
+}
+
+// CreateTemplateStack creates a stack from the git repository of a stack template and deploys it like the
+// stacks created from a git repository. The async and convergenceTimeout query parameters of the request are supported.
+func (handler *Handler) CreateTemplateStack(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint, payload *TemplateStackPayload) *httperror.HandlerError {
+	isUnique, err := handler.isUniqueStackName(0, payload.Name, endpoint.ID, payload.SwarmID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to check for stack name uniqueness inside the database", err}
+	}
+	if !isUnique {
+		return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
+	}
+
+	validationError := handler.validateEnvironmentSets(r, payload.EnvironmentSets, nil, endpoint)
+	if validationError != nil {
+		return validationError
+	}
+
+	stackID := handler.StackService.GetNextIdentifier()
+	stack := &portainer.Stack{
+		ID:              portainer.StackID(stackID),
+		Name:            payload.Name,
+		Type:            payload.Type,
+		SwarmID:         payload.SwarmID,
+		EndpointID:      endpoint.ID,
+		EntryPoint:      payload.EntryPoint,
+		Env:             payload.Env,
+		EnvironmentSets: environmentSetsOrEmpty(payload.EnvironmentSets),
+		AdditionalFiles: []string{},
+		Status:          portainer.StackStatusActive,
+	}
+
+	projectPath := handler.FileService.GetStackProjectPath(strconv.Itoa(int(stack.ID)))
+	stack.ProjectPath = projectPath
+
+	doCleanUp := true
+	defer handler.cleanUp(stack, &doCleanUp)
+
+	err = handler.cloneGitRepository(&cloneRepositoryParameters{url: payload.RepositoryURL, path: projectPath})
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to clone git repository", err}
+	}
+
+	if stack.Type == portainer.DockerSwarmStack {
+		config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
+		if configErr != nil {
+			return configErr
+		}
+
+		return handler.createAndDeployStack(w, r, stack, endpoint, &doCleanUp, payload.ResourceControl, func(output io.Writer) error {
+			return handler.deploySwarmStack(config, output)
+		})
+	}
+
+	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
+	if configErr != nil {
+		return configErr
+	}
+
+	return handler.createAndDeployStack(w, r, stack, endpoint, &doCleanUp, payload.ResourceControl, func(output io.Writer) error {
+		return handler.deployComposeStack(config, output)
+	})
+}
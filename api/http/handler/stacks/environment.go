@@ -0,0 +1,142 @@
+package stacks
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/security"
+)
+
+// validateEnvironmentSets ensures that the environment sets exist and can be used by the stacks of the endpoint.
+// The values of the secret variables are readable inside the containers of a stack, only administrators can use
+// an environment set containing secret variables. The sets already used by the stack, specified via attachedSetIDs,
+// are not subject to this restriction.
+func (handler *Handler) validateEnvironmentSets(r *http.Request, setIDs, attachedSetIDs []portainer.EnvironmentSetID, endpoint *portainer.Endpoint) *httperror.HandlerError {
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	for _, setID := range setIDs {
+		set, err := handler.EnvironmentSetService.EnvironmentSet(setID)
+		if err == portainer.ErrObjectNotFound {
+			return &httperror.HandlerError{http.StatusBadRequest, "Unable to find an environment set with the specified identifier inside the database", err}
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an environment set with the specified identifier inside the database", err}
+		}
+
+		if !security.AuthorizedEnvironmentSetEndpoint(set, endpoint) {
+			return &httperror.HandlerError{http.StatusBadRequest, "The environment set " + set.Name + " cannot be used on this endpoint", portainer.ErrEnvironmentSetNotApplicable}
+		}
+
+		if !securityContext.IsAdmin && hasSecretVariables(set) && !containsEnvironmentSet(attachedSetIDs, setID) {
+			return &httperror.HandlerError{http.StatusForbidden, "The environment set " + set.Name + " contains secret variables", portainer.ErrEnvironmentSetSecretAccess}
+		}
+	}
+	return nil
+}
+
+func hasSecretVariables(set *portainer.EnvironmentSet) bool {
+	for _, variable := range set.Variables {
+		if variable.Secret {
+			return true
+		}
+	}
+	return false
+}
+
+func containsEnvironmentSet(setIDs []portainer.EnvironmentSetID, setID portainer.EnvironmentSetID) bool {
+	for _, id := range setIDs {
+		if id == setID {
+			return true
+		}
+	}
+	return false
+}
+
+// stackEnvironment returns the environment of a stack. The variables of the environment sets referenced by the stack
+// are merged in the following order, each one overriding the variables with the same name of the previous ones:
+// global sets, endpoint group sets, endpoint sets and finally the Env of the stack. Sets of the same scope are merged
+// in the order they are referenced by the stack. The values of the secret variables are decrypted when revealSecrets
+// is set to true, otherwise they are replaced with the secret value mask.
+func (handler *Handler) stackEnvironment(stack *portainer.Stack, endpoint *portainer.Endpoint, revealSecrets bool) ([]portainer.EnvironmentVariable, error) {
+	sets := make([]portainer.EnvironmentSet, 0, len(stack.EnvironmentSets))
+	for _, setID := range stack.EnvironmentSets {
+		set, err := handler.EnvironmentSetService.EnvironmentSet(setID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !security.AuthorizedEnvironmentSetEndpoint(set, endpoint) {
+			return nil, portainer.ErrEnvironmentSetNotApplicable
+		}
+
+		sets = append(sets, *set)
+	}
+
+	sort.SliceStable(sets, func(i, j int) bool {
+		return sets[i].Scope < sets[j].Scope
+	})
+
+	environment := make([]portainer.EnvironmentVariable, 0)
+	indexes := make(map[string]int)
+	setVariable := func(variable portainer.EnvironmentVariable) {
+		if idx, ok := indexes[variable.Name]; ok {
+			environment[idx] = variable
+			return
+		}
+		indexes[variable.Name] = len(environment)
+		environment = append(environment, variable)
+	}
+
+	for _, set := range sets {
+		for _, variable := range set.Variables {
+			if variable.Secret && !revealSecrets {
+				variable.Value = portainer.SecretValueMask
+			} else if variable.Secret {
+				value, err := handler.EncryptionService.Decrypt(variable.Value)
+				if err != nil {
+					return nil, err
+				}
+				variable.Value = value
+			}
+			setVariable(variable)
+		}
+	}
+
+	for _, pair := range stack.Env {
+		setVariable(portainer.EnvironmentVariable{Name: pair.Name, Value: pair.Value})
+	}
+
+	return environment, nil
+}
+
+// deploymentStack returns a copy of the stack whose Env contains the whole environment of the stack.
+// It is the stack that must be handed to the stack managers.
+func (handler *Handler) deploymentStack(stack *portainer.Stack, endpoint *portainer.Endpoint) (*portainer.Stack, error) {
+	if len(stack.EnvironmentSets) == 0 {
+		return stack, nil
+	}
+
+	environment, err := handler.stackEnvironment(stack, endpoint, true)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment := *stack
+	deployment.Env = make([]portainer.Pair, 0, len(environment))
+	for _, variable := range environment {
+		deployment.Env = append(deployment.Env, portainer.Pair{Name: variable.Name, Value: variable.Value})
+	}
+
+	return &deployment, nil
+}
+
+func environmentSetsOrEmpty(setIDs []portainer.EnvironmentSetID) []portainer.EnvironmentSetID {
+	if setIDs == nil {
+		return []portainer.EnvironmentSetID{}
+	}
+	return setIDs
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/docker"
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/security"
 )
@@ -14,17 +15,23 @@ import (
 type Handler struct {
 	stackCreationMutex *sync.Mutex
 	stackDeletionMutex *sync.Mutex
+	stackJobQueue      *stackJobQueue
+	jobOutputs         *jobOutputs
 	requestBouncer     *security.RequestBouncer
 	*mux.Router
 	FileService            portainer.FileService
 	GitService             portainer.GitService
 	StackService           portainer.StackService
+	StackJobService        portainer.StackJobService
+	EnvironmentSetService  portainer.EnvironmentSetService
+	EncryptionService      portainer.EncryptionService
 	EndpointService        portainer.EndpointService
 	ResourceControlService portainer.ResourceControlService
 	RegistryService        portainer.RegistryService
 	DockerHubService       portainer.DockerHubService
 	SwarmStackManager      portainer.SwarmStackManager
 	ComposeStackManager    portainer.ComposeStackManager
+	DockerClientFactory    *docker.ClientFactory
 }
 
 // NewHandler creates a handler to manage stack operations.
@@ -33,12 +40,18 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		Router:             mux.NewRouter(),
 		stackCreationMutex: &sync.Mutex{},
 		stackDeletionMutex: &sync.Mutex{},
+		stackJobQueue:      newStackJobQueue(),
+		jobOutputs:         newJobOutputs(),
 		requestBouncer:     bouncer,
 	}
 	h.Handle("/stacks",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.stackCreate))).Methods(http.MethodPost)
 	h.Handle("/stacks",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.stackList))).Methods(http.MethodGet)
+	h.Handle("/stacks/jobs/{id}",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.stackJobInspect))).Methods(http.MethodGet)
+	h.Handle("/stacks/jobs/{id}/logs",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.stackJobLogs))).Methods(http.MethodGet)
 	h.Handle("/stacks/{id}",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.stackInspect))).Methods(http.MethodGet)
 	h.Handle("/stacks/{id}",
@@ -47,7 +60,13 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.stackUpdate))).Methods(http.MethodPut)
 	h.Handle("/stacks/{id}/file",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.stackFile))).Methods(http.MethodGet)
+	h.Handle("/stacks/{id}/duplicate",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.stackDuplicate))).Methods(http.MethodPost)
 	h.Handle("/stacks/{id}/migrate",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.stackMigrate))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/stop",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.stackStop))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/start",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.stackStart))).Methods(http.MethodPost)
 	return h
 }
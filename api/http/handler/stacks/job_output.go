@@ -0,0 +1,119 @@
+package stacks
+
+import (
+	"sync"
+
+	"github.com/portainer/portainer"
+)
+
+// jobOutput captures the output of a running stack job and lets readers follow it
+// while it is being written.
+type jobOutput struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	data   []byte
+	closed bool
+}
+
+func newJobOutput() *jobOutput {
+	output := &jobOutput{}
+	output.cond = sync.NewCond(&output.mutex)
+	return output
+}
+
+// Write appends p to the output and wakes up the readers.
+func (output *jobOutput) Write(p []byte) (int, error) {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+
+	output.data = append(output.data, p...)
+	output.cond.Broadcast()
+	return len(p), nil
+}
+
+// String returns the output written so far.
+func (output *jobOutput) String() string {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+
+	return string(output.data)
+}
+
+func (output *jobOutput) close() {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+
+	output.closed = true
+	output.cond.Broadcast()
+}
+
+// wake wakes up the readers so that they can check whether they must stop reading.
+func (output *jobOutput) wake() {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+
+	output.cond.Broadcast()
+}
+
+// next blocks until data is available after offset, the output is closed or done is closed.
+// It returns the data available after offset and whether the output is closed. Readers waiting
+// on done must be woken up using wake.
+func (output *jobOutput) next(done <-chan struct{}, offset int) ([]byte, bool) {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+
+	for len(output.data) <= offset && !output.closed {
+		select {
+		case <-done:
+			return nil, false
+		default:
+		}
+		output.cond.Wait()
+	}
+
+	data := make([]byte, len(output.data)-offset)
+	copy(data, output.data[offset:])
+	return data, output.closed
+}
+
+// jobOutputs keeps track of the output of the stack jobs being executed.
+type jobOutputs struct {
+	mutex   sync.Mutex
+	outputs map[portainer.StackJobID]*jobOutput
+}
+
+func newJobOutputs() *jobOutputs {
+	return &jobOutputs{
+		outputs: make(map[portainer.StackJobID]*jobOutput),
+	}
+}
+
+// open creates a job using the create function and registers its output. The registry is locked
+// while the job is created so that a job being executed always has a registered output.
+func (outputs *jobOutputs) open(create func() (portainer.StackJobID, error)) (*jobOutput, error) {
+	outputs.mutex.Lock()
+	defer outputs.mutex.Unlock()
+
+	jobID, err := create()
+	if err != nil {
+		return nil, err
+	}
+
+	output := newJobOutput()
+	outputs.outputs[jobID] = output
+	return output, nil
+}
+
+func (outputs *jobOutputs) get(jobID portainer.StackJobID) *jobOutput {
+	outputs.mutex.Lock()
+	defer outputs.mutex.Unlock()
+
+	return outputs.outputs[jobID]
+}
+
+func (outputs *jobOutputs) remove(jobID portainer.StackJobID) {
+	outputs.mutex.Lock()
+	defer outputs.mutex.Unlock()
+
+	delete(outputs.outputs, jobID)
+}
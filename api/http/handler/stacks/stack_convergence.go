@@ -0,0 +1,360 @@
+package stacks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/http/request"
+)
+
+const (
+	convergencePollInterval = 2 * time.Second
+	maxConvergenceTimeout   = 30 * time.Minute
+)
+
+var composeProjectNameRe = regexp.MustCompile("[^a-z0-9]+")
+
+// convergenceOptions represents the options of the verification executed after a stack deployment.
+type convergenceOptions struct {
+	timeout  time.Duration
+	rollback bool
+}
+
+// retrieveConvergenceOptions returns the convergence options specified using the optional convergenceTimeout
+// (in seconds) and rollback query parameters. It returns nil when no verification is requested.
+func retrieveConvergenceOptions(r *http.Request) (*convergenceOptions, error) {
+	timeout, err := request.RetrieveNumericQueryParameter(r, "convergenceTimeout", true)
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout < 0 || time.Duration(timeout)*time.Second > maxConvergenceTimeout {
+		return nil, portainer.Error("Invalid convergence timeout")
+	}
+
+	if timeout == 0 {
+		return nil, nil
+	}
+
+	rollback, err := request.RetrieveBooleanQueryParameter(r, "rollback", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &convergenceOptions{
+		timeout:  time.Duration(timeout) * time.Second,
+		rollback: rollback,
+	}, nil
+}
+
+// stackExecution represents an operation executed on a stack, optionally followed by the verification
+// that the services of the stack reached their desired state. The rollback operation is executed when
+// the verification fails and a rollback was requested.
+type stackExecution struct {
+	stack     *portainer.Stack
+	endpoint  *portainer.Endpoint
+	operation stackOperation
+	rollback  stackOperation
+	options   *convergenceOptions
+}
+
+// execute runs the operation of a stack execution. It returns the result of the verification, or nil when
+// no verification was requested or when the operation failed. A stack that did not converge, or whose state
+// could not be verified, is not an error.
+func (handler *Handler) execute(execution *stackExecution, output io.Writer) (*portainer.StackConvergence, error) {
+	err := execution.operation(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if execution.options == nil {
+		return nil, nil
+	}
+
+	convergence, err := handler.waitForConvergence(execution.stack, execution.endpoint, execution.options.timeout, output)
+	if err != nil {
+		writeOutput(output, "Unable to verify the state of the services of the stack: "+err.Error()+"\n")
+		convergence = &portainer.StackConvergence{
+			Services: []portainer.StackServiceStatus{},
+			Error:    err.Error(),
+		}
+	}
+
+	if convergence.Converged || !execution.options.rollback || execution.rollback == nil {
+		return convergence, nil
+	}
+
+	writeOutput(output, "Rolling back to the previous stack file\n")
+	err = execution.rollback(output)
+	if err != nil {
+		return convergence, portainer.Error("Unable to roll back the stack: " + err.Error())
+	}
+
+	convergence.RolledBack = true
+	return convergence, nil
+}
+
+// waitForConvergence waits until every service of the stack runs its desired number of replicas with passing
+// health checks. A stack is considered converged when this state is observed twice in a row, so that services
+// restarting in a loop are not reported as converged.
+func (handler *Handler) waitForConvergence(stack *portainer.Stack, endpoint *portainer.Endpoint, timeout time.Duration, output io.Writer) (*portainer.StackConvergence, error) {
+	cli, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	writeOutput(output, fmt.Sprintf("Waiting up to %s for the services of the stack to converge\n", timeout))
+
+	deadline := time.Now().Add(timeout)
+	messages := make(map[string]string)
+	previouslyConverged := false
+
+	for {
+		var services []portainer.StackServiceStatus
+		if stack.Type == portainer.DockerSwarmStack {
+			services, err = swarmServiceStatuses(cli, stack.Name)
+		} else {
+			services, err = composeServiceStatuses(cli, stack.Name)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, service := range services {
+			if messages[service.Name] != service.Message {
+				writeOutput(output, service.Name+": "+service.Message+"\n")
+				messages[service.Name] = service.Message
+			}
+		}
+
+		converged := servicesConverged(services)
+		if (converged && previouslyConverged) || time.Now().After(deadline) {
+			if converged {
+				writeOutput(output, "All the services of the stack converged\n")
+			} else {
+				writeOutput(output, portainer.ErrStackNotConverged.Error()+"\n")
+			}
+
+			return &portainer.StackConvergence{
+				Converged: converged,
+				Services:  services,
+			}, nil
+		}
+
+		previouslyConverged = converged
+		time.Sleep(convergencePollInterval)
+	}
+}
+
+func servicesConverged(services []portainer.StackServiceStatus) bool {
+	if len(services) == 0 {
+		return false
+	}
+
+	for _, service := range services {
+		if !service.Converged {
+			return false
+		}
+	}
+	return true
+}
+
+// swarmServiceStatuses returns the status of the services of a Swarm stack. Tasks only reach the running state
+// once their health check passes. Tasks running a previous version of the image are not counted, to account for
+// rolling updates in progress.
+func swarmServiceStatuses(cli *client.Client, stackName string) ([]portainer.StackServiceStatus, error) {
+	services, err := swarmStackServices(cli, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]portainer.StackServiceStatus, 0, len(services))
+	for _, service := range services {
+		taskFilter := filters.NewArgs()
+		taskFilter.Add("service", service.ID)
+
+		tasks, err := cli.TaskList(context.Background(), types.TaskListOptions{Filters: taskFilter})
+		if err != nil {
+			return nil, err
+		}
+
+		image := ""
+		if service.Spec.TaskTemplate.ContainerSpec != nil {
+			image = service.Spec.TaskTemplate.ContainerSpec.Image
+		}
+
+		desiredTasks := 0
+		runningTasks := 0
+		lastError := ""
+		var lastErrorTime time.Time
+		for _, task := range tasks {
+			if task.Status.Err != "" && task.Status.Timestamp.After(lastErrorTime) {
+				lastError = task.Status.Err
+				lastErrorTime = task.Status.Timestamp
+			}
+
+			if task.DesiredState != swarm.TaskStateRunning {
+				continue
+			}
+			desiredTasks++
+
+			if task.Status.State != swarm.TaskStateRunning {
+				continue
+			}
+			if task.Spec.ContainerSpec != nil && image != "" && task.Spec.ContainerSpec.Image != image {
+				continue
+			}
+			runningTasks++
+		}
+
+		status := portainer.StackServiceStatus{
+			Name:            service.Spec.Name,
+			DesiredReplicas: desiredTasks,
+			RunningReplicas: runningTasks,
+		}
+		if service.Spec.Mode.Replicated != nil && service.Spec.Mode.Replicated.Replicas != nil {
+			status.DesiredReplicas = int(*service.Spec.Mode.Replicated.Replicas)
+		}
+
+		statuses = append(statuses, replicaStatus(status, lastError))
+	}
+
+	sortServiceStatuses(statuses)
+	return statuses, nil
+}
+
+// composeServiceStatuses returns the status of the services of a Compose stack. Each container of a service
+// is expected to be running and, when it defines a health check, healthy.
+func composeServiceStatuses(cli *client.Client, stackName string) ([]portainer.StackServiceStatus, error) {
+	containers, err := composeProjectContainers(cli, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]*portainer.StackServiceStatus)
+	lastErrors := make(map[string]string)
+	for _, container := range containers {
+		serviceName := container.Labels[composeServiceLabel]
+		if serviceName == "" {
+			continue
+		}
+
+		status, ok := services[serviceName]
+		if !ok {
+			status = &portainer.StackServiceStatus{Name: serviceName}
+			services[serviceName] = status
+		}
+		status.DesiredReplicas++
+
+		containerInspect, err := cli.ContainerInspect(context.Background(), container.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		state := containerInspect.State
+		switch {
+		case state == nil:
+			continue
+		case state.Restarting:
+			lastErrors[serviceName] = "container " + strings.TrimPrefix(containerInspect.Name, "/") + " is restarting"
+		case !state.Running:
+			lastErrors[serviceName] = "container " + strings.TrimPrefix(containerInspect.Name, "/") + " exited with code " + strconv.Itoa(state.ExitCode)
+		case state.Health != nil && state.Health.Status != types.Healthy:
+			lastErrors[serviceName] = "container " + strings.TrimPrefix(containerInspect.Name, "/") + " is " + state.Health.Status
+		default:
+			status.RunningReplicas++
+		}
+	}
+
+	statuses := make([]portainer.StackServiceStatus, 0, len(services))
+	for name, status := range services {
+		statuses = append(statuses, replicaStatus(*status, lastErrors[name]))
+	}
+
+	sortServiceStatuses(statuses)
+	return statuses, nil
+}
+
+func replicaStatus(status portainer.StackServiceStatus, lastError string) portainer.StackServiceStatus {
+	status.Converged = status.RunningReplicas == status.DesiredReplicas
+	status.Message = fmt.Sprintf("%d/%d replicas running", status.RunningReplicas, status.DesiredReplicas)
+	if !status.Converged && lastError != "" {
+		status.Message += " (" + lastError + ")"
+	}
+	return status
+}
+
+func sortServiceStatuses(statuses []portainer.StackServiceStatus) {
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+}
+
+// composeProjectName returns the name of the Compose project associated to a stack, as normalized by libcompose.
+func composeProjectName(stackName string) string {
+	return composeProjectNameRe.ReplaceAllString(strings.ToLower(stackName), "")
+}
+
+func writeOutput(output io.Writer, message string) {
+	if output != nil {
+		io.WriteString(output, message)
+	}
+}
+
+// stackFilesSnapshot represents the files and the environment of a stack before an update.
+type stackFilesSnapshot struct {
+	env             []portainer.Pair
+	environmentSets []portainer.EnvironmentSetID
+	additionalFiles []string
+	files           map[string][]byte
+}
+
+// snapshotStackFiles saves the content of the entry point and of the additional files of a stack.
+func (handler *Handler) snapshotStackFiles(stack *portainer.Stack) (*stackFilesSnapshot, error) {
+	snapshot := &stackFilesSnapshot{
+		env:             append([]portainer.Pair{}, stack.Env...),
+		environmentSets: append([]portainer.EnvironmentSetID{}, stack.EnvironmentSets...),
+		additionalFiles: append([]string{}, stack.AdditionalFiles...),
+		files:           make(map[string][]byte),
+	}
+
+	for _, file := range append([]string{stack.EntryPoint}, stack.AdditionalFiles...) {
+		content, err := handler.FileService.GetFileContent(path.Join(stack.ProjectPath, file))
+		if err != nil {
+			return nil, err
+		}
+		snapshot.files[file] = content
+	}
+
+	return snapshot, nil
+}
+
+// restoreStackFiles restores the files and the environment of a stack saved in a snapshot.
+func (handler *Handler) restoreStackFiles(stack *portainer.Stack, snapshot *stackFilesSnapshot) error {
+	stackFolder := strconv.Itoa(int(stack.ID))
+	for file, content := range snapshot.files {
+		_, err := handler.FileService.StoreStackFileFromBytes(stackFolder, file, content)
+		if err != nil {
+			return err
+		}
+	}
+
+	stack.Env = snapshot.env
+	stack.EnvironmentSets = snapshot.environmentSets
+	stack.AdditionalFiles = snapshot.additionalFiles
+	return nil
+}
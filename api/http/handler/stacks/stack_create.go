@@ -3,6 +3,7 @@ package stacks
 import (
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/portainer/portainer"
 	httperror "github.com/portainer/portainer/http/error"
@@ -21,7 +22,31 @@ func (handler *Handler) cleanUp(stack *portainer.Stack, doCleanUp *bool) error {
 	return nil
 }
 
-// POST request on /api/stacks?type=<type>&method=<method>&endpointId=<endpointId>
+// isUniqueStackName returns false when another stack with the same name is associated to the endpoint
+// or, for Swarm stacks, is deployed inside the same Swarm cluster.
+func (handler *Handler) isUniqueStackName(stackID portainer.StackID, name string, endpointID portainer.EndpointID, swarmID string) (bool, error) {
+	stacks, err := handler.StackService.Stacks()
+	if err != nil {
+		return false, err
+	}
+
+	for _, stack := range stacks {
+		if stack.ID == stackID || !strings.EqualFold(stack.Name, name) {
+			continue
+		}
+
+		if stack.EndpointID == endpointID || (swarmID != "" && stack.SwarmID == swarmID) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// POST request on /api/stacks?type=<type>&method=<method>&endpointId=<endpointId>&async=<async>&convergenceTimeout=<timeout>
+// If the async query parameter is set to true, the stack is deployed in the background and a stack job is returned.
+// If the convergenceTimeout query parameter is set, the services of the stack are expected to reach their desired state
+// within this number of seconds and their status is returned with the stack.
 func (handler *Handler) stackCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
 	stackType, err := request.RetrieveNumericQueryParameter(r, "type", false)
 	if err != nil {
@@ -69,9 +94,11 @@ func (handler *Handler) createComposeStack(w http.ResponseWriter, r *http.Reques
 		return handler.createComposeStackFromGitRepository(w, r, endpoint)
 	case "file":
 		return handler.createComposeStackFromFileUpload(w, r, endpoint)
+	case "import":
+		return handler.importStack(w, r, portainer.DockerComposeStack, endpoint)
 	}
 
-	return &httperror.HandlerError{http.StatusBadRequest, "Invalid value for query parameter: method. Value must be one of: string, repository or file", request.ErrInvalidQueryParameter}
+	return &httperror.HandlerError{http.StatusBadRequest, "Invalid value for query parameter: method. Value must be one of: string, repository, file or import", request.ErrInvalidQueryParameter}
 }
 
 func (handler *Handler) createSwarmStack(w http.ResponseWriter, r *http.Request, method string, endpoint *portainer.Endpoint) *httperror.HandlerError {
@@ -82,7 +109,9 @@ func (handler *Handler) createSwarmStack(w http.ResponseWriter, r *http.Request,
 		return handler.createSwarmStackFromGitRepository(w, r, endpoint)
 	case "file":
 		return handler.createSwarmStackFromFileUpload(w, r, endpoint)
+	case "import":
+		return handler.importStack(w, r, portainer.DockerSwarmStack, endpoint)
 	}
 
-	return &httperror.HandlerError{http.StatusBadRequest, "Invalid value for query parameter: method. Value must be one of: string, repository or file", request.ErrInvalidQueryParameter}
+	return &httperror.HandlerError{http.StatusBadRequest, "Invalid value for query parameter: method. Value must be one of: string, repository, file or import", request.ErrInvalidQueryParameter}
 }
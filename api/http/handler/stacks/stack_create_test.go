@@ -0,0 +1,55 @@
+package stacks
+
+import (
+	"testing"
+
+	"github.com/portainer/portainer"
+)
+
+type testStackService struct {
+	portainer.StackService
+	stacks []portainer.Stack
+}
+
+func (service *testStackService) Stacks() ([]portainer.Stack, error) {
+	return service.stacks, nil
+}
+
+func TestIsUniqueStackName(t *testing.T) {
+	stacks := []portainer.Stack{
+		{ID: 1, Name: "web", EndpointID: 1},
+		{ID: 2, Name: "api", EndpointID: 2, SwarmID: "swarm1"},
+	}
+
+	tests := []struct {
+		name       string
+		stackID    portainer.StackID
+		stackName  string
+		endpointID portainer.EndpointID
+		swarmID    string
+		expected   bool
+	}{
+		{name: "New name", stackName: "db", endpointID: 1, expected: true},
+		{name: "Existing name on the same endpoint", stackName: "web", endpointID: 1, expected: false},
+		{name: "Existing name with a different case", stackName: "WEB", endpointID: 1, expected: false},
+		{name: "Duplicate keeping the name of the source on another endpoint", stackName: "web", endpointID: 3, expected: true},
+		{name: "Existing name in the same swarm", stackName: "api", endpointID: 3, swarmID: "swarm1", expected: false},
+		{name: "Existing name in another swarm", stackName: "api", endpointID: 3, swarmID: "swarm2", expected: true},
+		{name: "Stack being updated", stackID: 1, stackName: "web", endpointID: 1, expected: true},
+	}
+
+	handler := &Handler{StackService: &testStackService{stacks: stacks}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isUnique, err := handler.isUniqueStackName(test.stackID, test.stackName, test.endpointID, test.swarmID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if isUnique != test.expected {
+				t.Errorf("Unexpected uniqueness: got %t want %t", isUnique, test.expected)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package stacks
 
 import (
+	"io"
 	"net/http"
 	"strconv"
 
@@ -12,9 +13,10 @@ import (
 	"github.com/portainer/portainer/http/security"
 )
 
-// DELETE request on /api/stacks/:id?external=<external>&endpointId=<endpointId>
+// DELETE request on /api/stacks/:id?external=<external>&endpointId=<endpointId>&async=<async>
 // If the external query parameter is set to true, the id route variable is expected to be
 // the name of an external stack as a string.
+// If the async query parameter is set to true, the stack is removed in the background and a stack job is returned.
 func (handler *Handler) stackDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
 	stackID, err := request.RetrieveRouteVariableValue(r, "id")
 	if err != nil {
@@ -38,7 +40,7 @@ func (handler *Handler) stackDelete(w http.ResponseWriter, r *http.Request) *htt
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
 	}
 
-	resourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(stack.Name)
+	resourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(proxy.StackResourceControlID(stack))
 	if err != nil && err != portainer.ErrObjectNotFound {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve a resource control associated to the stack", err}
 	}
@@ -74,26 +76,39 @@ func (handler *Handler) stackDelete(w http.ResponseWriter, r *http.Request) *htt
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find the endpoint associated to the stack inside the database", err}
 	}
 
-	err = handler.deleteStack(stack, endpoint)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+	execution := &stackExecution{
+		stack:    stack,
+		endpoint: endpoint,
+		operation: func(output io.Writer) error {
+			err := handler.reloadStack(stack)
+			if err != nil {
+				return err
+			}
+			return handler.removeStack(stack, endpoint, output)
+		},
 	}
 
-	err = handler.StackService.DeleteStack(portainer.StackID(id))
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the stack from the database", err}
+	if isAsyncRequest(r) {
+		return handler.startStackJob(w, r, portainer.StackJobDelete, execution, nil)
 	}
 
-	err = handler.FileService.RemoveDirectory(stack.ProjectPath)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove stack files from disk", err}
+	_, err = handler.executeInQueue(execution)
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a stack with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
 	}
 
 	return response.Empty(w)
 }
 
 func (handler *Handler) deleteExternalStack(r *http.Request, w http.ResponseWriter, stackName string) *httperror.HandlerError {
-	stack, err := handler.StackService.StackByName(stackName)
+	endpointID, err := request.RetrieveNumericQueryParameter(r, "endpointId", false)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: endpointId", err}
+	}
+
+	stack, err := handler.StackService.StackByName(portainer.EndpointID(endpointID), stackName)
 	if err != nil && err != portainer.ErrObjectNotFound {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to check for stack existence inside the database", err}
 	}
@@ -101,11 +116,6 @@ func (handler *Handler) deleteExternalStack(r *http.Request, w http.ResponseWrit
 		return &httperror.HandlerError{http.StatusBadRequest, "A stack with this name exists inside the database. Cannot use external delete method", portainer.ErrStackNotExternal}
 	}
 
-	endpointID, err := request.RetrieveNumericQueryParameter(r, "endpointId", false)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: endpointId", err}
-	}
-
 	endpoint, err := handler.EndpointService.Endpoint(portainer.EndpointID(endpointID))
 	if err == portainer.ErrObjectNotFound {
 		return &httperror.HandlerError{http.StatusNotFound, "Unable to find the endpoint associated to the stack inside the database", err}
@@ -123,7 +133,7 @@ func (handler *Handler) deleteExternalStack(r *http.Request, w http.ResponseWrit
 		Type: portainer.DockerSwarmStack,
 	}
 
-	err = handler.deleteStack(stack, endpoint)
+	err = handler.deleteStack(stack, endpoint, nil)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to delete stack", err}
 	}
@@ -131,9 +141,29 @@ func (handler *Handler) deleteExternalStack(r *http.Request, w http.ResponseWrit
 	return response.Empty(w)
 }
 
-func (handler *Handler) deleteStack(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+func (handler *Handler) deleteStack(stack *portainer.Stack, endpoint *portainer.Endpoint, output io.Writer) error {
 	if stack.Type == portainer.DockerSwarmStack {
-		return handler.SwarmStackManager.Remove(stack, endpoint)
+		return handler.SwarmStackManager.Remove(stack, endpoint, output)
+	}
+
+	deployment, err := handler.deploymentStack(stack, endpoint)
+	if err != nil {
+		return err
+	}
+	return handler.ComposeStackManager.Down(deployment, endpoint, output)
+}
+
+// removeStack removes the stack from the endpoint, then from the database and the filesystem.
+func (handler *Handler) removeStack(stack *portainer.Stack, endpoint *portainer.Endpoint, output io.Writer) error {
+	err := handler.deleteStack(stack, endpoint, output)
+	if err != nil {
+		return err
 	}
-	return handler.ComposeStackManager.Down(stack, endpoint)
+
+	err = handler.StackService.DeleteStack(stack.ID)
+	if err != nil {
+		return err
+	}
+
+	return handler.FileService.RemoveDirectory(stack.ProjectPath)
 }
@@ -0,0 +1,247 @@
+package stacks
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/proxy"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+	"github.com/portainer/portainer/http/security"
+)
+
+type stackDuplicatePayload struct {
+	EndpointID int
+	SwarmID    string
+	Name       string
+	Env        []portainer.Pair
+}
+
+func (payload *stackDuplicatePayload) Validate(r *http.Request) error {
+	if payload.EndpointID == 0 {
+		return portainer.Error("Invalid endpoint identifier. Must be a positive number")
+	}
+	return nil
+}
+
+// POST request on /api/stacks/:id/duplicate
+// Deploys a copy of the stack on the target endpoint. The original stack is kept, the operations queued
+// on it wait for the copy to be deployed.
+// The environment variables specified in the payload override the ones of the original stack.
+func (handler *Handler) stackDuplicate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid stack identifier route variable", err}
+	}
+
+	var payload stackDuplicatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	stack, err := handler.StackService.Stack(portainer.StackID(stackID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a stack with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
+	}
+
+	resourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(proxy.StackResourceControlID(stack))
+	if err != nil && err != portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve a resource control associated to the stack", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	if resourceControl != nil {
+		if !securityContext.IsAdmin && !proxy.CanAccessStack(stack, resourceControl, securityContext.UserID, securityContext.UserMemberships) {
+			return &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", portainer.ErrResourceAccessDenied}
+		}
+	}
+
+	release, handlerErr := handler.lockStack(stack)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer release()
+
+	targetEndpoint, err := handler.EndpointService.Endpoint(portainer.EndpointID(payload.EndpointID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.EndpointAccess(r, targetEndpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", portainer.ErrEndpointAccessDenied}
+	}
+
+	if !supportsStackType(targetEndpoint, stack.Type) {
+		return &httperror.HandlerError{http.StatusBadRequest, "The target endpoint does not support this type of stack", portainer.ErrStackTypeMismatch}
+	}
+
+	if stack.Type == portainer.DockerSwarmStack && payload.SwarmID == "" {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", portainer.Error("Invalid Swarm ID")}
+	}
+
+	name := stack.Name
+	if payload.Name != "" {
+		name = payload.Name
+	}
+
+	isUnique, err := handler.isUniqueStackName(0, name, targetEndpoint.ID, payload.SwarmID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to check for stack name uniqueness inside the database", err}
+	}
+	if !isUnique {
+		return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
+	}
+
+	validationError := handler.validateEnvironmentSets(r, stack.EnvironmentSets, nil, targetEndpoint)
+	if validationError != nil {
+		return validationError
+	}
+
+	duplicate := &portainer.Stack{
+		ID:              portainer.StackID(handler.StackService.GetNextIdentifier()),
+		Name:            name,
+		Type:            stack.Type,
+		EndpointID:      targetEndpoint.ID,
+		SwarmID:         payload.SwarmID,
+		EntryPoint:      stack.EntryPoint,
+		Env:             overrideEnv(stack.Env, payload.Env),
+		EnvironmentSets: append([]portainer.EnvironmentSetID{}, stack.EnvironmentSets...),
+		AdditionalFiles: append([]string{}, stack.AdditionalFiles...),
+		Status:          portainer.StackStatusActive,
+	}
+
+	projectPath, err := handler.FileService.CopyStackProject(stack.ProjectPath, strconv.Itoa(int(duplicate.ID)))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to copy the stack files on disk", err}
+	}
+	duplicate.ProjectPath = projectPath
+
+	doCleanUp := true
+	defer handler.cleanUp(duplicate, &doCleanUp)
+
+	deploymentError := handler.duplicateStack(r, duplicate, targetEndpoint)
+	if deploymentError != nil {
+		return deploymentError
+	}
+
+	err = handler.StackService.CreateStack(duplicate)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+	}
+
+	doCleanUp = false
+
+	if resourceControl != nil {
+		err = handler.duplicateResourceControl(resourceControl, duplicate)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the resource control inside the database", err}
+		}
+	}
+
+	return response.JSON(w, duplicate)
+}
+
+func (handler *Handler) duplicateStack(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) *httperror.HandlerError {
+	if stack.Type == portainer.DockerSwarmStack {
+		config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
+		if configErr != nil {
+			return configErr
+		}
+
+		err := handler.deploySwarmStack(config, nil)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		}
+		return nil
+	}
+
+	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
+	if configErr != nil {
+		return configErr
+	}
+
+	err := handler.deployComposeStack(config, nil)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+	}
+	return nil
+}
+
+// duplicateResourceControl associates a copy of the resource control of a stack to another stack.
+// Nothing is done if a resource control is already associated to the other stack.
+func (handler *Handler) duplicateResourceControl(resourceControl *portainer.ResourceControl, stack *portainer.Stack) error {
+	resourceID := proxy.StackResourceControlID(stack)
+
+	existingResourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(resourceID)
+	if err != nil && err != portainer.ErrObjectNotFound {
+		return err
+	}
+	if existingResourceControl != nil {
+		return nil
+	}
+
+	duplicate := &portainer.ResourceControl{
+		ResourceID:         resourceID,
+		SubResourceIDs:     []string{},
+		Type:               portainer.StackResourceControl,
+		AdministratorsOnly: resourceControl.AdministratorsOnly,
+		UserAccesses:       resourceControl.UserAccesses,
+		TeamAccesses:       resourceControl.TeamAccesses,
+	}
+
+	return handler.ResourceControlService.CreateResourceControl(duplicate)
+}
+
+// supportsStackType returns false when the endpoint cannot be used to deploy the type of stack.
+// Swarm stacks require a Swarm endpoint while Compose stacks require a standalone endpoint.
+// Endpoints that were never snapshotted are assumed to support both types.
+func supportsStackType(endpoint *portainer.Endpoint, stackType portainer.StackType) bool {
+	if endpoint.Type == portainer.AzureEnvironment {
+		return false
+	}
+
+	if len(endpoint.Snapshots) == 0 {
+		return true
+	}
+
+	swarm := endpoint.Snapshots[len(endpoint.Snapshots)-1].Swarm
+	if stackType == portainer.DockerSwarmStack {
+		return swarm
+	}
+	return !swarm
+}
+
+// overrideEnv returns the environment variables of a stack where the value of the variables
+// defined in overrides is replaced. Variables only defined in overrides are added.
+func overrideEnv(env, overrides []portainer.Pair) []portainer.Pair {
+	result := make([]portainer.Pair, 0, len(env)+len(overrides))
+	result = append(result, env...)
+
+	for _, override := range overrides {
+		found := false
+		for idx := range result {
+			if result[idx].Name == override.Name {
+				result[idx].Value = override.Value
+				found = true
+			}
+		}
+
+		if !found {
+			result = append(result, override)
+		}
+	}
+
+	return result
+}
@@ -13,7 +13,8 @@ import (
 )
 
 type stackFileResponse struct {
-	StackFileContent string `json:"StackFileContent"`
+	StackFileContent string             `json:"StackFileContent"`
+	AdditionalFiles  []stackFilePayload `json:"AdditionalFiles"`
 }
 
 // GET request on /api/stacks/:id/file
@@ -30,7 +31,7 @@ func (handler *Handler) stackFile(w http.ResponseWriter, r *http.Request) *httpe
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
 	}
 
-	resourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(stack.Name)
+	resourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(proxy.StackResourceControlID(stack))
 	if err != nil && err != portainer.ErrObjectNotFound {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve a resource control associated to the stack", err}
 	}
@@ -54,5 +55,14 @@ func (handler *Handler) stackFile(w http.ResponseWriter, r *http.Request) *httpe
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve Compose file from disk", err}
 	}
 
-	return response.JSON(w, &stackFileResponse{StackFileContent: string(stackFileContent)})
+	additionalFiles := make([]stackFilePayload, 0)
+	for _, file := range stack.AdditionalFiles {
+		content, err := handler.FileService.GetFileContent(path.Join(stack.ProjectPath, file))
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve additional Compose file from disk", err}
+		}
+		additionalFiles = append(additionalFiles, stackFilePayload{Name: file, Content: string(content)})
+	}
+
+	return response.JSON(w, &stackFileResponse{StackFileContent: string(stackFileContent), AdditionalFiles: additionalFiles})
 }
@@ -0,0 +1,606 @@
+package stacks
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/filesystem"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/proxy"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+	"github.com/portainer/portainer/http/security"
+)
+
+const (
+	swarmStackNamespaceLabel = "com.docker.stack.namespace"
+	composeProjectLabel      = "com.docker.compose.project"
+	composeServiceLabel      = "com.docker.compose.service"
+)
+
+var anonymousVolumeRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type stackImportPayload struct {
+	Name    string
+	SwarmID string
+}
+
+func (payload *stackImportPayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Name) {
+		return portainer.Error("Invalid stack name")
+	}
+	return nil
+}
+
+// importStack creates a stack from a stack deployed outside of Portainer. The Compose file of the stack
+// is reconstructed from the specification of the running services (Swarm stack) or containers (Compose stack).
+// The resource control associated to the external stack is kept, the import is refused when any of the resource
+// controls that can be associated to the external stack denies the access to the user.
+func (handler *Handler) importStack(w http.ResponseWriter, r *http.Request, stackType portainer.StackType, endpoint *portainer.Endpoint) *httperror.HandlerError {
+	var payload stackImportPayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	if stackType == portainer.DockerSwarmStack && govalidator.IsNull(payload.SwarmID) {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", portainer.Error("Invalid Swarm ID")}
+	} else if stackType == portainer.DockerComposeStack {
+		payload.SwarmID = ""
+
+		// The stack is redeployed under the normalized project name, another name would duplicate its containers
+		if composeProjectName(payload.Name) != payload.Name {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", portainer.ErrInvalidComposeProjectName}
+		}
+	}
+
+	isUnique, err := handler.isUniqueStackName(0, payload.Name, endpoint.ID, payload.SwarmID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to check for stack name uniqueness inside the database", err}
+	}
+	if !isUnique {
+		return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists", portainer.ErrStackAlreadyExists}
+	}
+
+	stackID := handler.StackService.GetNextIdentifier()
+	stack := &portainer.Stack{
+		ID:              portainer.StackID(stackID),
+		Name:            payload.Name,
+		Type:            stackType,
+		EndpointID:      endpoint.ID,
+		SwarmID:         payload.SwarmID,
+		EntryPoint:      filesystem.ComposeFileDefaultName,
+		Env:             []portainer.Pair{},
+		EnvironmentSets: []portainer.EnvironmentSetID{},
+		AdditionalFiles: []string{},
+		Status:          portainer.StackStatusActive,
+	}
+
+	resourceControls, err := handler.externalStackResourceControls(stack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve a resource control associated to the stack", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	if !securityContext.IsAdmin {
+		for idx := range resourceControls {
+			if !proxy.CanAccessStack(stack, &resourceControls[idx], securityContext.UserID, securityContext.UserMemberships) {
+				return &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", portainer.ErrResourceAccessDenied}
+			}
+		}
+	}
+
+	cli, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Docker client", err}
+	}
+	defer cli.Close()
+
+	var definition map[string]interface{}
+	if stackType == portainer.DockerSwarmStack {
+		definition, err = swarmStackDefinition(cli, payload.Name)
+	} else {
+		definition, err = composeStackDefinition(cli, payload.Name)
+	}
+	if err == portainer.ErrExternalStackNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a running stack with this name on the endpoint", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to reconstruct the Compose file of the stack", err}
+	}
+
+	stackFolder := strconv.Itoa(int(stack.ID))
+	projectPath, err := handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, marshalComposeFile(definition))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist Compose file on disk", err}
+	}
+	stack.ProjectPath = projectPath
+
+	doCleanUp := true
+	defer handler.cleanUp(stack, &doCleanUp)
+
+	err = handler.StackService.CreateStack(stack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+	}
+
+	doCleanUp = false
+
+	if len(resourceControls) > 0 {
+		err = handler.adoptResourceControl(&resourceControls[0], stack)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the resource control changes inside the database", err}
+		}
+	}
+
+	return response.JSON(w, stack)
+}
+
+// externalStackResourceControls returns the resource controls that can be associated to an external stack, starting
+// with the resource control of the imported stack when it already exists. Depending on how it was created, the resource
+// control of an external stack is identified by the name of the stack alone, or by the name of the stack followed by
+// the identifier of the endpoint or of the Swarm cluster.
+func (handler *Handler) externalStackResourceControls(stack *portainer.Stack) ([]portainer.ResourceControl, error) {
+	resourceIDs := []string{
+		proxy.StackResourceControlID(stack),
+		proxy.StackResourceControlID(&portainer.Stack{Name: stack.Name, EndpointID: stack.EndpointID}),
+	}
+	if stack.SwarmID != "" {
+		resourceIDs = append(resourceIDs, stack.Name+"_"+stack.SwarmID)
+	}
+	resourceIDs = append(resourceIDs, stack.Name)
+
+	resourceControls := make([]portainer.ResourceControl, 0)
+	retrieved := make(map[string]bool)
+	for _, resourceID := range resourceIDs {
+		if retrieved[resourceID] {
+			continue
+		}
+		retrieved[resourceID] = true
+
+		resourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(resourceID)
+		if err == portainer.ErrObjectNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		resourceControls = append(resourceControls, *resourceControl)
+	}
+
+	return resourceControls, nil
+}
+
+// adoptResourceControl associates the resource control of an external stack to the imported stack.
+// A resource control identified by the name of the stack alone can be shared by the stacks with the same
+// name on other endpoints, it is copied instead of being moved.
+func (handler *Handler) adoptResourceControl(resourceControl *portainer.ResourceControl, stack *portainer.Stack) error {
+	resourceID := proxy.StackResourceControlID(stack)
+	if resourceControl.ResourceID == resourceID {
+		return nil
+	}
+
+	if resourceControl.ResourceID == stack.Name {
+		return handler.duplicateResourceControl(resourceControl, stack)
+	}
+
+	resourceControl.ResourceID = resourceID
+	return handler.ResourceControlService.UpdateResourceControl(resourceControl.ID, resourceControl)
+}
+
+// swarmStackDefinition reconstructs the Compose file of a Swarm stack from the specification of its services.
+// Networks and volumes created by the stack are declared in the Compose file, other networks and volumes
+// as well as secrets and configs are declared as external resources.
+func swarmStackDefinition(cli *client.Client, stackName string) (map[string]interface{}, error) {
+	filter := filters.NewArgs()
+	filter.Add("label", swarmStackNamespaceLabel+"="+stackName)
+
+	services, err := cli.ServiceList(context.Background(), types.ServiceListOptions{Filters: filter})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(services) == 0 {
+		return nil, portainer.ErrExternalStackNotFound
+	}
+
+	networkList, err := cli.NetworkList(context.Background(), types.NetworkListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	networksByID := make(map[string]types.NetworkResource)
+	for _, network := range networkList {
+		networksByID[network.ID] = network
+	}
+
+	prefix := stackName + "_"
+	serviceDefinitions := make(map[string]interface{})
+	networks := make(map[string]interface{})
+	volumes := make(map[string]interface{})
+	secrets := make(map[string]interface{})
+	configs := make(map[string]interface{})
+
+	for _, service := range services {
+		spec := service.Spec
+		containerSpec := spec.TaskTemplate.ContainerSpec
+		if containerSpec == nil {
+			continue
+		}
+
+		definition := map[string]interface{}{
+			"image": stripImageDigest(containerSpec.Image),
+		}
+
+		if len(containerSpec.Command) > 0 {
+			definition["entrypoint"] = stringSequence(containerSpec.Command)
+		}
+		if len(containerSpec.Args) > 0 {
+			definition["command"] = stringSequence(containerSpec.Args)
+		}
+		if len(containerSpec.Env) > 0 {
+			definition["environment"] = stringSequence(containerSpec.Env)
+		}
+		if containerSpec.Hostname != "" {
+			definition["hostname"] = containerSpec.Hostname
+		}
+		if containerSpec.Dir != "" {
+			definition["working_dir"] = containerSpec.Dir
+		}
+		if containerSpec.User != "" {
+			definition["user"] = containerSpec.User
+		}
+
+		labels := stringMapping(containerSpec.Labels, "com.docker.stack.")
+		if len(labels) > 0 {
+			definition["labels"] = labels
+		}
+
+		deploy := make(map[string]interface{})
+		if spec.Mode.Global != nil {
+			deploy["mode"] = "global"
+		} else if spec.Mode.Replicated != nil && spec.Mode.Replicated.Replicas != nil {
+			deploy["replicas"] = *spec.Mode.Replicated.Replicas
+		}
+
+		serviceLabels := stringMapping(spec.Labels, "com.docker.stack.")
+		if len(serviceLabels) > 0 {
+			deploy["labels"] = serviceLabels
+		}
+
+		if spec.TaskTemplate.Placement != nil && len(spec.TaskTemplate.Placement.Constraints) > 0 {
+			deploy["placement"] = map[string]interface{}{
+				"constraints": stringSequence(spec.TaskTemplate.Placement.Constraints),
+			}
+		}
+		definition["deploy"] = deploy
+
+		if spec.EndpointSpec != nil && len(spec.EndpointSpec.Ports) > 0 {
+			ports := make([]interface{}, 0)
+			for _, port := range spec.EndpointSpec.Ports {
+				portDefinition := map[string]interface{}{
+					"target":   port.TargetPort,
+					"protocol": string(port.Protocol),
+				}
+				if port.PublishedPort != 0 {
+					portDefinition["published"] = port.PublishedPort
+				}
+				if port.PublishMode != "" {
+					portDefinition["mode"] = string(port.PublishMode)
+				}
+				ports = append(ports, portDefinition)
+			}
+			definition["ports"] = ports
+		}
+
+		serviceNetworks := make([]string, 0)
+		for _, attachment := range spec.TaskTemplate.Networks {
+			network, ok := networksByID[attachment.Target]
+			if !ok {
+				continue
+			}
+
+			if network.Labels[swarmStackNamespaceLabel] == stackName {
+				networkName := strings.TrimPrefix(network.Name, prefix)
+				networkDefinition := map[string]interface{}{
+					"driver": network.Driver,
+				}
+				if network.Attachable {
+					networkDefinition["attachable"] = true
+				}
+				networks[networkName] = networkDefinition
+				serviceNetworks = append(serviceNetworks, networkName)
+			} else {
+				networks[network.Name] = map[string]interface{}{
+					"external": true,
+					"name":     network.Name,
+				}
+				serviceNetworks = append(serviceNetworks, network.Name)
+			}
+		}
+		if len(serviceNetworks) > 0 {
+			definition["networks"] = stringSequence(serviceNetworks)
+		}
+
+		serviceVolumes := make([]string, 0)
+		for _, m := range containerSpec.Mounts {
+			switch m.Type {
+			case mount.TypeVolume:
+				if m.Source == "" {
+					serviceVolumes = append(serviceVolumes, m.Target)
+					continue
+				}
+
+				volumeName := m.Source
+				if m.VolumeOptions != nil && m.VolumeOptions.Labels[swarmStackNamespaceLabel] == stackName {
+					volumeName = strings.TrimPrefix(m.Source, prefix)
+					volumeDefinition := make(map[string]interface{})
+					if m.VolumeOptions.DriverConfig != nil && m.VolumeOptions.DriverConfig.Name != "" {
+						volumeDefinition["driver"] = m.VolumeOptions.DriverConfig.Name
+					}
+					volumes[volumeName] = volumeDefinition
+				} else {
+					volumes[volumeName] = map[string]interface{}{
+						"external": true,
+						"name":     m.Source,
+					}
+				}
+				serviceVolumes = append(serviceVolumes, mountDefinition(volumeName, m.Target, m.ReadOnly))
+			case mount.TypeBind:
+				serviceVolumes = append(serviceVolumes, mountDefinition(m.Source, m.Target, m.ReadOnly))
+			}
+		}
+		if len(serviceVolumes) > 0 {
+			definition["volumes"] = stringSequence(serviceVolumes)
+		}
+
+		serviceSecrets := make([]interface{}, 0)
+		for _, secret := range containerSpec.Secrets {
+			secretName := strings.TrimPrefix(secret.SecretName, prefix)
+			secretDefinition := map[string]interface{}{
+				"source": secretName,
+			}
+			if secret.File != nil {
+				secretDefinition["target"] = secret.File.Name
+			}
+			serviceSecrets = append(serviceSecrets, secretDefinition)
+			secrets[secretName] = map[string]interface{}{
+				"external": true,
+				"name":     secret.SecretName,
+			}
+		}
+		if len(serviceSecrets) > 0 {
+			definition["secrets"] = serviceSecrets
+		}
+
+		serviceConfigs := make([]interface{}, 0)
+		for _, config := range containerSpec.Configs {
+			configName := strings.TrimPrefix(config.ConfigName, prefix)
+			configDefinition := map[string]interface{}{
+				"source": configName,
+			}
+			if config.File != nil {
+				configDefinition["target"] = config.File.Name
+			}
+			serviceConfigs = append(serviceConfigs, configDefinition)
+			configs[configName] = map[string]interface{}{
+				"external": true,
+				"name":     config.ConfigName,
+			}
+		}
+		if len(serviceConfigs) > 0 {
+			definition["configs"] = serviceConfigs
+		}
+
+		serviceDefinitions[strings.TrimPrefix(spec.Name, prefix)] = definition
+	}
+
+	return map[string]interface{}{
+		"version":  swarmComposeFileVersion,
+		"services": serviceDefinitions,
+		"networks": networks,
+		"volumes":  volumes,
+		"secrets":  secrets,
+		"configs":  configs,
+	}, nil
+}
+
+// composeStackDefinition reconstructs the Compose file of a Compose stack from the configuration of its containers.
+// The configuration inherited from the image of a container is not reported in the Compose file.
+func composeStackDefinition(cli *client.Client, projectName string) (map[string]interface{}, error) {
+	filter := filters.NewArgs()
+	filter.Add("label", composeProjectLabel+"="+projectName)
+
+	containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{All: true, Filters: filter})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(containers) == 0 {
+		return nil, portainer.ErrExternalStackNotFound
+	}
+
+	prefix := projectName + "_"
+	services := make(map[string]interface{})
+	networks := make(map[string]interface{})
+	volumes := make(map[string]interface{})
+
+	for _, container := range containers {
+		serviceName := container.Labels[composeServiceLabel]
+		if serviceName == "" || services[serviceName] != nil {
+			continue
+		}
+
+		containerInspect, err := cli.ContainerInspect(context.Background(), container.ID)
+		if err != nil {
+			return nil, err
+		}
+		config := containerInspect.Config
+
+		imageConfig := config
+		imageInspect, _, err := cli.ImageInspectWithRaw(context.Background(), containerInspect.Image)
+		if err == nil && imageInspect.Config != nil {
+			imageConfig = imageInspect.Config
+		}
+
+		definition := map[string]interface{}{
+			"image": config.Image,
+		}
+
+		if strings.Join(config.Entrypoint, " ") != strings.Join(imageConfig.Entrypoint, " ") {
+			definition["entrypoint"] = stringSequence(config.Entrypoint)
+		}
+		if strings.Join(config.Cmd, " ") != strings.Join(imageConfig.Cmd, " ") {
+			definition["command"] = stringSequence(config.Cmd)
+		}
+		if config.WorkingDir != imageConfig.WorkingDir {
+			definition["working_dir"] = config.WorkingDir
+		}
+		if config.User != imageConfig.User {
+			definition["user"] = config.User
+		}
+
+		environment := make([]string, 0)
+		for _, variable := range config.Env {
+			if imageConfig == config || !containsFile(imageConfig.Env, variable) {
+				environment = append(environment, variable)
+			}
+		}
+		if len(environment) > 0 {
+			definition["environment"] = stringSequence(environment)
+		}
+
+		labels := stringMapping(config.Labels, "com.docker.compose.")
+		for key, value := range imageConfig.Labels {
+			if imageConfig != config && labels[key] == value {
+				delete(labels, key)
+			}
+		}
+		if len(labels) > 0 {
+			definition["labels"] = labels
+		}
+
+		hostConfig := containerInspect.HostConfig
+		if hostConfig != nil {
+			if hostConfig.RestartPolicy.Name != "" && hostConfig.RestartPolicy.Name != "no" {
+				definition["restart"] = string(hostConfig.RestartPolicy.Name)
+			}
+			if hostConfig.Privileged {
+				definition["privileged"] = true
+			}
+
+			ports := make([]string, 0)
+			for port, bindings := range hostConfig.PortBindings {
+				containerPort := port.Port()
+				if port.Proto() != "tcp" {
+					containerPort += "/" + port.Proto()
+				}
+
+				for _, binding := range bindings {
+					portDefinition := containerPort
+					if binding.HostPort != "" {
+						portDefinition = binding.HostPort + ":" + portDefinition
+					}
+					if binding.HostIP != "" && binding.HostIP != "0.0.0.0" {
+						portDefinition = binding.HostIP + ":" + portDefinition
+					}
+					ports = append(ports, portDefinition)
+				}
+			}
+			if len(ports) > 0 {
+				sort.Strings(ports)
+				definition["ports"] = stringSequence(ports)
+			}
+		}
+
+		serviceVolumes := make([]string, 0)
+		for _, m := range containerInspect.Mounts {
+			switch m.Type {
+			case mount.TypeVolume:
+				if anonymousVolumeRe.MatchString(m.Name) {
+					serviceVolumes = append(serviceVolumes, m.Destination)
+					continue
+				}
+
+				volumeName := m.Name
+				if strings.HasPrefix(m.Name, prefix) {
+					volumeName = strings.TrimPrefix(m.Name, prefix)
+					volumes[volumeName] = map[string]interface{}{}
+				} else {
+					volumes[volumeName] = map[string]interface{}{
+						"external": true,
+					}
+				}
+				serviceVolumes = append(serviceVolumes, mountDefinition(volumeName, m.Destination, !m.RW))
+			case mount.TypeBind:
+				serviceVolumes = append(serviceVolumes, mountDefinition(m.Source, m.Destination, !m.RW))
+			}
+		}
+		if len(serviceVolumes) > 0 {
+			definition["volumes"] = stringSequence(serviceVolumes)
+		}
+
+		serviceNetworks := make([]string, 0)
+		if containerInspect.NetworkSettings != nil {
+			for networkName := range containerInspect.NetworkSettings.Networks {
+				switch {
+				case networkName == prefix+"default":
+					continue
+				case networkName == "bridge" || networkName == "host" || networkName == "none":
+					definition["network_mode"] = networkName
+				case strings.HasPrefix(networkName, prefix):
+					networkName = strings.TrimPrefix(networkName, prefix)
+					networks[networkName] = map[string]interface{}{}
+					serviceNetworks = append(serviceNetworks, networkName)
+				default:
+					networks[networkName] = map[string]interface{}{
+						"external": true,
+					}
+					serviceNetworks = append(serviceNetworks, networkName)
+				}
+			}
+		}
+		if len(serviceNetworks) > 0 {
+			sort.Strings(serviceNetworks)
+			definition["networks"] = stringSequence(serviceNetworks)
+		}
+
+		services[serviceName] = definition
+	}
+
+	return map[string]interface{}{
+		"version":  composeComposeFileVersion,
+		"services": services,
+		"networks": networks,
+		"volumes":  volumes,
+	}, nil
+}
+
+// stripImageDigest removes the digest added to the image reference when a stack is deployed,
+// so that the image is resolved again on the next deployment.
+func stripImageDigest(image string) string {
+	if index := strings.Index(image, "@"); index != -1 {
+		return image[:index]
+	}
+	return image
+}
+
+func mountDefinition(source, target string, readOnly bool) string {
+	definition := source + ":" + target
+	if readOnly {
+		definition += ":ro"
+	}
+	return definition
+}
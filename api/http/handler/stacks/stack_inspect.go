@@ -11,7 +11,14 @@ import (
 	"github.com/portainer/portainer/http/security"
 )
 
+type stackInspectResponse struct {
+	proxy.ExtendedStack
+	Environment []portainer.EnvironmentVariable `json:"Environment"`
+}
+
 // GET request on /api/stacks/:id
+// The response contains the whole environment of the stack, including the variables of the environment sets
+// referenced by the stack. The values of the secret variables are masked.
 func (handler *Handler) stackInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
 	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
 	if err != nil {
@@ -25,7 +32,7 @@ func (handler *Handler) stackInspect(w http.ResponseWriter, r *http.Request) *ht
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
 	}
 
-	resourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(stack.Name)
+	resourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(proxy.StackResourceControlID(stack))
 	if err != nil && err != portainer.ErrObjectNotFound {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve a resource control associated to the stack", err}
 	}
@@ -44,5 +51,39 @@ func (handler *Handler) stackInspect(w http.ResponseWriter, r *http.Request) *ht
 		}
 	}
 
-	return response.JSON(w, extendedStack)
+	environment, err := handler.inspectStackEnvironment(stack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the environment of the stack", err}
+	}
+
+	return response.JSON(w, &stackInspectResponse{ExtendedStack: extendedStack, Environment: environment})
+}
+
+// inspectStackEnvironment returns the environment of a stack with masked secret values. An environment set
+// that cannot be used anymore on the endpoint of the stack, for instance after the endpoint has been moved
+// to another group, is skipped so that the stack can still be inspected and fixed.
+func (handler *Handler) inspectStackEnvironment(stack *portainer.Stack) ([]portainer.EnvironmentVariable, error) {
+	endpoint, err := handler.EndpointService.Endpoint(stack.EndpointID)
+	if err == portainer.ErrObjectNotFound {
+		endpoint = &portainer.Endpoint{ID: stack.EndpointID}
+	} else if err != nil {
+		return nil, err
+	}
+
+	applicableStack := *stack
+	applicableStack.EnvironmentSets = make([]portainer.EnvironmentSetID, 0, len(stack.EnvironmentSets))
+	for _, setID := range stack.EnvironmentSets {
+		set, err := handler.EnvironmentSetService.EnvironmentSet(setID)
+		if err == portainer.ErrObjectNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if security.AuthorizedEnvironmentSetEndpoint(set, endpoint) {
+			applicableStack.EnvironmentSets = append(applicableStack.EnvironmentSets, setID)
+		}
+	}
+
+	return handler.stackEnvironment(&applicableStack, endpoint, false)
 }
@@ -0,0 +1,71 @@
+package stacks
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+	"github.com/portainer/portainer/http/security"
+)
+
+// GET request on /api/stacks/jobs/:id
+func (handler *Handler) stackJobInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	job, handlerErr := handler.retrieveStackJob(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	return response.JSON(w, job)
+}
+
+// retrieveStackJob returns the stack job identified by the id route variable. Only administrators
+// and the user who started the job can access it. The output of a job being executed is
+// retrieved from the job output buffer.
+func (handler *Handler) retrieveStackJob(r *http.Request) (*portainer.StackJob, *httperror.HandlerError) {
+	jobID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid stack job identifier route variable", err}
+	}
+
+	job, err := handler.StackJobService.StackJob(portainer.StackJobID(jobID))
+	if err == portainer.ErrObjectNotFound {
+		return nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find a stack job with the specified identifier inside the database", err}
+	} else if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack job with the specified identifier inside the database", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	if !securityContext.IsAdmin && securityContext.UserID != job.UserID {
+		return nil, &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", portainer.ErrResourceAccessDenied}
+	}
+
+	if job.Status != portainer.StackJobQueued && job.Status != portainer.StackJobRunning {
+		return job, nil
+	}
+
+	output := handler.jobOutputs.get(job.ID)
+	if output != nil {
+		job.Output = output.String()
+		return job, nil
+	}
+
+	// The job might have completed after it was retrieved
+	job, err = handler.StackJobService.StackJob(job.ID)
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack job with the specified identifier inside the database", err}
+	}
+
+	if job.Status == portainer.StackJobQueued || job.Status == portainer.StackJobRunning {
+		job.Status = portainer.StackJobFailed
+		job.Error = "The stack job was interrupted"
+		handler.persistStackJob(job)
+	}
+
+	return job, nil
+}
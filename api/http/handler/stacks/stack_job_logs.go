@@ -0,0 +1,109 @@
+package stacks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+)
+
+// GET request on /api/stacks/jobs/:id/logs
+// Streams the output of a stack job as server-sent events. Each line of output is sent as a data event
+// and a final status event containing the job is sent once the job is completed. Streaming stops when
+// the client disconnects.
+func (handler *Handler) stackJobLogs(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	job, handlerErr := handler.retrieveStackJob(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Streaming is not supported", portainer.Error("Streaming unsupported")}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	output := handler.jobOutputs.get(job.ID)
+	if output == nil {
+		writeLogEvents(w, []byte(job.Output), true)
+		writeStatusEvent(w, job)
+		flusher.Flush()
+		return nil
+	}
+
+	done := r.Context().Done()
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		select {
+		case <-done:
+			output.wake()
+		case <-stop:
+		}
+	}()
+
+	var pending []byte
+	offset := 0
+	for {
+		data, closed := output.next(done, offset)
+		if r.Context().Err() != nil {
+			return nil
+		}
+		offset += len(data)
+
+		pending = writeLogEvents(w, append(pending, data...), closed)
+		flusher.Flush()
+
+		if closed {
+			break
+		}
+	}
+
+	job, err := handler.StackJobService.StackJob(job.ID)
+	if err != nil {
+		return nil
+	}
+
+	writeStatusEvent(w, job)
+	flusher.Flush()
+	return nil
+}
+
+// writeLogEvents writes a data event for each complete line of data and returns the remaining
+// partial line. The partial line is written as well when final is set to true.
+func writeLogEvents(w http.ResponseWriter, data []byte, final bool) []byte {
+	for {
+		index := bytes.IndexByte(data, '\n')
+		if index < 0 {
+			break
+		}
+
+		fmt.Fprintf(w, "data: %s\n\n", bytes.TrimSuffix(data[:index], []byte("\r")))
+		data = data[index+1:]
+	}
+
+	if final && len(data) > 0 {
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		return nil
+	}
+
+	return data
+}
+
+func writeStatusEvent(w http.ResponseWriter, job *portainer.StackJob) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+}
@@ -0,0 +1,50 @@
+package stacks
+
+import (
+	"sync"
+
+	"github.com/portainer/portainer"
+)
+
+// stackJobQueue orders the operations executed on the stacks. The operations on a stack are executed
+// one at a time, in the order they were queued, while operations on different stacks run concurrently.
+type stackJobQueue struct {
+	mutex sync.Mutex
+	lanes map[portainer.StackID][]chan struct{}
+}
+
+func newStackJobQueue() *stackJobQueue {
+	return &stackJobQueue{
+		lanes: make(map[portainer.StackID][]chan struct{}),
+	}
+}
+
+// enqueue queues an operation on a stack. The returned channel is closed when the operation can be executed,
+// release must be called once the operation is completed.
+func (queue *stackJobQueue) enqueue(stackID portainer.StackID) <-chan struct{} {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	ready := make(chan struct{})
+	queue.lanes[stackID] = append(queue.lanes[stackID], ready)
+	if len(queue.lanes[stackID]) == 1 {
+		close(ready)
+	}
+
+	return ready
+}
+
+// release completes the operation being executed on a stack and lets the next queued operation run.
+func (queue *stackJobQueue) release(stackID portainer.StackID) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	lane := queue.lanes[stackID]
+	if len(lane) <= 1 {
+		delete(queue.lanes, stackID)
+		return
+	}
+
+	queue.lanes[stackID] = lane[1:]
+	close(lane[1])
+}
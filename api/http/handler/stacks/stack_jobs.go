@@ -0,0 +1,258 @@
+package stacks
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/proxy"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+	"github.com/portainer/portainer/http/security"
+)
+
+// stackOperation represents an operation executed on a stack. The output of the
+// deployment tools is written to output when it is not nil.
+type stackOperation func(output io.Writer) error
+
+// isAsyncRequest returns true when the optional async query parameter is set to true.
+func isAsyncRequest(r *http.Request) bool {
+	async, _ := request.RetrieveBooleanQueryParameter(r, "async", true)
+	return async
+}
+
+// stackDeploymentResponse represents the response of a stack deployment, including the result
+// of the verification of the services of the stack when it was requested and the resource control
+// associated to the stack when it was created along with the stack.
+type stackDeploymentResponse struct {
+	*portainer.Stack
+	Convergence     *portainer.StackConvergence `json:"Convergence,omitempty"`
+	ResourceControl *portainer.ResourceControl  `json:"ResourceControl,omitempty"`
+}
+
+// createAndDeployStack deploys a new stack and persists it. When the request is asynchronous,
+// the stack is persisted first to reserve its identifier and name, then deployed in a stack job.
+// The stack is removed if the deployment fails. resourceControl is optional, it is associated to the
+// stack once the stack is persisted.
+func (handler *Handler) createAndDeployStack(w http.ResponseWriter, r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint, doCleanUp *bool, resourceControl *portainer.ResourceControl, deploy stackOperation) *httperror.HandlerError {
+	options, err := retrieveConvergenceOptions(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: convergenceTimeout", err}
+	}
+
+	execution := &stackExecution{
+		stack:     stack,
+		endpoint:  endpoint,
+		operation: deploy,
+		options:   options,
+	}
+
+	if !isAsyncRequest(r) {
+		convergence, err := handler.executeInQueue(execution)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		}
+
+		err = handler.StackService.CreateStack(stack)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+		}
+
+		*doCleanUp = false
+
+		if resourceControl != nil {
+			err = handler.createStackResourceControl(resourceControl, stack)
+			if err != nil {
+				return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the resource control inside the database", err}
+			}
+		}
+
+		return response.JSON(w, &stackDeploymentResponse{Stack: stack, Convergence: convergence, ResourceControl: resourceControl})
+	}
+
+	err = handler.StackService.CreateStack(stack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+	}
+
+	*doCleanUp = false
+
+	if resourceControl != nil {
+		err = handler.createStackResourceControl(resourceControl, stack)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the resource control inside the database", err}
+		}
+	}
+
+	return handler.startStackJob(w, r, portainer.StackJobCreate, execution, func() {
+		err := handler.StackService.DeleteStack(stack.ID)
+		if err != nil {
+			log.Printf("http error: Unable to remove stack after a failed deployment (err=%s)\n", err)
+		}
+
+		if resourceControl != nil {
+			err = handler.ResourceControlService.DeleteResourceControl(resourceControl.ID)
+			if err != nil {
+				log.Printf("http error: Unable to remove the resource control of a stack after a failed deployment (err=%s)\n", err)
+			}
+		}
+
+		err = handler.FileService.RemoveDirectory(stack.ProjectPath)
+		if err != nil {
+			log.Printf("http error: Unable to cleanup stack creation (err=%s)\n", err)
+		}
+	})
+}
+
+// createStackResourceControl associates a resource control to a stack and persists it.
+func (handler *Handler) createStackResourceControl(resourceControl *portainer.ResourceControl, stack *portainer.Stack) error {
+	resourceControl.Type = portainer.StackResourceControl
+	resourceControl.ResourceID = proxy.StackResourceControlID(stack)
+	return handler.ResourceControlService.CreateResourceControl(resourceControl)
+}
+
+// startStackJob persists a new stack job, runs the stack execution in the background and writes the job
+// to the response. onFailure is optional and called when the operation of the execution fails.
+func (handler *Handler) startStackJob(w http.ResponseWriter, r *http.Request, jobType portainer.StackJobType, execution *stackExecution, onFailure func()) *httperror.HandlerError {
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	job := &portainer.StackJob{
+		StackID:   execution.stack.ID,
+		Type:      jobType,
+		Status:    portainer.StackJobQueued,
+		UserID:    securityContext.UserID,
+		CreatedAt: time.Now().Unix(),
+	}
+
+	output, err := handler.jobOutputs.open(func() (portainer.StackJobID, error) {
+		err := handler.StackJobService.CreateStackJob(job)
+		return job.ID, err
+	})
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack job inside the database", err}
+	}
+
+	queuedJob := *job
+	ready := handler.stackJobQueue.enqueue(execution.stack.ID)
+	go handler.runStackJob(job, output, execution, ready, onFailure)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	return response.JSON(w, &queuedJob)
+}
+
+// runStackJob runs the stack execution of a stack job once ready is closed. A stack that did not converge fails the job.
+func (handler *Handler) runStackJob(job *portainer.StackJob, output *jobOutput, execution *stackExecution, ready <-chan struct{}, onFailure func()) {
+	defer handler.jobOutputs.remove(job.ID)
+	defer output.close()
+
+	queuedExecution := handler.queuedExecution(execution, ready, func() {
+		job.Status = portainer.StackJobRunning
+		job.StartedAt = time.Now().Unix()
+		handler.persistStackJob(job)
+	})
+
+	convergence, err := handler.execute(queuedExecution, output)
+	job.Convergence = convergence
+	if err != nil {
+		job.Status = portainer.StackJobFailed
+		job.Error = err.Error()
+		if onFailure != nil && convergence == nil {
+			onFailure()
+		}
+	} else if convergence != nil && !convergence.Converged {
+		job.Status = portainer.StackJobFailed
+		job.Error = portainer.ErrStackNotConverged.Error()
+	} else {
+		job.Status = portainer.StackJobSucceeded
+	}
+
+	job.Output = output.String()
+	job.FinishedAt = time.Now().Unix()
+	handler.persistStackJob(job)
+}
+
+// executeInQueue queues a stack execution and runs it once the operations queued before it on the stack
+// are completed. It is used by the synchronous requests so that they do not run concurrently with stack jobs.
+func (handler *Handler) executeInQueue(execution *stackExecution) (*portainer.StackConvergence, error) {
+	ready := handler.stackJobQueue.enqueue(execution.stack.ID)
+	return handler.execute(handler.queuedExecution(execution, ready, nil), nil)
+}
+
+// queuedExecution returns a copy of a stack execution whose operation is executed once ready is closed.
+// The operations queued on a stack are executed one at a time, in the order they were queued. The stack is
+// released while its services are verified, a rollback is queued after the operations queued in the meantime.
+// onStart is optional and called when the operation starts.
+func (handler *Handler) queuedExecution(execution *stackExecution, ready <-chan struct{}, onStart func()) *stackExecution {
+	stackID := execution.stack.ID
+	queuedExecution := *execution
+
+	queuedExecution.operation = func(output io.Writer) error {
+		<-ready
+		defer handler.stackJobQueue.release(stackID)
+
+		if onStart != nil {
+			onStart()
+		}
+
+		return execution.operation(output)
+	}
+
+	if execution.rollback != nil {
+		queuedExecution.rollback = func(output io.Writer) error {
+			<-handler.stackJobQueue.enqueue(stackID)
+			defer handler.stackJobQueue.release(stackID)
+
+			return execution.rollback(output)
+		}
+	}
+
+	return &queuedExecution
+}
+
+// lockStack waits until the operations queued on a stack are completed and reloads the stack, as these
+// operations can update or remove it. The returned function releases the stack, it must be called once
+// the request is done with the stack.
+func (handler *Handler) lockStack(stack *portainer.Stack) (func(), *httperror.HandlerError) {
+	stackID := stack.ID
+	<-handler.stackJobQueue.enqueue(stackID)
+	release := func() {
+		handler.stackJobQueue.release(stackID)
+	}
+
+	err := handler.reloadStack(stack)
+	if err == portainer.ErrObjectNotFound {
+		release()
+		return nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find a stack with the specified identifier inside the database", err}
+	} else if err != nil {
+		release()
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
+	}
+
+	return release, nil
+}
+
+// reloadStack replaces a stack with its current version in the database. It is used by the operations
+// waiting in the queue of a stack, as the operations executed before them can update or remove the stack.
+func (handler *Handler) reloadStack(stack *portainer.Stack) error {
+	current, err := handler.StackService.Stack(stack.ID)
+	if err != nil {
+		return err
+	}
+
+	*stack = *current
+	return nil
+}
+
+func (handler *Handler) persistStackJob(job *portainer.StackJob) {
+	err := handler.StackJobService.UpdateStackJob(job.ID, job)
+	if err != nil {
+		log.Printf("http error: Unable to persist stack job (job=%d) (err=%s)\n", job.ID, err)
+	}
+}
@@ -0,0 +1,349 @@
+package stacks
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+type testStackJobService struct {
+	mutex    sync.Mutex
+	statuses map[portainer.StackJobID][]portainer.StackJobStatus
+	nextID   portainer.StackJobID
+}
+
+func newTestStackJobService() *testStackJobService {
+	return &testStackJobService{statuses: make(map[portainer.StackJobID][]portainer.StackJobStatus)}
+}
+
+func (service *testStackJobService) StackJob(ID portainer.StackJobID) (*portainer.StackJob, error) {
+	return nil, portainer.ErrObjectNotFound
+}
+
+func (service *testStackJobService) StackJobs() ([]portainer.StackJob, error) {
+	return nil, nil
+}
+
+func (service *testStackJobService) CreateStackJob(job *portainer.StackJob) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	service.nextID++
+	job.ID = service.nextID
+	return nil
+}
+
+func (service *testStackJobService) UpdateStackJob(ID portainer.StackJobID, job *portainer.StackJob) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	service.statuses[ID] = append(service.statuses[ID], job.Status)
+	return nil
+}
+
+func (service *testStackJobService) DeleteStackJob(ID portainer.StackJobID) error {
+	return nil
+}
+
+func TestStackJobQueue(t *testing.T) {
+	tests := []struct {
+		name     string
+		stackIDs []portainer.StackID
+		ready    []bool
+	}{
+		{
+			name:     "First operation on a stack is ready",
+			stackIDs: []portainer.StackID{1},
+			ready:    []bool{true},
+		},
+		{
+			name:     "Operations on the same stack wait for the first one",
+			stackIDs: []portainer.StackID{1, 1, 1},
+			ready:    []bool{true, false, false},
+		},
+		{
+			name:     "Operations on different stacks run concurrently",
+			stackIDs: []portainer.StackID{1, 2, 1, 3},
+			ready:    []bool{true, true, false, true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queue := newStackJobQueue()
+
+			channels := make([]<-chan struct{}, len(test.stackIDs))
+			for idx, stackID := range test.stackIDs {
+				channels[idx] = queue.enqueue(stackID)
+			}
+
+			for idx, ready := range test.ready {
+				if isClosed(channels[idx]) != ready {
+					t.Errorf("Unexpected state for operation %d: got ready=%t want ready=%t", idx, !ready, ready)
+				}
+			}
+		})
+	}
+}
+
+func TestStackJobQueueOrder(t *testing.T) {
+	queue := newStackJobQueue()
+
+	first := queue.enqueue(1)
+	second := queue.enqueue(1)
+	third := queue.enqueue(1)
+
+	steps := []struct {
+		name    string
+		release bool
+		ready   []bool
+	}{
+		{name: "Before any release", ready: []bool{true, false, false}},
+		{name: "After the first release", release: true, ready: []bool{true, true, false}},
+		{name: "After the second release", release: true, ready: []bool{true, true, true}},
+		{name: "After the last release", release: true, ready: []bool{true, true, true}},
+	}
+
+	for _, step := range steps {
+		if step.release {
+			queue.release(1)
+		}
+
+		for idx, channel := range []<-chan struct{}{first, second, third} {
+			if isClosed(channel) != step.ready[idx] {
+				t.Errorf("%s: unexpected state for operation %d: want ready=%t", step.name, idx, step.ready[idx])
+			}
+		}
+	}
+
+	if len(queue.lanes) != 0 {
+		t.Errorf("Expected the queue to be empty, but it contains %d stacks", len(queue.lanes))
+	}
+}
+
+func TestRunStackJob(t *testing.T) {
+	tests := []struct {
+		name              string
+		operationErr      error
+		expectedStatuses  []portainer.StackJobStatus
+		expectedError     string
+		expectedOnFailure bool
+	}{
+		{
+			name:             "Successful operation",
+			expectedStatuses: []portainer.StackJobStatus{portainer.StackJobRunning, portainer.StackJobSucceeded},
+		},
+		{
+			name:              "Failed operation",
+			operationErr:      portainer.Error("deployment failed"),
+			expectedStatuses:  []portainer.StackJobStatus{portainer.StackJobRunning, portainer.StackJobFailed},
+			expectedError:     "deployment failed",
+			expectedOnFailure: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := newTestStackJobService()
+			handler := &Handler{
+				stackJobQueue:   newStackJobQueue(),
+				jobOutputs:      newJobOutputs(),
+				StackJobService: service,
+			}
+
+			job := &portainer.StackJob{StackID: 1, Status: portainer.StackJobQueued}
+			output, _ := handler.jobOutputs.open(func() (portainer.StackJobID, error) {
+				err := service.CreateStackJob(job)
+				return job.ID, err
+			})
+
+			execution := &stackExecution{
+				stack: &portainer.Stack{ID: 1},
+				operation: func(output io.Writer) error {
+					io.WriteString(output, "deploying\n")
+					return test.operationErr
+				},
+			}
+
+			onFailureCalled := false
+			handler.runStackJob(job, output, execution, handler.stackJobQueue.enqueue(1), func() {
+				onFailureCalled = true
+			})
+
+			statuses := service.statuses[job.ID]
+			if len(statuses) != len(test.expectedStatuses) {
+				t.Fatalf("Unexpected status transitions: got %v want %v", statuses, test.expectedStatuses)
+			}
+			for idx := range statuses {
+				if statuses[idx] != test.expectedStatuses[idx] {
+					t.Errorf("Unexpected status transitions: got %v want %v", statuses, test.expectedStatuses)
+				}
+			}
+
+			if job.Error != test.expectedError {
+				t.Errorf("Unexpected job error: got %q want %q", job.Error, test.expectedError)
+			}
+			if onFailureCalled != test.expectedOnFailure {
+				t.Errorf("Unexpected call to onFailure: got %t want %t", onFailureCalled, test.expectedOnFailure)
+			}
+			if job.Output != "deploying\n" {
+				t.Errorf("Unexpected job output: got %q", job.Output)
+			}
+			if handler.jobOutputs.get(job.ID) != nil {
+				t.Error("Expected the job output to be unregistered")
+			}
+			if len(handler.stackJobQueue.lanes) != 0 {
+				t.Error("Expected the stack to be released")
+			}
+		})
+	}
+}
+
+func TestRunStackJobWaitsForQueue(t *testing.T) {
+	service := newTestStackJobService()
+	handler := &Handler{
+		stackJobQueue:   newStackJobQueue(),
+		jobOutputs:      newJobOutputs(),
+		StackJobService: service,
+	}
+
+	blocker := handler.stackJobQueue.enqueue(1)
+	<-blocker
+
+	job := &portainer.StackJob{ID: 1, StackID: 1, Status: portainer.StackJobQueued}
+	execution := &stackExecution{
+		stack:     &portainer.Stack{ID: 1},
+		operation: func(output io.Writer) error { return nil },
+	}
+
+	done := make(chan struct{})
+	go func() {
+		handler.runStackJob(job, newJobOutput(), execution, handler.stackJobQueue.enqueue(1), nil)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Expected the job to wait for the operation queued before it")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	handler.stackJobQueue.release(1)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the job to run once the previous operation is released")
+	}
+
+	if job.Status != portainer.StackJobSucceeded {
+		t.Errorf("Unexpected job status: got %d want %d", job.Status, portainer.StackJobSucceeded)
+	}
+}
+
+func isClosed(channel <-chan struct{}) bool {
+	select {
+	case <-channel:
+		return true
+	default:
+		return false
+	}
+}
+
+func (service *testStackService) Stack(ID portainer.StackID) (*portainer.Stack, error) {
+	for _, stack := range service.stacks {
+		if stack.ID == ID {
+			return &stack, nil
+		}
+	}
+	return nil, portainer.ErrObjectNotFound
+}
+
+func TestExecuteInQueueWaitsForQueuedJobs(t *testing.T) {
+	handler := &Handler{stackJobQueue: newStackJobQueue()}
+
+	<-handler.stackJobQueue.enqueue(1)
+
+	executed := make(chan struct{})
+	execution := &stackExecution{
+		stack: &portainer.Stack{ID: 1},
+		operation: func(output io.Writer) error {
+			close(executed)
+			return nil
+		},
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := handler.executeInQueue(execution)
+		done <- err
+	}()
+
+	select {
+	case <-executed:
+		t.Fatal("Expected the operation to wait for the job queued before it")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	handler.stackJobQueue.release(1)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the operation to run once the previous job is released")
+	}
+
+	if len(handler.stackJobQueue.lanes) != 0 {
+		t.Error("Expected the stack to be released")
+	}
+}
+
+func TestLockStack(t *testing.T) {
+	tests := []struct {
+		name           string
+		stackID        portainer.StackID
+		expectedStatus int
+		expectedName   string
+	}{
+		{name: "Stack is reloaded", stackID: 1, expectedName: "updated"},
+		{name: "Stack removed by a queued job", stackID: 2, expectedStatus: 404},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := &Handler{
+				stackJobQueue: newStackJobQueue(),
+				StackService:  &testStackService{stacks: []portainer.Stack{{ID: 1, Name: "updated"}}},
+			}
+
+			stack := &portainer.Stack{ID: test.stackID, Name: "stale"}
+			release, handlerErr := handler.lockStack(stack)
+			if handlerErr != nil {
+				if handlerErr.StatusCode != test.expectedStatus {
+					t.Fatalf("Unexpected status code: got %d want %d", handlerErr.StatusCode, test.expectedStatus)
+				}
+			} else {
+				if test.expectedStatus != 0 {
+					t.Fatalf("Expected an error with the status code %d", test.expectedStatus)
+				}
+				if stack.Name != test.expectedName {
+					t.Errorf("Unexpected stack name: got %s want %s", stack.Name, test.expectedName)
+				}
+				if isClosed(handler.stackJobQueue.enqueue(test.stackID)) {
+					t.Error("Expected the stack to be locked")
+				}
+				release()
+			}
+
+			if test.expectedStatus != 0 && len(handler.stackJobQueue.lanes) != 0 {
+				t.Error("Expected the stack to be released")
+			}
+		})
+	}
+}
@@ -43,7 +43,7 @@ func (handler *Handler) stackMigrate(w http.ResponseWriter, r *http.Request) *ht
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
 	}
 
-	resourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(stack.Name)
+	resourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(proxy.StackResourceControlID(stack))
 	if err != nil && err != portainer.ErrObjectNotFound {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve a resource control associated to the stack", err}
 	}
@@ -59,6 +59,12 @@ func (handler *Handler) stackMigrate(w http.ResponseWriter, r *http.Request) *ht
 		}
 	}
 
+	release, handlerErr := handler.lockStack(stack)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer release()
+
 	// TODO: this is a work-around for stacks created with Portainer version >= 1.17.1
 	// The EndpointID property is not available for these stacks, this API endpoint
 	// can use the optional EndpointID query parameter to associate a valid endpoint identifier to the stack.
@@ -84,26 +90,50 @@ func (handler *Handler) stackMigrate(w http.ResponseWriter, r *http.Request) *ht
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
 	}
 
+	validationError := handler.validateEnvironmentSets(r, stack.EnvironmentSets, nil, targetEndpoint)
+	if validationError != nil {
+		return validationError
+	}
+
 	stack.EndpointID = portainer.EndpointID(payload.EndpointID)
 	if payload.SwarmID != "" {
 		stack.SwarmID = payload.SwarmID
 	}
 
+	isUnique, err := handler.isUniqueStackName(stack.ID, stack.Name, stack.EndpointID, stack.SwarmID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to check for stack name uniqueness inside the database", err}
+	}
+	if !isUnique {
+		return &httperror.HandlerError{http.StatusConflict, "A stack with this name already exists on the target endpoint", portainer.ErrStackAlreadyExists}
+	}
+
 	migrationError := handler.migrateStack(r, stack, targetEndpoint)
 	if migrationError != nil {
 		return migrationError
 	}
 
-	err = handler.deleteStack(stack, endpoint)
+	err = handler.deleteStack(stack, endpoint, nil)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
 	}
 
+	stack.Status = portainer.StackStatusActive
+	stack.StoppedReplicas = nil
+
 	err = handler.StackService.UpdateStack(stack.ID, stack)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
 	}
 
+	if resourceControl != nil {
+		resourceControl.ResourceID = proxy.StackResourceControlID(stack)
+		err = handler.ResourceControlService.UpdateResourceControl(resourceControl.ID, resourceControl)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the resource control changes inside the database", err}
+		}
+	}
+
 	return response.JSON(w, stack)
 }
 
@@ -120,7 +150,7 @@ func (handler *Handler) migrateComposeStack(r *http.Request, stack *portainer.St
 		return configErr
 	}
 
-	err := handler.deployComposeStack(config)
+	err := handler.deployComposeStack(config, nil)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
 	}
@@ -134,7 +164,7 @@ func (handler *Handler) migrateSwarmStack(r *http.Request, stack *portainer.Stac
 		return configErr
 	}
 
-	err := handler.deploySwarmStack(config)
+	err := handler.deploySwarmStack(config, nil)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
 	}
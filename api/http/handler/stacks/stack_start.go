@@ -0,0 +1,94 @@
+package stacks
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/response"
+)
+
+// POST request on /api/stacks/:id/start
+// The replicated services of a Swarm stack are scaled back to the replica counts saved when the stack
+// was stopped. The containers of a Compose stack are started.
+func (handler *Handler) stackStart(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stack, endpoint, release, handlerErr := handler.retrieveStackAndEndpoint(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer release()
+
+	if stack.Status != portainer.StackStatusInactive {
+		return &httperror.HandlerError{http.StatusBadRequest, "The stack is already running", portainer.ErrStackAlreadyActive}
+	}
+
+	cli, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Docker client", err}
+	}
+	defer cli.Close()
+
+	if stack.Type == portainer.DockerSwarmStack {
+		err = startSwarmStack(cli, stack)
+	} else {
+		err = startComposeStack(cli, stack)
+	}
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to start the stack", err}
+	}
+
+	stack.Status = portainer.StackStatusActive
+	stack.StoppedReplicas = nil
+	err = handler.StackService.UpdateStack(stack.ID, stack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	}
+
+	return response.JSON(w, stack)
+}
+
+// startSwarmStack restores the replica counts of the services of a Swarm stack. Services removed
+// since the stack was stopped are ignored.
+func startSwarmStack(cli *client.Client, stack *portainer.Stack) error {
+	services, err := swarmStackServices(cli, stack.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, service := range services {
+		replicas, ok := stack.StoppedReplicas[service.Spec.Name]
+		if !ok || service.Spec.Mode.Replicated == nil {
+			continue
+		}
+
+		err = scaleService(cli, service, replicas)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func startComposeStack(cli *client.Client, stack *portainer.Stack) error {
+	containers, err := composeProjectContainers(cli, stack.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, container := range containers {
+		if container.State == "running" {
+			continue
+		}
+
+		err = cli.ContainerStart(context.Background(), container.ID, types.ContainerStartOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
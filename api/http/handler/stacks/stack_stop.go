@@ -0,0 +1,207 @@
+package stacks
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/proxy"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+	"github.com/portainer/portainer/http/security"
+)
+
+// POST request on /api/stacks/:id/stop
+// The replicated services of a Swarm stack are scaled down to zero, their replica counts are saved
+// to be restored when the stack is started. Global services are left untouched.
+// The containers of a Compose stack are stopped, the containers already stopped are started again when
+// one of them cannot be stopped so that the stack is left running.
+func (handler *Handler) stackStop(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stack, endpoint, release, handlerErr := handler.retrieveStackAndEndpoint(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer release()
+
+	if stack.Status == portainer.StackStatusInactive {
+		return &httperror.HandlerError{http.StatusBadRequest, "The stack is already stopped", portainer.ErrStackAlreadyInactive}
+	}
+
+	// Stopping a container waits for its stop timeout, the request timeout of the default client is too short
+	cli, err := handler.DockerClientFactory.CreateStreamingClient(endpoint, "")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Docker client", err}
+	}
+	defer cli.Close()
+
+	if stack.Type == portainer.DockerSwarmStack {
+		err = handler.stopSwarmStack(cli, stack)
+	} else {
+		err = stopComposeStack(cli, stack)
+	}
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to stop the stack", err}
+	}
+
+	stack.Status = portainer.StackStatusInactive
+	err = handler.StackService.UpdateStack(stack.ID, stack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	}
+
+	return response.JSON(w, stack)
+}
+
+// retrieveStackAndEndpoint returns the stack identified by the id route variable and the endpoint it is
+// deployed on, after ensuring that the user can access the stack. The stack is locked once the operations
+// queued on it are completed, the returned function releases it.
+func (handler *Handler) retrieveStackAndEndpoint(r *http.Request) (*portainer.Stack, *portainer.Endpoint, func(), *httperror.HandlerError) {
+	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, nil, nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid stack identifier route variable", err}
+	}
+
+	stack, err := handler.StackService.Stack(portainer.StackID(stackID))
+	if err == portainer.ErrObjectNotFound {
+		return nil, nil, nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find a stack with the specified identifier inside the database", err}
+	} else if err != nil {
+		return nil, nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
+	}
+
+	resourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(proxy.StackResourceControlID(stack))
+	if err != nil && err != portainer.ErrObjectNotFound {
+		return nil, nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve a resource control associated to the stack", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return nil, nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	if resourceControl != nil {
+		if !securityContext.IsAdmin && !proxy.CanAccessStack(stack, resourceControl, securityContext.UserID, securityContext.UserMemberships) {
+			return nil, nil, nil, &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", portainer.ErrResourceAccessDenied}
+		}
+	}
+
+	release, handlerErr := handler.lockStack(stack)
+	if handlerErr != nil {
+		return nil, nil, nil, handlerErr
+	}
+
+	endpoint, err := handler.EndpointService.Endpoint(stack.EndpointID)
+	if err == portainer.ErrObjectNotFound {
+		release()
+		return nil, nil, nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find the endpoint associated to the stack inside the database", err}
+	} else if err != nil {
+		release()
+		return nil, nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find the endpoint associated to the stack inside the database", err}
+	}
+
+	return stack, endpoint, release, nil
+}
+
+// stopSwarmStack scales down the replicated services of a Swarm stack. The replica counts are persisted
+// before the services are scaled down so that a partially stopped stack can still be restored. Services
+// already scaled down to zero keep the replica count saved by a previous attempt.
+func (handler *Handler) stopSwarmStack(cli *client.Client, stack *portainer.Stack) error {
+	services, err := swarmStackServices(cli, stack.Name)
+	if err != nil {
+		return err
+	}
+
+	replicas := make(map[string]uint64)
+	for _, service := range services {
+		if service.Spec.Mode.Replicated == nil || service.Spec.Mode.Replicated.Replicas == nil {
+			continue
+		}
+
+		count := *service.Spec.Mode.Replicated.Replicas
+		if previousCount, ok := stack.StoppedReplicas[service.Spec.Name]; ok && count == 0 {
+			count = previousCount
+		}
+		replicas[service.Spec.Name] = count
+	}
+
+	stack.StoppedReplicas = replicas
+	err = handler.StackService.UpdateStack(stack.ID, stack)
+	if err != nil {
+		return err
+	}
+
+	for _, service := range services {
+		if _, ok := replicas[service.Spec.Name]; !ok {
+			continue
+		}
+
+		err = scaleService(cli, service, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stopComposeStack stops the running containers of a Compose stack. When a container cannot be stopped,
+// the containers stopped so far are started again.
+func stopComposeStack(cli *client.Client, stack *portainer.Stack) error {
+	containers, err := composeProjectContainers(cli, stack.Name)
+	if err != nil {
+		return err
+	}
+
+	stoppedContainers := make([]string, 0, len(containers))
+	for _, container := range containers {
+		if container.State != "running" {
+			continue
+		}
+
+		err = cli.ContainerStop(context.Background(), container.ID, nil)
+		if err != nil {
+			for _, containerID := range stoppedContainers {
+				startErr := cli.ContainerStart(context.Background(), containerID, types.ContainerStartOptions{})
+				if startErr != nil {
+					log.Printf("http error: Unable to restart container after a failed stack stop (container=%s) (err=%s)\n", containerID, startErr)
+				}
+			}
+			return err
+		}
+
+		stoppedContainers = append(stoppedContainers, container.ID)
+	}
+
+	return nil
+}
+
+func swarmStackServices(cli *client.Client, stackName string) ([]swarm.Service, error) {
+	filter := filters.NewArgs()
+	filter.Add("label", swarmStackNamespaceLabel+"="+stackName)
+
+	return cli.ServiceList(context.Background(), types.ServiceListOptions{Filters: filter})
+}
+
+func composeProjectContainers(cli *client.Client, stackName string) ([]types.Container, error) {
+	filter := filters.NewArgs()
+	filter.Add("label", composeProjectLabel+"="+composeProjectName(stackName))
+
+	return cli.ContainerList(context.Background(), types.ContainerListOptions{All: true, Filters: filter})
+}
+
+// scaleService updates the replica count of a replicated service. The registry authentication
+// of the current service specification is kept.
+func scaleService(cli *client.Client, service swarm.Service, replicas uint64) error {
+	spec := service.Spec
+	mode := *spec.Mode.Replicated
+	mode.Replicas = &replicas
+	spec.Mode.Replicated = &mode
+
+	_, err := cli.ServiceUpdate(context.Background(), service.ID, service.Version, spec, types.ServiceUpdateOptions{})
+	return err
+}
@@ -1,6 +1,7 @@
 package stacks
 
 import (
+	"io"
 	"net/http"
 	"strconv"
 
@@ -16,6 +17,8 @@ import (
 type updateComposeStackPayload struct {
 	StackFileContent string
 	Env              []portainer.Pair
+	EnvironmentSets  []portainer.EnvironmentSetID
+	AdditionalFiles  []stackFilePayload
 }
 
 func (payload *updateComposeStackPayload) Validate(r *http.Request) error {
@@ -28,6 +31,8 @@ func (payload *updateComposeStackPayload) Validate(r *http.Request) error {
 type updateSwarmStackPayload struct {
 	StackFileContent string
 	Env              []portainer.Pair
+	EnvironmentSets  []portainer.EnvironmentSetID
+	AdditionalFiles  []stackFilePayload
 	Prune            bool
 }
 
@@ -38,7 +43,11 @@ func (payload *updateSwarmStackPayload) Validate(r *http.Request) error {
 	return nil
 }
 
-// PUT request on /api/stacks/:id?endpointId=<endpointId>
+// PUT request on /api/stacks/:id?endpointId=<endpointId>&async=<async>&convergenceTimeout=<timeout>&rollback=<rollback>
+// If the async query parameter is set to true, the stack is redeployed in the background and a stack job is returned.
+// If the convergenceTimeout query parameter is set, the services of the stack are expected to reach their desired state
+// within this number of seconds. If the rollback query parameter is also set to true, the previous stack files are
+// redeployed when they do not.
 func (handler *Handler) stackUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
 	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
 	if err != nil {
@@ -52,7 +61,7 @@ func (handler *Handler) stackUpdate(w http.ResponseWriter, r *http.Request) *htt
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
 	}
 
-	resourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(stack.Name)
+	resourceControl, err := handler.ResourceControlService.ResourceControlByResourceID(proxy.StackResourceControlID(stack))
 	if err != nil && err != portainer.ErrObjectNotFound {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve a resource control associated to the stack", err}
 	}
@@ -86,78 +95,206 @@ func (handler *Handler) stackUpdate(w http.ResponseWriter, r *http.Request) *htt
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find the endpoint associated to the stack inside the database", err}
 	}
 
-	updateError := handler.updateAndDeployStack(r, stack, endpoint)
+	options, err := retrieveConvergenceOptions(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: convergenceTimeout", err}
+	}
+
+	parameters, updateError := handler.retrieveUpdateParameters(r, stack, endpoint)
 	if updateError != nil {
 		return updateError
 	}
 
-	err = handler.StackService.UpdateStack(stack.ID, stack)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	execution := handler.updateExecution(stack, endpoint, parameters, securityContext, options)
+
+	if isAsyncRequest(r) {
+		return handler.startStackJob(w, r, portainer.StackJobUpdate, execution, nil)
 	}
 
-	return response.JSON(w, stack)
+	convergence, err := handler.executeInQueue(execution)
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a stack with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+	}
+
+	return response.JSON(w, &stackDeploymentResponse{Stack: stack, Convergence: convergence})
 }
 
-func (handler *Handler) updateAndDeployStack(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) *httperror.HandlerError {
+// stackUpdateParameters represents the changes applied to a stack by an update request.
+type stackUpdateParameters struct {
+	stackFileContent string
+	env              []portainer.Pair
+	environmentSets  []portainer.EnvironmentSetID
+	additionalFiles  []stackFilePayload
+	prune            bool
+}
+
+// retrieveUpdateParameters decodes and validates the payload of an update request.
+func (handler *Handler) retrieveUpdateParameters(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) (*stackUpdateParameters, *httperror.HandlerError) {
+	var parameters *stackUpdateParameters
 	if stack.Type == portainer.DockerSwarmStack {
-		return handler.updateSwarmStack(r, stack, endpoint)
+		var payload updateSwarmStackPayload
+		err := request.DecodeAndValidateJSONPayload(r, &payload)
+		if err != nil {
+			return nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+		}
+
+		parameters = &stackUpdateParameters{
+			stackFileContent: payload.StackFileContent,
+			env:              payload.Env,
+			environmentSets:  payload.EnvironmentSets,
+			additionalFiles:  payload.AdditionalFiles,
+			prune:            payload.Prune,
+		}
+	} else {
+		var payload updateComposeStackPayload
+		err := request.DecodeAndValidateJSONPayload(r, &payload)
+		if err != nil {
+			return nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+		}
+
+		parameters = &stackUpdateParameters{
+			stackFileContent: payload.StackFileContent,
+			env:              payload.Env,
+			environmentSets:  payload.EnvironmentSets,
+			additionalFiles:  payload.AdditionalFiles,
+		}
 	}
-	return handler.updateComposeStack(r, stack, endpoint)
-}
 
-func (handler *Handler) updateComposeStack(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) *httperror.HandlerError {
-	var payload updateComposeStackPayload
-	err := request.DecodeAndValidateJSONPayload(r, &payload)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	if parameters.environmentSets != nil {
+		validationError := handler.validateEnvironmentSets(r, parameters.environmentSets, stack.EnvironmentSets, endpoint)
+		if validationError != nil {
+			return nil, validationError
+		}
 	}
 
-	stack.Env = payload.Env
+	if parameters.additionalFiles != nil {
+		err := validateAdditionalFiles(parameters.additionalFiles, stack.EntryPoint)
+		if err != nil {
+			return nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+		}
+	}
 
-	stackFolder := strconv.Itoa(int(stack.ID))
-	_, err = handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist updated Compose file on disk", err}
+	return parameters, nil
+}
+
+// updateExecution returns the execution updating the files of a stack and redeploying it. The stack is reloaded
+// and its files are only updated once the operations queued before on the stack are completed, so that the
+// files deployed by these operations are not replaced while they are in progress. The updated stack is persisted
+// once it is deployed. When a rollback is requested, the stack files are saved before they are updated.
+func (handler *Handler) updateExecution(stack *portainer.Stack, endpoint *portainer.Endpoint, parameters *stackUpdateParameters, securityContext *security.RestrictedRequestContext, options *convergenceOptions) *stackExecution {
+	var deploy stackOperation
+	var snapshot *stackFilesSnapshot
+
+	execution := &stackExecution{
+		stack:    stack,
+		endpoint: endpoint,
+		options:  options,
 	}
 
-	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
-	if configErr != nil {
-		return configErr
+	execution.operation = func(output io.Writer) error {
+		err := handler.reloadStack(stack)
+		if err != nil {
+			return err
+		}
+		stack.EndpointID = endpoint.ID
+
+		if options != nil && options.rollback {
+			snapshot, err = handler.snapshotStackFiles(stack)
+			if err != nil {
+				return err
+			}
+		}
+
+		droppedFiles, err := handler.updateStackFiles(stack, parameters)
+		if err != nil {
+			return err
+		}
+
+		// Redeploying a stopped stack starts its services again
+		stack.Status = portainer.StackStatusActive
+		stack.StoppedReplicas = nil
+
+		deploy, err = handler.redeployOperation(stack, endpoint, securityContext, parameters.prune)
+		if err != nil {
+			return err
+		}
+
+		err = deploy(output)
+		if err != nil {
+			return err
+		}
+
+		handler.removeUnusedAdditionalFiles(stack, droppedFiles)
+		return handler.StackService.UpdateStack(stack.ID, stack)
 	}
 
-	err = handler.deployComposeStack(config)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+	if options != nil && options.rollback {
+		execution.rollback = func(output io.Writer) error {
+			err := handler.restoreStackFiles(stack, snapshot)
+			if err != nil {
+				return err
+			}
+
+			err = deploy(output)
+			if err != nil {
+				return err
+			}
+
+			return handler.StackService.UpdateStack(stack.ID, stack)
+		}
 	}
 
-	return nil
+	return execution
 }
 
-func (handler *Handler) updateSwarmStack(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) *httperror.HandlerError {
-	var payload updateSwarmStackPayload
-	err := request.DecodeAndValidateJSONPayload(r, &payload)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+// updateStackFiles stores the updated stack files on disk and applies the environment of the update to the stack.
+// It returns the additional files that are no longer associated to the stack.
+func (handler *Handler) updateStackFiles(stack *portainer.Stack, parameters *stackUpdateParameters) ([]string, error) {
+	stack.Env = parameters.env
+	if parameters.environmentSets != nil {
+		stack.EnvironmentSets = parameters.environmentSets
 	}
 
-	stack.Env = payload.Env
-
 	stackFolder := strconv.Itoa(int(stack.ID))
-	_, err = handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
+	_, err := handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(parameters.stackFileContent))
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist updated Compose file on disk", err}
+		return nil, err
 	}
 
-	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, payload.Prune)
-	if configErr != nil {
-		return configErr
+	return handler.updateAdditionalFiles(stack, stackFolder, parameters.additionalFiles)
+}
+
+// redeployOperation returns the operation deploying a stack using the registries accessible to the user.
+func (handler *Handler) redeployOperation(stack *portainer.Stack, endpoint *portainer.Endpoint, securityContext *security.RestrictedRequestContext, prune bool) (stackOperation, error) {
+	dockerhub, registries, handlerErr := handler.deploymentRegistries(securityContext)
+	if handlerErr != nil {
+		return nil, handlerErr.Err
 	}
 
-	err = handler.deploySwarmStack(config)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+	if stack.Type == portainer.DockerSwarmStack {
+		config := &swarmStackDeploymentConfig{
+			stack:      stack,
+			endpoint:   endpoint,
+			dockerhub:  dockerhub,
+			registries: registries,
+			prune:      prune,
+		}
+
+		return func(output io.Writer) error {
+			return handler.deploySwarmStack(config, output)
+		}, nil
 	}
 
-	return nil
+	config := &composeStackDeploymentConfig{
+		stack:      stack,
+		endpoint:   endpoint,
+		dockerhub:  dockerhub,
+		registries: registries,
+	}
+
+	return func(output io.Writer) error {
+		return handler.deployComposeStack(config, output)
+	}, nil
 }
@@ -5,30 +5,56 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/docker"
 	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/handler/stacks"
 	"github.com/portainer/portainer/http/security"
 )
 
 // Handler represents an HTTP API handler for managing templates.
 type Handler struct {
+	requestBouncer *security.RequestBouncer
 	*mux.Router
-	TemplateService portainer.TemplateService
+	TemplateService        portainer.TemplateService
+	TemplateSourceService  portainer.TemplateSourceService
+	SettingsService        portainer.SettingsService
+	EndpointService        portainer.EndpointService
+	ResourceControlService portainer.ResourceControlService
+	RegistryService        portainer.RegistryService
+	DockerHubService       portainer.DockerHubService
+	CredentialsService     portainer.RegistryCredentialsService
+	DockerClientFactory    *docker.ClientFactory
+	StackHandler           *stacks.Handler
 }
 
 // NewHandler returns a new instance of Handler.
 func NewHandler(bouncer *security.RequestBouncer) *Handler {
 	h := &Handler{
-		Router: mux.NewRouter(),
+		Router:         mux.NewRouter(),
+		requestBouncer: bouncer,
 	}
 	h.Handle("/templates",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.templateList))).Methods(http.MethodGet)
 	h.Handle("/templates",
 		bouncer.AdministratorAccess(httperror.LoggerHandler(h.templateCreate))).Methods(http.MethodPost)
+	h.Handle("/templates/refresh",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.templateRefresh))).Methods(http.MethodPost)
 	h.Handle("/templates/{id}",
-		bouncer.AdministratorAccess(httperror.LoggerHandler(h.templateInspect))).Methods(http.MethodGet)
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.templateInspect))).Methods(http.MethodGet)
 	h.Handle("/templates/{id}",
 		bouncer.AdministratorAccess(httperror.LoggerHandler(h.templateUpdate))).Methods(http.MethodPut)
 	h.Handle("/templates/{id}",
 		bouncer.AdministratorAccess(httperror.LoggerHandler(h.templateDelete))).Methods(http.MethodDelete)
+	h.Handle("/templates/{id}/access",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.templateUpdateAccess))).Methods(http.MethodPut)
+	h.Handle("/templates/{id}/deploy",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.templateDeploy))).Methods(http.MethodPost)
+	h.Handle("/templates/{id}/validate",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.templateValidate))).Methods(http.MethodPost)
 	return h
 }
+
+// authorizedTemplateAccess returns true when the user associated to the security context can use the template.
+func authorizedTemplateAccess(template *portainer.Template, context *security.RestrictedRequestContext) bool {
+	return len(security.FilterTemplates([]portainer.Template{*template}, context)) == 1
+}
@@ -9,6 +9,7 @@ import (
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/request"
 	"github.com/portainer/portainer/http/response"
+	"github.com/portainer/portainer/templates"
 )
 
 type templateCreatePayload struct {
@@ -25,6 +26,7 @@ type templateCreatePayload struct {
 	Platform   string
 	Categories []string
 	Env        []portainer.TemplateEnv
+	Inputs     []portainer.TemplateInput
 
 	// Mandatory container
 	Image string
@@ -93,6 +95,12 @@ func (handler *Handler) templateCreate(w http.ResponseWriter, r *http.Request) *
 		Platform:          payload.Platform,
 		Categories:        payload.Categories,
 		Env:               payload.Env,
+		Inputs:            payload.Inputs,
+	}
+
+	err = templates.NormalizeTemplate(template)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid template inputs", err}
 	}
 
 	if template.Type == portainer.ContainerTemplate {
@@ -0,0 +1,196 @@
+package templates
+
+import (
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/docker/docker/api/types"
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/security"
+	"github.com/portainer/portainer/templates"
+)
+
+type templateDeployPayload struct {
+	EndpointID int
+	Name       string
+	SwarmID    string
+	NodeName   string
+	Inputs     map[string]string
+	// Environment sets of the stack deployed from a stack template
+	EnvironmentSets []portainer.EnvironmentSetID
+
+	// Access control, the deployed resources are only accessible by the current user
+	// when neither Public, AdministratorsOnly, Users or Teams are specified.
+	Public             bool
+	AdministratorsOnly bool
+	Users              []int
+	Teams              []int
+}
+
+func (payload *templateDeployPayload) Validate(r *http.Request) error {
+	if payload.EndpointID == 0 {
+		return portainer.Error("Invalid endpoint identifier. Must be a positive number")
+	}
+	if payload.Public && (payload.AdministratorsOnly || len(payload.Users) > 0 || len(payload.Teams) > 0) {
+		return portainer.Error("Invalid access control declaration. Public cannot be used with AdministratorsOnly, Users or Teams")
+	}
+	return nil
+}
+
+// templateDeployResponse represents the response of a container template deployment. The response of a
+// stack template deployment is the one of the stack creation requests.
+type templateDeployResponse struct {
+	Container       *types.ContainerJSON       `json:",omitempty"`
+	ResourceControl *portainer.ResourceControl `json:",omitempty"`
+}
+
+// POST request on /api/templates/:id/deploy?async=<async>&convergenceTimeout=<timeout>
+// Deploys a container template (image pull, container creation and start) or a stack template
+// (stack creation from the template git repository) on the specified endpoint.
+// The query parameters are only used by stack templates, they are handled as in the stack creation requests.
+func (handler *Handler) templateDeploy(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	templateID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid template identifier route variable", err}
+	}
+
+	var payload templateDeployPayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	template, err := handler.TemplateService.Template(portainer.TemplateID(templateID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a template with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a template with the specified identifier inside the database", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	if !authorizedTemplateAccess(template, securityContext) {
+		return &httperror.HandlerError{http.StatusForbidden, "Access denied to template", portainer.ErrResourceAccessDenied}
+	}
+
+	endpoint, err := handler.EndpointService.Endpoint(portainer.EndpointID(payload.EndpointID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.EndpointAccess(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", portainer.ErrEndpointAccessDenied}
+	}
+
+	if !security.AuthorizedTemplateEndpoint(template, endpoint) {
+		return &httperror.HandlerError{http.StatusForbidden, "The template is not available on this endpoint", portainer.ErrResourceAccessDenied}
+	}
+
+	env, err := templates.ResolveInputs(template, payload.Inputs)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid template inputs", err}
+	}
+
+	resourceControl := createResourceControl(&payload, securityContext)
+	if resourceControl != nil && !security.AuthorizedResourceControlCreation(resourceControl, securityContext) {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to create a resource control for the deployed resources", portainer.ErrResourceAccessDenied}
+	}
+
+	deployment := &templateDeployment{
+		template:        template,
+		payload:         &payload,
+		endpoint:        endpoint,
+		env:             env,
+		securityContext: securityContext,
+		resourceControl: resourceControl,
+	}
+
+	switch template.Type {
+	case portainer.ContainerTemplate:
+		return handler.deployContainerTemplate(w, deployment)
+	case portainer.SwarmStackTemplate, portainer.ComposeStackTemplate:
+		if govalidator.IsNull(payload.Name) {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", portainer.Error("Invalid stack name")}
+		}
+		if template.Type == portainer.SwarmStackTemplate && govalidator.IsNull(payload.SwarmID) {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", portainer.Error("Invalid Swarm ID")}
+		}
+		return handler.deployStackTemplate(w, r, deployment)
+	}
+
+	return &httperror.HandlerError{http.StatusBadRequest, "Unsupported template type", portainer.Error("Unsupported template type")}
+}
+
+type templateDeployment struct {
+	template        *portainer.Template
+	payload         *templateDeployPayload
+	endpoint        *portainer.Endpoint
+	env             []portainer.Pair
+	securityContext *security.RestrictedRequestContext
+	resourceControl *portainer.ResourceControl
+}
+
+// createResourceControl returns the resource control that will be associated to the deployed resources
+// or nil when the resources must be public.
+func createResourceControl(payload *templateDeployPayload, securityContext *security.RestrictedRequestContext) *portainer.ResourceControl {
+	if payload.Public {
+		return nil
+	}
+
+	userAccesses := make([]portainer.UserResourceAccess, 0)
+	for _, id := range payload.Users {
+		userAccesses = append(userAccesses, portainer.UserResourceAccess{
+			UserID:      portainer.UserID(id),
+			AccessLevel: portainer.ReadWriteAccessLevel,
+		})
+	}
+
+	teamAccesses := make([]portainer.TeamResourceAccess, 0)
+	for _, id := range payload.Teams {
+		teamAccesses = append(teamAccesses, portainer.TeamResourceAccess{
+			TeamID:      portainer.TeamID(id),
+			AccessLevel: portainer.ReadWriteAccessLevel,
+		})
+	}
+
+	if !payload.AdministratorsOnly && len(userAccesses) == 0 && len(teamAccesses) == 0 {
+		userAccesses = append(userAccesses, portainer.UserResourceAccess{
+			UserID:      securityContext.UserID,
+			AccessLevel: portainer.ReadWriteAccessLevel,
+		})
+	}
+
+	return &portainer.ResourceControl{
+		AdministratorsOnly: payload.AdministratorsOnly,
+		UserAccesses:       userAccesses,
+		TeamAccesses:       teamAccesses,
+		SubResourceIDs:     []string{},
+	}
+}
+
+func (handler *Handler) applyResourceControl(resourceControl *portainer.ResourceControl, resourceType portainer.ResourceControlType, resourceID string, subResourceIDs []string) (*portainer.ResourceControl, error) {
+	if resourceControl == nil {
+		return nil, nil
+	}
+
+	resourceControl.Type = resourceType
+	resourceControl.ResourceID = resourceID
+	if subResourceIDs != nil {
+		resourceControl.SubResourceIDs = subResourceIDs
+	}
+
+	err := handler.ResourceControlService.CreateResourceControl(resourceControl)
+	if err != nil {
+		return nil, err
+	}
+
+	return resourceControl, nil
+}
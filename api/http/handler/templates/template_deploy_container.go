@@ -0,0 +1,266 @@
+package templates
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/response"
+	"github.com/portainer/portainer/http/security"
+	"github.com/portainer/portainer/registry"
+)
+
+type imagePullMessage struct {
+	Error string `json:"error"`
+}
+
+type registryAuthenticationHeader struct {
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	Serveraddress string `json:"serveraddress"`
+}
+
+func (handler *Handler) deployContainerTemplate(w http.ResponseWriter, deployment *templateDeployment) *httperror.HandlerError {
+	template := deployment.template
+
+	settings, err := handler.SettingsService.Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the settings from the database", err}
+	}
+
+	if !deployment.securityContext.IsAdmin {
+		if !settings.AllowPrivilegedModeForRegularUsers && template.Privileged {
+			return &httperror.HandlerError{http.StatusForbidden, "Privileged mode is not allowed for regular users", portainer.ErrResourceAccessDenied}
+		}
+
+		for _, volume := range template.Volumes {
+			if volume.Bind != "" && !settings.AllowBindMountsForRegularUsers {
+				return &httperror.HandlerError{http.StatusForbidden, "Bind mounts are not allowed for regular users", portainer.ErrResourceAccessDenied}
+			}
+		}
+	}
+
+	config, hostConfig, err := createContainerConfiguration(template, deployment.env)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid container template configuration", err}
+	}
+
+	cli, err := handler.DockerClientFactory.CreateStreamingClient(deployment.endpoint, deployment.payload.NodeName)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create a Docker client", err}
+	}
+	defer cli.Close()
+
+	registryAuth, err := handler.registryAuthentication(config.Image, deployment.securityContext)
+	if err == portainer.ErrRegistryAccessDenied {
+		return &httperror.HandlerError{http.StatusForbidden, "Access denied to the registry hosting the template image", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the registry credentials", err}
+	}
+
+	err = pullImage(cli, config.Image, registryAuth)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to pull the template image", err}
+	}
+
+	createResponse, err := cli.ContainerCreate(context.Background(), config, hostConfig, nil, deployment.payload.Name)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create the container", err}
+	}
+
+	err = cli.ContainerStart(context.Background(), createResponse.ID, types.ContainerStartOptions{})
+	if err != nil {
+		removeContainer(cli, createResponse.ID)
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to start the container", err}
+	}
+
+	containerInspect, err := cli.ContainerInspect(context.Background(), createResponse.ID)
+	if err != nil {
+		removeContainer(cli, createResponse.ID)
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to inspect the container", err}
+	}
+
+	volumeIDs := make([]string, 0)
+	for _, mountPoint := range containerInspect.Mounts {
+		if mountPoint.Type == mount.TypeVolume && mountPoint.Name != "" {
+			volumeIDs = append(volumeIDs, mountPoint.Name)
+		}
+	}
+
+	resourceControl, err := handler.applyResourceControl(deployment.resourceControl, portainer.ContainerResourceControl, containerInspect.ID, volumeIDs)
+	if err != nil {
+		removeContainer(cli, createResponse.ID)
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the resource control inside the database", err}
+	}
+
+	return response.JSON(w, &templateDeployResponse{Container: &containerInspect, ResourceControl: resourceControl})
+}
+
+// createContainerConfiguration translates a container template into a container configuration.
+// Volumes without bind are created as anonymous volumes.
+func createContainerConfiguration(template *portainer.Template, env []portainer.Pair) (*container.Config, *container.HostConfig, error) {
+	image := template.Image
+	if template.Registry != "" {
+		image = strings.TrimSuffix(template.Registry, "/") + "/" + image
+	}
+
+	exposedPorts, portBindings, err := nat.ParsePortSpecs(template.Ports)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	labels := make(map[string]string)
+	for _, label := range template.Labels {
+		labels[label.Name] = label.Value
+	}
+
+	containerEnv := make([]string, 0, len(env))
+	for _, envvar := range env {
+		containerEnv = append(containerEnv, envvar.Name+"="+envvar.Value)
+	}
+
+	config := &container.Config{
+		Image:        image,
+		Env:          containerEnv,
+		Labels:       labels,
+		ExposedPorts: exposedPorts,
+		Hostname:     template.Hostname,
+		OpenStdin:    template.Interactive,
+		Tty:          template.Interactive,
+	}
+
+	if template.Command != "" {
+		config.Cmd = strings.Fields(template.Command)
+	}
+
+	hostConfig := &container.HostConfig{
+		PortBindings:  portBindings,
+		Privileged:    template.Privileged,
+		RestartPolicy: container.RestartPolicy{Name: template.RestartPolicy},
+		NetworkMode:   container.NetworkMode(template.Network),
+		Binds:         []string{},
+		Mounts:        []mount.Mount{},
+	}
+
+	for _, volume := range template.Volumes {
+		if volume.Bind != "" {
+			bind := volume.Bind + ":" + volume.Container
+			if volume.ReadOnly {
+				bind += ":ro"
+			}
+			hostConfig.Binds = append(hostConfig.Binds, bind)
+			continue
+		}
+
+		hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+			Type:     mount.TypeVolume,
+			Target:   volume.Container,
+			ReadOnly: volume.ReadOnly,
+		})
+	}
+
+	return config, hostConfig, nil
+}
+
+// registryAuthentication returns the encoded credentials of the registry hosting the image.
+// Registries that are not accessible to the user are ignored, ErrRegistryAccessDenied is returned when the
+// image is only hosted on such registries. Docker Hub images use the Docker Hub credentials.
+func (handler *Handler) registryAuthentication(image string, securityContext *security.RestrictedRequestContext) (string, error) {
+	domain, err := registry.ImageDomain(image)
+	if err != nil {
+		return "", err
+	}
+
+	registries, err := handler.RegistryService.Registries()
+	if err != nil {
+		return "", err
+	}
+
+	header := &registryAuthenticationHeader{
+		Serveraddress: domain,
+	}
+
+	matchingRegistry, err := registry.MatchImageRegistry(image, security.FilterRegistries(registries, securityContext))
+	if err != nil {
+		return "", err
+	}
+
+	if matchingRegistry != nil {
+		credentials, err := handler.CredentialsService.Credentials(matchingRegistry)
+		if err != nil {
+			return "", err
+		}
+		header.Username = credentials.Username
+		header.Password = credentials.Password
+	} else {
+		restrictedRegistry, err := registry.MatchImageRegistry(image, registries)
+		if err != nil {
+			return "", err
+		}
+
+		if restrictedRegistry != nil {
+			return "", portainer.ErrRegistryAccessDenied
+		}
+
+		dockerhub, err := handler.DockerHubService.DockerHub()
+		if err != nil {
+			return "", err
+		}
+
+		if registry.IsDockerHubImage(image) && dockerhub.Authentication {
+			header.Username = dockerhub.Username
+			header.Password = dockerhub.Password
+		}
+	}
+
+	headerData, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.URLEncoding.EncodeToString(headerData), nil
+}
+
+// pullImage pulls the image and waits for the pull to complete. Errors reported
+// by the Docker engine while pulling are returned.
+func pullImage(cli *client.Client, image, registryAuth string) error {
+	reader, err := cli.ImagePull(context.Background(), image, types.ImagePullOptions{RegistryAuth: registryAuth})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	decoder := json.NewDecoder(reader)
+	for {
+		var message imagePullMessage
+		err := decoder.Decode(&message)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if message.Error != "" {
+			return errors.New(message.Error)
+		}
+	}
+}
+
+func removeContainer(cli *client.Client, containerID string) {
+	err := cli.ContainerRemove(context.Background(), containerID, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
+	if err != nil {
+		log.Printf("http error: Unable to remove container after a failed template deployment (container=%s) (err=%s)\n", containerID, err)
+	}
+}
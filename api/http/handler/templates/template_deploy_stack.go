@@ -0,0 +1,38 @@
+package templates
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/filesystem"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/handler/stacks"
+)
+
+// deployStackTemplate creates a stack from the git repository of the template. The stack is created and
+// deployed by the stacks handler, see stacks.Handler.CreateTemplateStack.
+func (handler *Handler) deployStackTemplate(w http.ResponseWriter, r *http.Request, deployment *templateDeployment) *httperror.HandlerError {
+	template := deployment.template
+
+	entryPoint := template.Repository.StackFile
+	if entryPoint == "" {
+		entryPoint = filesystem.ComposeFileDefaultName
+	}
+
+	payload := &stacks.TemplateStackPayload{
+		Name:            deployment.payload.Name,
+		Type:            portainer.DockerComposeStack,
+		RepositoryURL:   template.Repository.URL,
+		EntryPoint:      entryPoint,
+		Env:             deployment.env,
+		EnvironmentSets: deployment.payload.EnvironmentSets,
+		ResourceControl: deployment.resourceControl,
+	}
+
+	if template.Type == portainer.SwarmStackTemplate {
+		payload.Type = portainer.DockerSwarmStack
+		payload.SwarmID = deployment.payload.SwarmID
+	}
+
+	return handler.StackHandler.CreateTemplateStack(w, r, deployment.endpoint, payload)
+}
@@ -7,6 +7,7 @@ import (
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/request"
 	"github.com/portainer/portainer/http/response"
+	"github.com/portainer/portainer/http/security"
 )
 
 // GET request on /api/templates/:id
@@ -23,5 +24,14 @@ func (handler *Handler) templateInspect(w http.ResponseWriter, r *http.Request)
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a template with the specified identifier inside the database", err}
 	}
 
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	if !authorizedTemplateAccess(template, securityContext) {
+		return &httperror.HandlerError{http.StatusForbidden, "Access denied to template", portainer.ErrResourceAccessDenied}
+	}
+
 	return response.JSON(w, template)
 }
@@ -3,13 +3,21 @@ package templates
 import (
 	"net/http"
 
+	"github.com/portainer/portainer"
 	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
 	"github.com/portainer/portainer/http/response"
 	"github.com/portainer/portainer/http/security"
 )
 
-// GET request on /api/templates
+// GET request on /api/templates?endpointId=<endpointId>
+// If the endpointId query parameter is specified, only the templates available on this endpoint are returned.
 func (handler *Handler) templateList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericQueryParameter(r, "endpointId", true)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: endpointId", err}
+	}
+
 	templates, err := handler.TemplateService.Templates()
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve templates from the database", err}
@@ -22,5 +30,16 @@ func (handler *Handler) templateList(w http.ResponseWriter, r *http.Request) *ht
 
 	filteredTemplates := security.FilterTemplates(templates, securityContext)
 
+	if endpointID != 0 {
+		endpoint, err := handler.EndpointService.Endpoint(portainer.EndpointID(endpointID))
+		if err == portainer.ErrObjectNotFound {
+			return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+		}
+
+		filteredTemplates = security.FilterTemplatesByEndpoint(filteredTemplates, endpoint)
+	}
+
 	return response.JSON(w, filteredTemplates)
 }
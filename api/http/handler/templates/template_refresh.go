@@ -0,0 +1,41 @@
+package templates
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// POST request on /api/templates/refresh?sourceId=<sourceId>
+// If the sourceId query parameter is not specified, all the template sources are refreshed.
+func (handler *Handler) templateRefresh(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	sourceID, err := request.RetrieveNumericQueryParameter(r, "sourceId", true)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: sourceId", err}
+	}
+
+	if sourceID != 0 {
+		err = handler.TemplateSourceService.RefreshTemplateSource(portainer.TemplateSourceID(sourceID))
+	} else {
+		err = handler.TemplateSourceService.RefreshTemplateSources()
+	}
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a template source with the specified identifier inside the settings", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to refresh the template sources", err}
+	}
+
+	settings, err := handler.SettingsService.Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the settings from the database", err}
+	}
+
+	for idx := range settings.TemplateSources {
+		settings.TemplateSources[idx].RepositoryPassword = ""
+	}
+
+	return response.JSON(w, settings.TemplateSources)
+}
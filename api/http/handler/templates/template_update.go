@@ -7,6 +7,7 @@ import (
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/request"
 	"github.com/portainer/portainer/http/response"
+	"github.com/portainer/portainer/templates"
 )
 
 type templateUpdatePayload struct {
@@ -19,6 +20,7 @@ type templateUpdatePayload struct {
 	Platform          *string
 	Categories        []string
 	Env               []portainer.TemplateEnv
+	Inputs            []portainer.TemplateInput
 	Image             *string
 	Registry          *string
 	Repository        portainer.TemplateRepository
@@ -59,6 +61,11 @@ func (handler *Handler) templateUpdate(w http.ResponseWriter, r *http.Request) *
 
 	updateTemplate(template, &payload)
 
+	err = templates.NormalizeTemplate(template)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid template inputs", err}
+	}
+
 	err = handler.TemplateService.UpdateTemplate(template.ID, template)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusNotFound, "Unable to persist template changes inside the database", err}
@@ -148,8 +155,11 @@ func updateTemplate(template *portainer.Template, payload *templateUpdatePayload
 		template.Categories = payload.Categories
 	}
 
-	if payload.Env != nil {
+	if payload.Inputs != nil {
+		template.Inputs = payload.Inputs
+	} else if payload.Env != nil {
 		template.Env = payload.Env
+		template.Inputs = nil
 	}
 
 	if payload.AdministratorOnly != nil {
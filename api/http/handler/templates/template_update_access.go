@@ -0,0 +1,72 @@
+package templates
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+type templateUpdateAccessPayload struct {
+	AuthorizedUsers []int
+	AuthorizedTeams []int
+	EndpointGroups  []int
+}
+
+func (payload *templateUpdateAccessPayload) Validate(r *http.Request) error {
+	return nil
+}
+
+// PUT request on /api/templates/:id/access
+func (handler *Handler) templateUpdateAccess(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	templateID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid template identifier route variable", err}
+	}
+
+	var payload templateUpdateAccessPayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	template, err := handler.TemplateService.Template(portainer.TemplateID(templateID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a template with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a template with the specified identifier inside the database", err}
+	}
+
+	if payload.AuthorizedUsers != nil {
+		authorizedUserIDs := []portainer.UserID{}
+		for _, value := range payload.AuthorizedUsers {
+			authorizedUserIDs = append(authorizedUserIDs, portainer.UserID(value))
+		}
+		template.AuthorizedUsers = authorizedUserIDs
+	}
+
+	if payload.AuthorizedTeams != nil {
+		authorizedTeamIDs := []portainer.TeamID{}
+		for _, value := range payload.AuthorizedTeams {
+			authorizedTeamIDs = append(authorizedTeamIDs, portainer.TeamID(value))
+		}
+		template.AuthorizedTeams = authorizedTeamIDs
+	}
+
+	if payload.EndpointGroups != nil {
+		endpointGroupIDs := []portainer.EndpointGroupID{}
+		for _, value := range payload.EndpointGroups {
+			endpointGroupIDs = append(endpointGroupIDs, portainer.EndpointGroupID(value))
+		}
+		template.EndpointGroups = endpointGroupIDs
+	}
+
+	err = handler.TemplateService.UpdateTemplate(template.ID, template)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist template changes inside the database", err}
+	}
+
+	return response.JSON(w, template)
+}
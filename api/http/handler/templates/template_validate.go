@@ -0,0 +1,58 @@
+package templates
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+	"github.com/portainer/portainer/http/security"
+	"github.com/portainer/portainer/templates"
+)
+
+type templateValidatePayload struct {
+	Inputs map[string]string
+}
+
+func (payload *templateValidatePayload) Validate(r *http.Request) error {
+	return nil
+}
+
+// POST request on /api/templates/:id/validate
+// Validates the values of the template inputs and returns the resulting environment variables.
+func (handler *Handler) templateValidate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	templateID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid template identifier route variable", err}
+	}
+
+	var payload templateValidatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	template, err := handler.TemplateService.Template(portainer.TemplateID(templateID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a template with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a template with the specified identifier inside the database", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	if !authorizedTemplateAccess(template, securityContext) {
+		return &httperror.HandlerError{http.StatusForbidden, "Access denied to template", portainer.ErrResourceAccessDenied}
+	}
+
+	env, err := templates.ResolveInputs(template, payload.Inputs)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid template inputs", err}
+	}
+
+	return response.JSON(w, env)
+}
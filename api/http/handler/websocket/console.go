@@ -0,0 +1,252 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/http/security"
+)
+
+const (
+	errInvalidTerminalSize       = portainer.Error("Invalid terminal size")
+	errUnsupportedControlMessage = portainer.Error("Unsupported control message")
+)
+
+const (
+	controlMessageResize = "resize"
+	closeMessageTimeout  = time.Second
+)
+
+type (
+	// consoleSession represents an interactive session (exec or attach) forwarded over a websocket connection.
+	// Messages sent by the client are forwarded to the process as input, except the text messages containing
+	// a control message such as {"type":"resize","width":120,"height":40}.
+	consoleSession struct {
+		info          portainer.ConsoleSession
+		tty           bool
+		recorder      *sessionRecorder
+		recording     *portainer.SessionRecording
+		resize        func(width, height uint) error
+		startTime     time.Time
+		lastActivity  time.Time
+		idleTimeout   time.Duration
+		idleTimer     *time.Timer
+		websocketConn *websocket.Conn
+		terminated    error
+		mutex         sync.Mutex
+	}
+
+	controlMessage struct {
+		Type   string `json:"type"`
+		Width  uint   `json:"width"`
+		Height uint   `json:"height"`
+	}
+)
+
+func newConsoleSession(info portainer.ConsoleSession) *consoleSession {
+	now := time.Now()
+	info.StartTime = now.Unix()
+	info.LastActivity = now.Unix()
+
+	return &consoleSession{
+		info:         info,
+		startTime:    now,
+		lastActivity: now,
+	}
+}
+
+// openConsoleSession registers a new console session for the user performing the request and starts
+// the recording of the session when session recording is enabled in the settings.
+// It returns an error if the user already reached the maximum number of concurrent sessions.
+func (handler *Handler) openConsoleSession(r *http.Request, params *webSocketRequestParams, containerID, execID string) (*consoleSession, error) {
+	settings, err := handler.SettingsService.Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return nil, err
+	}
+
+	session := newConsoleSession(portainer.ConsoleSession{
+		UserID:      tokenData.ID,
+		Username:    tokenData.Username,
+		EndpointID:  params.endpoint.ID,
+		ContainerID: containerID,
+		ExecID:      execID,
+		NodeName:    params.nodeName,
+	})
+
+	err = handler.sessionRegistry.register(session, settings.ConsoleSessions.MaxSessionsPerUser)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.SessionRecording.Enabled {
+		session.recorder, session.recording, err = handler.startSessionRecording(session)
+		if err != nil {
+			handler.sessionRegistry.unregister(session.info.ID)
+			return nil, err
+		}
+	}
+
+	if settings.ConsoleSessions.IdleTimeout != "" {
+		idleTimeout, err := time.ParseDuration(settings.ConsoleSessions.IdleTimeout)
+		if err == nil && idleTimeout > 0 {
+			session.startIdleTimer(idleTimeout)
+		}
+	}
+
+	return session, nil
+}
+
+// closeConsoleSession removes the session from the registry and stops the recording of the session.
+func (handler *Handler) closeConsoleSession(session *consoleSession) {
+	session.stopIdleTimer()
+	handler.sessionRegistry.unregister(session.info.ID)
+	handler.stopSessionRecording(session.recorder, session.recording)
+}
+
+// setConnection associates the websocket connection of the client to the session.
+// The connection is closed straight away if the session was terminated in the meantime.
+func (session *consoleSession) setConnection(websocketConn *websocket.Conn) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	session.websocketConn = websocketConn
+	if session.terminated != nil {
+		session.closeConnection()
+	}
+}
+
+// terminate closes the websocket connection of the client, the reason is sent to the client inside the close message.
+func (session *consoleSession) terminate(reason error) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if session.terminated != nil {
+		return
+	}
+
+	session.terminated = reason
+	if session.websocketConn != nil {
+		session.closeConnection()
+	}
+}
+
+func (session *consoleSession) closeConnection() {
+	closeMessage := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, session.terminated.Error())
+	session.websocketConn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(closeMessageTimeout))
+	session.websocketConn.Close()
+}
+
+// snapshot returns a copy of the session metadata.
+func (session *consoleSession) snapshot() portainer.ConsoleSession {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	return session.info
+}
+
+// input records the data sent by the client and marks the session as active.
+func (session *consoleSession) input(data []byte) {
+	session.touch()
+	session.recorder.recordInput(data)
+}
+
+// output records the data sent to the client and marks the session as active, so that a process producing
+// output is not considered idle. It can be called on a nil session.
+func (session *consoleSession) output(data []byte) {
+	if session == nil {
+		return
+	}
+	session.touch()
+	session.recorder.recordOutput(data)
+}
+
+func (session *consoleSession) touch() {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	session.lastActivity = time.Now()
+	session.info.LastActivity = session.lastActivity.Unix()
+}
+
+// startIdleTimer terminates the session once no message was exchanged with the client during the idle timeout.
+func (session *consoleSession) startIdleTimer(idleTimeout time.Duration) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	session.idleTimeout = idleTimeout
+	session.idleTimer = time.AfterFunc(idleTimeout, session.checkIdleTimeout)
+}
+
+func (session *consoleSession) checkIdleTimeout() {
+	session.mutex.Lock()
+	idle := time.Since(session.lastActivity)
+	if idle < session.idleTimeout {
+		session.idleTimer.Reset(session.idleTimeout - idle)
+		session.mutex.Unlock()
+		return
+	}
+	session.mutex.Unlock()
+
+	session.terminate(portainer.ErrConsoleSessionIdleTimeout)
+}
+
+func (session *consoleSession) stopIdleTimer() {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if session.idleTimer != nil {
+		session.idleTimer.Stop()
+	}
+}
+
+// isControlMessage returns true when the data of a text message is a control message envelope.
+// Binary messages are never control messages.
+func isControlMessage(data []byte) bool {
+	if len(data) == 0 || data[0] != '{' {
+		return false
+	}
+
+	var message controlMessage
+	err := json.Unmarshal(data, &message)
+	return err == nil && message.Type == controlMessageResize
+}
+
+// handleControlMessage executes a control message sent by the client. Errors are logged
+// and do not interrupt the session.
+func (session *consoleSession) handleControlMessage(data []byte) {
+	session.touch()
+
+	err := session.executeControlMessage(data)
+	if err != nil {
+		log.Printf("websocket error: unable to execute console control message (err=%s)\n", err)
+	}
+}
+
+func (session *consoleSession) executeControlMessage(data []byte) error {
+	var message controlMessage
+	err := json.Unmarshal(data, &message)
+	if err != nil {
+		return err
+	}
+
+	switch message.Type {
+	case controlMessageResize:
+		if message.Width == 0 || message.Height == 0 {
+			return errInvalidTerminalSize
+		}
+
+		session.recorder.recordResize(message.Width, message.Height)
+		return session.resize(message.Width, message.Height)
+	}
+
+	return errUnsupportedControlMessage
+}
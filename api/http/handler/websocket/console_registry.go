@@ -0,0 +1,81 @@
+package websocket
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/portainer/portainer"
+)
+
+// consoleSessionRegistry keeps track of the active console sessions.
+type consoleSessionRegistry struct {
+	sessions map[portainer.ConsoleSessionID]*consoleSession
+	lastID   portainer.ConsoleSessionID
+	mutex    sync.RWMutex
+}
+
+func newConsoleSessionRegistry() *consoleSessionRegistry {
+	return &consoleSessionRegistry{
+		sessions: make(map[portainer.ConsoleSessionID]*consoleSession),
+	}
+}
+
+// register assigns an identifier to the session and adds it to the registry.
+// A maxSessionsPerUser value of 0 does not limit the number of sessions of the user.
+func (registry *consoleSessionRegistry) register(session *consoleSession, maxSessionsPerUser int) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if maxSessionsPerUser > 0 {
+		userSessions := 0
+		for _, existingSession := range registry.sessions {
+			if existingSession.info.UserID == session.info.UserID {
+				userSessions++
+			}
+		}
+
+		if userSessions >= maxSessionsPerUser {
+			return portainer.ErrConsoleSessionLimitReached
+		}
+	}
+
+	registry.lastID++
+	session.info.ID = registry.lastID
+	registry.sessions[session.info.ID] = session
+
+	return nil
+}
+
+func (registry *consoleSessionRegistry) unregister(ID portainer.ConsoleSessionID) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	delete(registry.sessions, ID)
+}
+
+func (registry *consoleSessionRegistry) session(ID portainer.ConsoleSessionID) (*consoleSession, error) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	session, ok := registry.sessions[ID]
+	if !ok {
+		return nil, portainer.ErrObjectNotFound
+	}
+	return session, nil
+}
+
+// list returns the metadata of the active sessions ordered by identifier.
+func (registry *consoleSessionRegistry) list() []portainer.ConsoleSession {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	sessions := make([]portainer.ConsoleSession, 0, len(registry.sessions))
+	for _, session := range registry.sessions {
+		sessions = append(sessions, session.snapshot())
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ID < sessions[j].ID
+	})
+
+	return sessions
+}
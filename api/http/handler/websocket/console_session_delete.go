@@ -0,0 +1,28 @@
+package websocket
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+	"github.com/portainer/portainer/http/response"
+)
+
+// DELETE request on /api/websocket/sessions/:id
+// The websocket connection of the client is closed, which terminates the exec or attach session.
+func (handler *Handler) consoleSessionDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	sessionID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid console session identifier route variable", err}
+	}
+
+	session, err := handler.sessionRegistry.session(portainer.ConsoleSessionID(sessionID))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an active console session with the specified identifier", err}
+	}
+
+	session.terminate(portainer.ErrConsoleSessionTerminated)
+
+	return response.Empty(w)
+}
@@ -0,0 +1,13 @@
+package websocket
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/response"
+)
+
+// GET request on /api/websocket/sessions
+func (handler *Handler) consoleSessionList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return response.JSON(w, handler.sessionRegistry.list())
+}
@@ -0,0 +1,56 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/http/proxy"
+	"github.com/portainer/portainer/http/security"
+)
+
+// checkContainerAccess ensures that the user issuing the request can access a container.
+// The same resource control checks as the ones applied by the Docker proxy to /containers/{id} are used.
+func (handler *Handler) checkContainerAccess(r *http.Request, cli *client.Client, endpoint *portainer.Endpoint, container *types.ContainerJSON) error {
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return err
+	}
+
+	if tokenData.Role == portainer.AdministratorRole {
+		return nil
+	}
+
+	resourceControls, err := handler.ResourceControlService.ResourceControls()
+	if err != nil {
+		return err
+	}
+
+	memberships, err := handler.TeamMembershipService.TeamMembershipsByUserID(tokenData.ID)
+	if err != nil {
+		return err
+	}
+
+	info, err := cli.Info(context.Background())
+	if err != nil {
+		return err
+	}
+
+	swarmID := ""
+	if info.Swarm.Cluster != nil {
+		swarmID = info.Swarm.Cluster.ID
+	}
+
+	var labels map[string]string
+	if container.Config != nil {
+		labels = container.Config.Labels
+	}
+
+	if !proxy.CanAccessContainer(container.ID, labels, resourceControls, endpoint.ID, swarmID, tokenData.ID, memberships) {
+		return portainer.ErrResourceAccessDenied
+	}
+
+	return nil
+}
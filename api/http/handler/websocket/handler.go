@@ -1,9 +1,12 @@
 package websocket
 
 import (
+	"net/http"
+
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/docker"
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/security"
 )
@@ -11,10 +14,23 @@ import (
 // Handler is the HTTP handler used to handle websocket operations.
 type Handler struct {
 	*mux.Router
-	EndpointService    portainer.EndpointService
-	SignatureService   portainer.DigitalSignatureService
-	requestBouncer     *security.RequestBouncer
-	connectionUpgrader websocket.Upgrader
+	EndpointService         portainer.EndpointService
+	SignatureService        portainer.DigitalSignatureService
+	SettingsService         portainer.SettingsService
+	SessionRecordingService portainer.SessionRecordingService
+	FileService             portainer.FileService
+	ResourceControlService  portainer.ResourceControlService
+	TeamMembershipService   portainer.TeamMembershipService
+	ClientFactory           *docker.ClientFactory
+	requestBouncer          *security.RequestBouncer
+	connectionUpgrader      websocket.Upgrader
+	sessionRegistry         *consoleSessionRegistry
+}
+
+type webSocketRequestParams struct {
+	ID       string
+	nodeName string
+	endpoint *portainer.Endpoint
 }
 
 // NewHandler creates a handler to manage websocket operations.
@@ -23,8 +39,17 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		Router:             mux.NewRouter(),
 		connectionUpgrader: websocket.Upgrader{},
 		requestBouncer:     bouncer,
+		sessionRegistry:    newConsoleSessionRegistry(),
 	}
 	h.PathPrefix("/websocket/exec").Handler(
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.websocketExec)))
+	h.PathPrefix("/websocket/attach").Handler(
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.websocketAttach)))
+	h.PathPrefix("/websocket/logs").Handler(
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.websocketLogs)))
+	h.Handle("/websocket/sessions",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.consoleSessionList))).Methods(http.MethodGet)
+	h.Handle("/websocket/sessions/{id}",
+		bouncer.AdministratorAccess(httperror.LoggerHandler(h.consoleSessionDelete))).Methods(http.MethodDelete)
 	return h
 }
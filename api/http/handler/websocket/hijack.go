@@ -0,0 +1,165 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/gorilla/websocket"
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/crypto"
+)
+
+// hijackStartOperation sends a request that is hijacked by the Docker engine (exec start or container attach)
+// and forwards the raw stream between the hijacked connection and the websocket connection.
+func hijackStartOperation(websocketConn *websocket.Conn, endpoint *portainer.Endpoint, request *http.Request, session *consoleSession) error {
+	dial, err := initDial(endpoint)
+	if err != nil {
+		return err
+	}
+
+	// When we set up a TCP connection for hijack, there could be long periods
+	// of inactivity (a long running command with no output) that in certain
+	// network setups may cause ECONNTIMEOUT, leaving the client in an unknown
+	// state. Setting TCP KeepAlive on the socket connection will prohibit
+	// ECONNTIMEOUT unless the socket connection truly is broken
+	if tcpConn, ok := dial.(*net.TCPConn); ok {
+		tcpConn.SetKeepAlive(true)
+		tcpConn.SetKeepAlivePeriod(30 * time.Second)
+	}
+
+	httpConn := httputil.NewClientConn(dial, nil)
+	defer httpConn.Close()
+
+	return hijackRequest(websocketConn, httpConn, request, session)
+}
+
+func initDial(endpoint *portainer.Endpoint) (net.Conn, error) {
+	url, err := url.Parse(endpoint.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	host := url.Host
+
+	if url.Scheme == "unix" || url.Scheme == "npipe" {
+		host = url.Path
+	}
+
+	if endpoint.TLSConfig.TLS {
+		tlsConfig, err := crypto.CreateTLSConfigurationFromDisk(endpoint.TLSConfig.TLSCACertPath, endpoint.TLSConfig.TLSCertPath, endpoint.TLSConfig.TLSKeyPath, endpoint.TLSConfig.TLSSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+
+		return tls.Dial(url.Scheme, host, tlsConfig)
+	}
+
+	return createDial(url.Scheme, host)
+}
+
+func hijackRequest(websocketConn *websocket.Conn, httpConn *httputil.ClientConn, request *http.Request, session *consoleSession) error {
+	// Server hijacks the connection, error 'connection closed' expected
+	resp, err := httpConn.Do(request)
+	if err != httputil.ErrPersistEOF {
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			resp.Body.Close()
+			return fmt.Errorf("unable to upgrade to tcp, received %d", resp.StatusCode)
+		}
+	}
+
+	tcpConn, brw := httpConn.Hijack()
+	defer tcpConn.Close()
+
+	errorChan := make(chan error, 2)
+	go streamFromTCPConnToWebsocketConn(websocketConn, brw, session, errorChan)
+	go streamFromWebsocketConnToTCPConn(websocketConn, tcpConn, session, errorChan)
+
+	err = <-errorChan
+	if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+		return err
+	}
+
+	return nil
+}
+
+func streamFromWebsocketConnToTCPConn(websocketConn *websocket.Conn, tcpConn net.Conn, session *consoleSession, errorChan chan error) {
+	for {
+		messageType, in, err := websocketConn.ReadMessage()
+		if err != nil {
+			errorChan <- err
+			break
+		}
+
+		if messageType == websocket.TextMessage && isControlMessage(in) {
+			session.handleControlMessage(in)
+			continue
+		}
+
+		session.input(in)
+
+		_, err = tcpConn.Write(in)
+		if err != nil {
+			errorChan <- err
+			break
+		}
+	}
+}
+
+// streamFromTCPConnToWebsocketConn forwards the output of the hijacked connection to the websocket connection.
+// When the process does not have a TTY, the stdout and stderr streams are multiplexed by the Docker engine
+// and the output is demultiplexed before being forwarded.
+func streamFromTCPConnToWebsocketConn(websocketConn *websocket.Conn, br *bufio.Reader, session *consoleSession, errorChan chan error) {
+	writer := &websocketOutputWriter{websocketConn: websocketConn, session: session}
+
+	if !session.tty {
+		_, err := stdcopy.StdCopy(writer, writer, br)
+		if err == nil {
+			err = io.EOF
+		}
+		errorChan <- err
+		return
+	}
+
+	for {
+		out := make([]byte, 2048)
+		n, err := br.Read(out)
+		if err != nil {
+			errorChan <- err
+			break
+		}
+
+		_, err = writer.Write(out[:n])
+		if err != nil {
+			errorChan <- err
+			break
+		}
+	}
+}
+
+// websocketOutputWriter writes the output of a container process to a websocket connection.
+// The session is optional and only used to record the output.
+type websocketOutputWriter struct {
+	websocketConn *websocket.Conn
+	session       *consoleSession
+}
+
+func (writer *websocketOutputWriter) Write(p []byte) (int, error) {
+	writer.session.output(p)
+
+	err := writer.websocketConn.WriteMessage(websocket.TextMessage, p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
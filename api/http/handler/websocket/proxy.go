@@ -0,0 +1,143 @@
+package websocket
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/gorilla/websocket"
+	"github.com/portainer/portainer"
+)
+
+// proxyWebsocketRequest connects to the websocket endpoint of the agent and pumps the messages
+// between both websocket connections. Control messages sent by the client are executed instead of
+// being forwarded.
+func (handler *Handler) proxyWebsocketRequest(w http.ResponseWriter, r *http.Request, params *webSocketRequestParams, session *consoleSession) error {
+	agentURL, err := url.Parse(params.endpoint.URL)
+	if err != nil {
+		return err
+	}
+
+	agentURL.Scheme = "ws"
+	agentURL.Path = r.URL.Path
+	agentURL.RawQuery = r.URL.RawQuery
+
+	dialer := &websocket.Dialer{}
+	if params.endpoint.TLSConfig.TLS || params.endpoint.TLSConfig.TLSSkipVerify {
+		agentURL.Scheme = "wss"
+		dialer.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: params.endpoint.TLSConfig.TLSSkipVerify,
+		}
+	}
+
+	signature, err := handler.SignatureService.CreateSignature(portainer.PortainerAgentSignatureMessage)
+	if err != nil {
+		return err
+	}
+
+	headers := http.Header{}
+	headers.Set(portainer.PortainerAgentPublicKeyHeader, handler.SignatureService.EncodedPublicKey())
+	headers.Set(portainer.PortainerAgentSignatureHeader, signature)
+	headers.Set(portainer.PortainerAgentTargetHeader, params.nodeName)
+
+	agentConn, _, err := dialer.Dial(agentURL.String(), headers)
+	if err != nil {
+		return err
+	}
+	defer agentConn.Close()
+
+	websocketConn, err := handler.connectionUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+	defer websocketConn.Close()
+	session.setConnection(websocketConn)
+
+	errorChan := make(chan error, 2)
+	go streamFromAgentConnToWebsocketConn(agentConn, websocketConn, session, errorChan)
+	go streamFromWebsocketConnToAgentConn(websocketConn, agentConn, session, errorChan)
+
+	err = <-errorChan
+	if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+		return err
+	}
+
+	return nil
+}
+
+// streamFromAgentConnToWebsocketConn forwards the messages of the agent to the websocket connection. The agent forwards
+// the raw stream of the process, the output of processes running without a TTY is demultiplexed before being forwarded.
+func streamFromAgentConnToWebsocketConn(agentConn, websocketConn *websocket.Conn, session *consoleSession, errorChan chan error) {
+	if !session.tty {
+		streamMultiplexedOutputFromAgentConn(agentConn, websocketConn, session, errorChan)
+		return
+	}
+
+	for {
+		messageType, data, err := agentConn.ReadMessage()
+		if err != nil {
+			errorChan <- err
+			break
+		}
+
+		session.output(data)
+
+		err = websocketConn.WriteMessage(messageType, data)
+		if err != nil {
+			errorChan <- err
+			break
+		}
+	}
+}
+
+func streamMultiplexedOutputFromAgentConn(agentConn, websocketConn *websocket.Conn, session *consoleSession, errorChan chan error) {
+	reader, writer := io.Pipe()
+
+	go func() {
+		for {
+			_, data, err := agentConn.ReadMessage()
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+
+			_, err = writer.Write(data)
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	output := &websocketOutputWriter{websocketConn: websocketConn, session: session}
+	_, err := stdcopy.StdCopy(output, output, reader)
+	if err == nil {
+		err = io.EOF
+	}
+	reader.CloseWithError(err)
+	errorChan <- err
+}
+
+func streamFromWebsocketConnToAgentConn(websocketConn, agentConn *websocket.Conn, session *consoleSession, errorChan chan error) {
+	for {
+		messageType, data, err := websocketConn.ReadMessage()
+		if err != nil {
+			errorChan <- err
+			break
+		}
+
+		if messageType == websocket.TextMessage && isControlMessage(data) {
+			session.handleControlMessage(data)
+			continue
+		}
+
+		session.input(data)
+
+		err = agentConn.WriteMessage(messageType, data)
+		if err != nil {
+			errorChan <- err
+			break
+		}
+	}
+}
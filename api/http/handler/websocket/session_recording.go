@@ -0,0 +1,199 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	asciicastVersion       = 2
+	asciicastDefaultWidth  = 80
+	asciicastDefaultHeight = 24
+	asciicastOutputEvent   = "o"
+	asciicastInputEvent    = "i"
+	asciicastResizeEvent   = "r"
+)
+
+type (
+	// sessionRecorder writes the content of an exec session to a file using the asciicast v2 format.
+	// See https://github.com/asciinema/asciinema/blob/develop/doc/asciicast-v2.md
+	// All the methods can be called on a nil recorder, in which case nothing is recorded.
+	sessionRecorder struct {
+		file          *os.File
+		startTime     time.Time
+		size          int64
+		pendingOutput []byte
+		pendingInput  []byte
+		mutex         sync.Mutex
+	}
+
+	asciicastHeader struct {
+		Version   int               `json:"version"`
+		Width     int               `json:"width"`
+		Height    int               `json:"height"`
+		Timestamp int64             `json:"timestamp"`
+		Env       map[string]string `json:"env"`
+	}
+)
+
+func newSessionRecorder(filePath string, startTime time.Time) (*sessionRecorder, error) {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := &sessionRecorder{
+		file:      file,
+		startTime: startTime,
+	}
+
+	header := asciicastHeader{
+		Version:   asciicastVersion,
+		Width:     asciicastDefaultWidth,
+		Height:    asciicastDefaultHeight,
+		Timestamp: startTime.Unix(),
+		Env:       map[string]string{"TERM": "xterm"},
+	}
+
+	err = recorder.writeLine(header)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return recorder, nil
+}
+
+func (recorder *sessionRecorder) recordOutput(data []byte) {
+	if recorder == nil {
+		return
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.pendingOutput = recorder.recordEvent(asciicastOutputEvent, recorder.pendingOutput, data)
+}
+
+func (recorder *sessionRecorder) recordInput(data []byte) {
+	if recorder == nil {
+		return
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.pendingInput = recorder.recordEvent(asciicastInputEvent, recorder.pendingInput, data)
+}
+
+func (recorder *sessionRecorder) recordResize(width, height uint) {
+	if recorder == nil {
+		return
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.recordEvent(asciicastResizeEvent, nil, []byte(fmt.Sprintf("%dx%d", width, height)))
+}
+
+// recordEvent writes an event containing the pending data followed by the new data.
+// A trailing incomplete UTF-8 sequence is not written and returned so that it can be prepended
+// to the next event of the same type.
+func (recorder *sessionRecorder) recordEvent(eventType string, pending, data []byte) []byte {
+	data = append(pending, data...)
+
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+
+	if cut > 0 {
+		elapsed := time.Since(recorder.startTime).Seconds()
+		err := recorder.writeLine([]interface{}{elapsed, eventType, string(data[:cut])})
+		if err != nil {
+			log.Printf("websocket error: unable to write session recording event (err=%s)\n", err)
+		}
+	}
+
+	return append([]byte{}, data[cut:]...)
+}
+
+func (recorder *sessionRecorder) writeLine(content interface{}) error {
+	line, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+
+	n, err := recorder.file.Write(append(line, '\n'))
+	recorder.size += int64(n)
+	return err
+}
+
+// close closes the underlying file and returns the size of the recording.
+func (recorder *sessionRecorder) close() (int64, error) {
+	if recorder == nil {
+		return 0, nil
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return recorder.size, recorder.file.Close()
+}
+
+// startSessionRecording creates the session recording associated to an exec or attach session.
+func (handler *Handler) startSessionRecording(session *consoleSession) (*sessionRecorder, *portainer.SessionRecording, error) {
+	recording := &portainer.SessionRecording{
+		UserID:      session.info.UserID,
+		Username:    session.info.Username,
+		EndpointID:  session.info.EndpointID,
+		ContainerID: session.info.ContainerID,
+		ExecID:      session.info.ExecID,
+		NodeName:    session.info.NodeName,
+		StartTime:   session.info.StartTime,
+	}
+
+	err := handler.SessionRecordingService.CreateSessionRecording(recording)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recordingIdentifier := strconv.Itoa(int(recording.ID))
+	recorder, err := newSessionRecorder(handler.FileService.GetSessionRecordingPath(recordingIdentifier), session.startTime)
+	if err != nil {
+		handler.SessionRecordingService.DeleteSessionRecording(recording.ID)
+		return nil, nil, err
+	}
+
+	return recorder, recording, nil
+}
+
+// stopSessionRecording closes the recording file and persists the end time and size of the recording.
+func (handler *Handler) stopSessionRecording(recorder *sessionRecorder, recording *portainer.SessionRecording) {
+	if recorder == nil {
+		return
+	}
+
+	size, err := recorder.close()
+	if err != nil {
+		log.Printf("websocket error: unable to close session recording file (recording=%d) (err=%s)\n", recording.ID, err)
+	}
+
+	recording.EndTime = time.Now().Unix()
+	recording.Size = size
+
+	err = handler.SessionRecordingService.UpdateSessionRecording(recording.ID, recording)
+	if err != nil {
+		log.Printf("websocket error: unable to update session recording (recording=%d) (err=%s)\n", recording.ID, err)
+	}
+}
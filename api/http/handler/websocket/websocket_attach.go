@@ -0,0 +1,130 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+)
+
+// websocketAttach handles GET requests on /websocket/attach?id=<containerID>&endpointId=<endpointID>&nodeName=<nodeName>&token=<token>
+// If the nodeName query parameter is present, the request will be proxied to the underlying agent endpoint.
+// If the nodeName query parameter is not specified, the request will be upgraded to the websocket protocol and
+// an AttachStart operation HTTP request will be created and hijacked.
+// Text messages containing a control message are used to resize the TTY, see websocketExec.
+// Authentication and access is controled via the mandatory token query parameter, the resource controls
+// associated to the container are checked before the connection is upgraded.
+// When session recording is enabled in the settings, the session is recorded using the asciicast v2 format.
+func (handler *Handler) websocketAttach(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	containerID, err := request.RetrieveQueryParameter(r, "id", false)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: id", err}
+	}
+	if !govalidator.IsHexadecimal(containerID) {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: id (must be hexadecimal identifier)", err}
+	}
+
+	endpointID, err := request.RetrieveNumericQueryParameter(r, "endpointId", false)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: endpointId", err}
+	}
+
+	endpoint, err := handler.EndpointService.Endpoint(portainer.EndpointID(endpointID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.EndpointAccess(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", portainer.ErrEndpointAccessDenied}
+	}
+
+	params := &webSocketRequestParams{
+		endpoint: endpoint,
+		ID:       containerID,
+		nodeName: r.FormValue("nodeName"),
+	}
+
+	err = handler.handleAttachRequest(w, r, params)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return &httperror.HandlerError{http.StatusNotFound, "Unable to find a container with the specified identifier", err}
+		} else if err == portainer.ErrResourceAccessDenied {
+			return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access container", err}
+		} else if err == portainer.ErrConsoleSessionLimitReached {
+			return &httperror.HandlerError{http.StatusTooManyRequests, "Unable to open a new console session", err}
+		}
+		return &httperror.HandlerError{http.StatusInternalServerError, "An error occured during websocket attach operation", err}
+	}
+
+	return nil
+}
+
+func (handler *Handler) handleAttachRequest(w http.ResponseWriter, r *http.Request, params *webSocketRequestParams) error {
+	r.Header.Del("Origin")
+
+	cli, err := handler.ClientFactory.CreateClient(params.endpoint, params.nodeName)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	container, err := cli.ContainerInspect(context.Background(), params.ID)
+	if err != nil {
+		return err
+	}
+
+	err = handler.checkContainerAccess(r, cli, params.endpoint, &container)
+	if err != nil {
+		return err
+	}
+
+	session, err := handler.openConsoleSession(r, params, container.ID, "")
+	if err != nil {
+		return err
+	}
+	defer handler.closeConsoleSession(session)
+
+	session.tty = container.Config.Tty
+	session.resize = func(width, height uint) error {
+		return cli.ContainerResize(context.Background(), params.ID, types.ResizeOptions{Width: width, Height: height})
+	}
+
+	if params.nodeName != "" || params.endpoint.Type == portainer.AgentOnDockerEnvironment {
+		return handler.proxyWebsocketRequest(w, r, params, session)
+	}
+
+	websocketConn, err := handler.connectionUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+	defer websocketConn.Close()
+	session.setConnection(websocketConn)
+
+	attachStartRequest, err := createAttachStartRequest(params.ID)
+	if err != nil {
+		return err
+	}
+
+	return hijackStartOperation(websocketConn, params.endpoint, attachStartRequest, session)
+}
+
+func createAttachStartRequest(containerID string) (*http.Request, error) {
+	request, err := http.NewRequest("POST", "/containers/"+containerID+"/attach?stream=1&stdin=1&stdout=1&stderr=1", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "text/plain")
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "tcp")
+
+	return request, nil
+}
@@ -1,42 +1,43 @@
 package websocket
 
 import (
-	"bufio"
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"fmt"
-	"net"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
-	"time"
 
 	"github.com/asaskevich/govalidator"
-	"github.com/gorilla/websocket"
-	"github.com/koding/websocketproxy"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
 	"github.com/portainer/portainer"
-	"github.com/portainer/portainer/crypto"
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/request"
 )
 
-type webSocketExecRequestParams struct {
-	execID   string
-	nodeName string
-	endpoint *portainer.Endpoint
-}
+const errExecInstanceNotFound = portainer.Error("Unable to find an exec instance with the specified identifier")
 
 type execStartOperationPayload struct {
 	Tty    bool
 	Detach bool
 }
 
+type execInspectResponse struct {
+	ContainerID   string
+	ProcessConfig struct {
+		Tty bool `json:"tty"`
+	}
+}
+
 // websocketExec handles GET requests on /websocket/exec?id=<execID>&endpointId=<endpointID>&nodeName=<nodeName>&token=<token>
 // If the nodeName query parameter is present, the request will be proxied to the underlying agent endpoint.
 // If the nodeName query parameter is not specified, the request will be upgraded to the websocket protocol and
 // an ExecStart operation HTTP request will be created and hijacked.
+// Messages are forwarded to the process as input, except text messages containing a control message used to resize
+// the TTY, e.g. {"type":"resize","width":120,"height":40}. The output of processes running without a TTY is demultiplexed.
 // Authentication and access is controled via the mandatory token query parameter.
+// When session recording is enabled in the settings, the session is recorded using the asciicast v2 format.
 func (handler *Handler) websocketExec(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
 	execID, err := request.RetrieveQueryParameter(r, "id", false)
 	if err != nil {
@@ -63,129 +64,130 @@ func (handler *Handler) websocketExec(w http.ResponseWriter, r *http.Request) *h
 		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", portainer.ErrEndpointAccessDenied}
 	}
 
-	params := &webSocketExecRequestParams{
+	params := &webSocketRequestParams{
 		endpoint: endpoint,
-		execID:   execID,
+		ID:       execID,
 		nodeName: r.FormValue("nodeName"),
 	}
 
-	err = handler.handleRequest(w, r, params)
-	if err != nil {
+	err = handler.handleExecRequest(w, r, params)
+	if err == errExecInstanceNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an exec instance with the specified identifier", err}
+	} else if err == portainer.ErrConsoleSessionLimitReached {
+		return &httperror.HandlerError{http.StatusTooManyRequests, "Unable to open a new console session", err}
+	} else if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "An error occured during websocket exec operation", err}
 	}
 
 	return nil
 }
 
-func (handler *Handler) handleRequest(w http.ResponseWriter, r *http.Request, params *webSocketExecRequestParams) error {
+func (handler *Handler) handleExecRequest(w http.ResponseWriter, r *http.Request, params *webSocketRequestParams) error {
 	r.Header.Del("Origin")
 
-	if params.nodeName != "" || params.endpoint.Type == portainer.AgentOnDockerEnvironment {
-		return handler.proxyWebsocketRequest(w, r, params)
+	cli, err := handler.ClientFactory.CreateClient(params.endpoint, params.nodeName)
+	if err != nil {
+		return err
 	}
+	defer cli.Close()
 
-	websocketConn, err := handler.connectionUpgrader.Upgrade(w, r, nil)
+	execInspect, err := handler.inspectExecInstance(cli, params)
 	if err != nil {
 		return err
 	}
-	defer websocketConn.Close()
-
-	return hijackExecStartOperation(websocketConn, params.endpoint, params.execID)
-}
 
-func (handler *Handler) proxyWebsocketRequest(w http.ResponseWriter, r *http.Request, params *webSocketExecRequestParams) error {
-	agentURL, err := url.Parse(params.endpoint.URL)
+	session, err := handler.openConsoleSession(r, params, execInspect.ContainerID, params.ID)
 	if err != nil {
 		return err
 	}
+	defer handler.closeConsoleSession(session)
 
-	agentURL.Scheme = "ws"
-	proxy := websocketproxy.NewProxy(agentURL)
+	session.tty = execInspect.ProcessConfig.Tty
+	session.resize = func(width, height uint) error {
+		return cli.ContainerExecResize(context.Background(), params.ID, types.ResizeOptions{Width: width, Height: height})
+	}
 
-	if params.endpoint.TLSConfig.TLS || params.endpoint.TLSConfig.TLSSkipVerify {
-		agentURL.Scheme = "wss"
-		proxy.Dialer = &websocket.Dialer{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: params.endpoint.TLSConfig.TLSSkipVerify,
-			},
-		}
+	if params.nodeName != "" || params.endpoint.Type == portainer.AgentOnDockerEnvironment {
+		return handler.proxyWebsocketRequest(w, r, params, session)
 	}
 
-	signature, err := handler.SignatureService.CreateSignature(portainer.PortainerAgentSignatureMessage)
+	websocketConn, err := handler.connectionUpgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return err
 	}
+	defer websocketConn.Close()
+	session.setConnection(websocketConn)
 
-	proxy.Director = func(incoming *http.Request, out http.Header) {
-		out.Set(portainer.PortainerAgentPublicKeyHeader, handler.SignatureService.EncodedPublicKey())
-		out.Set(portainer.PortainerAgentSignatureHeader, signature)
-		out.Set(portainer.PortainerAgentTargetHeader, params.nodeName)
+	execStartRequest, err := createExecStartRequest(params.ID, session.tty)
+	if err != nil {
+		return err
 	}
 
-	proxy.ServeHTTP(w, r)
-
-	return nil
+	return hijackStartOperation(websocketConn, params.endpoint, execStartRequest, session)
 }
 
-func hijackExecStartOperation(websocketConn *websocket.Conn, endpoint *portainer.Endpoint, execID string) error {
-	dial, err := initDial(endpoint)
+// inspectExecInstance retrieves the container and the process configuration of an exec instance. The Docker client
+// does not expose the process configuration of an exec instance, the request is sent using the HTTP client of the
+// Docker client instead. Requests sent to an agent are signed.
+func (handler *Handler) inspectExecInstance(cli *client.Client, params *webSocketRequestParams) (*execInspectResponse, error) {
+	daemonURL, err := url.Parse(cli.DaemonHost())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// When we set up a TCP connection for hijack, there could be long periods
-	// of inactivity (a long running command with no output) that in certain
-	// network setups may cause ECONNTIMEOUT, leaving the client in an unknown
-	// state. Setting TCP KeepAlive on the socket connection will prohibit
-	// ECONNTIMEOUT unless the socket connection truly is broken
-	if tcpConn, ok := dial.(*net.TCPConn); ok {
-		tcpConn.SetKeepAlive(true)
-		tcpConn.SetKeepAlivePeriod(30 * time.Second)
+	// The HTTP client of the Docker client dials the socket itself, the host is only used in the request
+	if daemonURL.Scheme == "unix" || daemonURL.Scheme == "npipe" {
+		daemonURL.Host = "docker"
 	}
 
-	httpConn := httputil.NewClientConn(dial, nil)
-	defer httpConn.Close()
-
-	execStartRequest, err := createExecStartRequest(execID)
-	if err != nil {
-		return err
+	daemonURL.Scheme = "http"
+	if params.endpoint.TLSConfig.TLS {
+		daemonURL.Scheme = "https"
 	}
+	daemonURL.Path = "/v" + cli.ClientVersion() + "/exec/" + params.ID + "/json"
 
-	err = hijackRequest(websocketConn, httpConn, execStartRequest)
+	request, err := http.NewRequest("GET", daemonURL.String(), nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
-}
+	if params.endpoint.Type == portainer.AgentOnDockerEnvironment {
+		signature, err := handler.SignatureService.CreateSignature(portainer.PortainerAgentSignatureMessage)
+		if err != nil {
+			return nil, err
+		}
+
+		request.Header.Set(portainer.PortainerAgentPublicKeyHeader, handler.SignatureService.EncodedPublicKey())
+		request.Header.Set(portainer.PortainerAgentSignatureHeader, signature)
+		if params.nodeName != "" {
+			request.Header.Set(portainer.PortainerAgentTargetHeader, params.nodeName)
+		}
+	}
 
-func initDial(endpoint *portainer.Endpoint) (net.Conn, error) {
-	url, err := url.Parse(endpoint.URL)
+	response, err := cli.HTTPClient().Do(request)
 	if err != nil {
 		return nil, err
 	}
+	defer response.Body.Close()
 
-	host := url.Host
-
-	if url.Scheme == "unix" || url.Scheme == "npipe" {
-		host = url.Path
+	if response.StatusCode == http.StatusNotFound {
+		return nil, errExecInstanceNotFound
+	} else if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to inspect exec instance, received %d", response.StatusCode)
 	}
 
-	if endpoint.TLSConfig.TLS {
-		tlsConfig, err := crypto.CreateTLSConfigurationFromDisk(endpoint.TLSConfig.TLSCACertPath, endpoint.TLSConfig.TLSCertPath, endpoint.TLSConfig.TLSKeyPath, endpoint.TLSConfig.TLSSkipVerify)
-		if err != nil {
-			return nil, err
-		}
-
-		return tls.Dial(url.Scheme, host, tlsConfig)
+	var execInspect execInspectResponse
+	err = json.NewDecoder(response.Body).Decode(&execInspect)
+	if err != nil {
+		return nil, err
 	}
 
-	return createDial(url.Scheme, host)
+	return &execInspect, nil
 }
 
-func createExecStartRequest(execID string) (*http.Request, error) {
+func createExecStartRequest(execID string, tty bool) (*http.Request, error) {
 	execStartOperationPayload := &execStartOperationPayload{
-		Tty:    true,
+		Tty:    tty,
 		Detach: false,
 	}
 
@@ -206,64 +208,3 @@ func createExecStartRequest(execID string) (*http.Request, error) {
 
 	return request, nil
 }
-
-func hijackRequest(websocketConn *websocket.Conn, httpConn *httputil.ClientConn, request *http.Request) error {
-	// Server hijacks the connection, error 'connection closed' expected
-	resp, err := httpConn.Do(request)
-	if err != httputil.ErrPersistEOF {
-		if err != nil {
-			return err
-		}
-		if resp.StatusCode != http.StatusSwitchingProtocols {
-			resp.Body.Close()
-			return fmt.Errorf("unable to upgrade to tcp, received %d", resp.StatusCode)
-		}
-	}
-
-	tcpConn, brw := httpConn.Hijack()
-	defer tcpConn.Close()
-
-	errorChan := make(chan error, 1)
-	go streamFromTCPConnToWebsocketConn(websocketConn, brw, errorChan)
-	go streamFromWebsocketConnToTCPConn(websocketConn, tcpConn, errorChan)
-
-	err = <-errorChan
-	if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
-		return err
-	}
-
-	return nil
-}
-
-func streamFromWebsocketConnToTCPConn(websocketConn *websocket.Conn, tcpConn net.Conn, errorChan chan error) {
-	for {
-		_, in, err := websocketConn.ReadMessage()
-		if err != nil {
-			errorChan <- err
-			break
-		}
-
-		_, err = tcpConn.Write(in)
-		if err != nil {
-			errorChan <- err
-			break
-		}
-	}
-}
-
-func streamFromTCPConnToWebsocketConn(websocketConn *websocket.Conn, br *bufio.Reader, errorChan chan error) {
-	for {
-		out := make([]byte, 2048)
-		_, err := br.Read(out)
-		if err != nil {
-			errorChan <- err
-			break
-		}
-
-		err = websocketConn.WriteMessage(websocket.TextMessage, out)
-		if err != nil {
-			errorChan <- err
-			break
-		}
-	}
-}
@@ -0,0 +1,147 @@
+package websocket
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/gorilla/websocket"
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/request"
+)
+
+// websocketLogs handles GET requests on /websocket/logs?id=<containerID>&endpointId=<endpointID>&nodeName=<nodeName>&tail=<tail>&since=<since>&timestamps=<timestamps>&token=<token>
+// The request will be upgraded to the websocket protocol and the logs of the container will be streamed in follow mode.
+// The stdout and stderr streams of containers running without a TTY are demultiplexed before being sent.
+// If the nodeName query parameter is present, the logs are retrieved from the specified node of the agent cluster.
+// Authentication and access is controled via the mandatory token query parameter, the resource controls
+// associated to the container are checked before the connection is upgraded.
+func (handler *Handler) websocketLogs(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	containerID, err := request.RetrieveQueryParameter(r, "id", false)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: id", err}
+	}
+	if !govalidator.IsHexadecimal(containerID) {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: id (must be hexadecimal identifier)", err}
+	}
+
+	endpointID, err := request.RetrieveNumericQueryParameter(r, "endpointId", false)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: endpointId", err}
+	}
+
+	tail, _ := request.RetrieveQueryParameter(r, "tail", true)
+	if tail == "" {
+		tail = "all"
+	}
+	since, _ := request.RetrieveQueryParameter(r, "since", true)
+	timestamps, _ := request.RetrieveBooleanQueryParameter(r, "timestamps", true)
+
+	endpoint, err := handler.EndpointService.Endpoint(portainer.EndpointID(endpointID))
+	if err == portainer.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.EndpointAccess(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", portainer.ErrEndpointAccessDenied}
+	}
+
+	params := &webSocketRequestParams{
+		endpoint: endpoint,
+		ID:       containerID,
+		nodeName: r.FormValue("nodeName"),
+	}
+
+	options := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Tail:       tail,
+		Since:      since,
+		Timestamps: timestamps,
+	}
+
+	err = handler.handleLogsRequest(w, r, params, options)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return &httperror.HandlerError{http.StatusNotFound, "Unable to find a container with the specified identifier", err}
+		} else if err == portainer.ErrResourceAccessDenied {
+			return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access container", err}
+		}
+		return &httperror.HandlerError{http.StatusInternalServerError, "An error occured during websocket logs operation", err}
+	}
+
+	return nil
+}
+
+func (handler *Handler) handleLogsRequest(w http.ResponseWriter, r *http.Request, params *webSocketRequestParams, options types.ContainerLogsOptions) error {
+	r.Header.Del("Origin")
+
+	cli, err := handler.ClientFactory.CreateStreamingClient(params.endpoint, params.nodeName)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	container, err := cli.ContainerInspect(context.Background(), params.ID)
+	if err != nil {
+		return err
+	}
+
+	err = handler.checkContainerAccess(r, cli, params.endpoint, &container)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logs, err := cli.ContainerLogs(ctx, params.ID, options)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	websocketConn, err := handler.connectionUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+	defer websocketConn.Close()
+
+	// Messages sent by the client are discarded, reading the connection is required
+	// to detect when the client closes it and stop following the logs.
+	go func() {
+		for {
+			_, _, err := websocketConn.NextReader()
+			if err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	writer := &websocketOutputWriter{websocketConn: websocketConn}
+	if container.Config.Tty {
+		_, err = io.Copy(writer, logs)
+	} else {
+		_, err = stdcopy.StdCopy(writer, writer, logs)
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return websocketConn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
@@ -1,6 +1,11 @@
 package proxy
 
-import "github.com/portainer/portainer"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/portainer/portainer"
+)
 
 type (
 	// ExtendedStack represents a stack combined with its associated access control
@@ -121,6 +126,41 @@ func getResourceControlByResourceID(resourceID string, resourceControls []portai
 	return nil
 }
 
+// StackResourceControlID returns the identifier of the resource control associated to a stack.
+// Stack names are only unique per endpoint (or per Swarm cluster for Swarm stacks), the identifier
+// is composed of the name of the stack followed by the identifier of the Swarm cluster or of the endpoint.
+func StackResourceControlID(stack *portainer.Stack) string {
+	if stack.Type == portainer.DockerSwarmStack && stack.SwarmID != "" {
+		return stack.Name + "_" + stack.SwarmID
+	}
+	return stack.Name + "_" + strconv.Itoa(int(stack.EndpointID))
+}
+
+// scopeStackResourceControls adds the name of the stack as a sub resource identifier of the stack resource controls
+// associated to the endpoint or to the Swarm cluster the endpoint is part of. Resources deployed as part of a stack
+// only reference the stack by its name inside their labels.
+func scopeStackResourceControls(resourceControls []portainer.ResourceControl, endpointID portainer.EndpointID, swarmID string) {
+	suffixes := []string{"_" + strconv.Itoa(int(endpointID))}
+	if swarmID != "" {
+		suffixes = append(suffixes, "_"+swarmID)
+	}
+
+	for idx := range resourceControls {
+		resourceControl := &resourceControls[idx]
+		if resourceControl.Type != portainer.StackResourceControl {
+			continue
+		}
+
+		for _, suffix := range suffixes {
+			if len(resourceControl.ResourceID) > len(suffix) && strings.HasSuffix(resourceControl.ResourceID, suffix) {
+				stackName := strings.TrimSuffix(resourceControl.ResourceID, suffix)
+				resourceControl.SubResourceIDs = append(resourceControl.SubResourceIDs, stackName)
+				break
+			}
+		}
+	}
+}
+
 // CanAccessStack checks if a user can access a stack
 func CanAccessStack(stack *portainer.Stack, resourceControl *portainer.ResourceControl, userID portainer.UserID, memberships []portainer.TeamMembership) bool {
 	userTeamIDs := make([]portainer.TeamID, 0)
@@ -135,6 +175,40 @@ func CanAccessStack(stack *portainer.Stack, resourceControl *portainer.ResourceC
 	return false
 }
 
+// CanAccessContainer checks if a user can access a container. As done by the Docker proxy when a container is inspected,
+// the resource controls associated to the container, to its Swarm service and to its stack are checked. The endpointID and
+// swarmID parameters are used to resolve the resource controls of the stacks deployed on the endpoint.
+func CanAccessContainer(containerID string, labels map[string]string, resourceControls []portainer.ResourceControl,
+	endpointID portainer.EndpointID, swarmID string, userID portainer.UserID, memberships []portainer.TeamMembership) bool {
+
+	scopeStackResourceControls(resourceControls, endpointID, swarmID)
+
+	userTeamIDs := make([]portainer.TeamID, 0)
+	for _, membership := range memberships {
+		userTeamIDs = append(userTeamIDs, membership.TeamID)
+	}
+
+	resourceIdentifiers := []string{
+		containerID,
+		labels[containerLabelForServiceIdentifier],
+		labels[containerLabelForSwarmStackIdentifier],
+		labels[containerLabelForComposeStackIdentifier],
+	}
+
+	for _, resourceIdentifier := range resourceIdentifiers {
+		if resourceIdentifier == "" {
+			continue
+		}
+
+		resourceControl := getResourceControlByResourceID(resourceIdentifier, resourceControls)
+		if resourceControl != nil && !canUserAccessResource(userID, userTeamIDs, resourceControl) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // FilterStacks filters stacks based on user role and resource controls.
 func FilterStacks(stacks []portainer.Stack, resourceControls []portainer.ResourceControl, isAdmin bool,
 	userID portainer.UserID, memberships []portainer.TeamMembership) []ExtendedStack {
@@ -148,7 +222,7 @@ func FilterStacks(stacks []portainer.Stack, resourceControls []portainer.Resourc
 
 	for _, stack := range stacks {
 		extendedStack := ExtendedStack{stack, portainer.ResourceControl{}}
-		resourceControl := getResourceControlByResourceID(stack.Name, resourceControls)
+		resourceControl := getResourceControlByResourceID(StackResourceControlID(&stack), resourceControls)
 		if resourceControl == nil {
 			filteredStacks = append(filteredStacks, extendedStack)
 		} else if resourceControl != nil && (isAdmin || canUserAccessResource(userID, userTeamIDs, resourceControl)) {
@@ -3,49 +3,98 @@ package proxy
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"path"
+	"path/filepath"
 	"strings"
 
+	"github.com/portainer/portainer"
 	"github.com/portainer/portainer/archive"
 )
 
+const (
+	// ErrInvalidBuildContextPath defines an error raised when a file or directory of the build context is located outside of the context
+	ErrInvalidBuildContextPath = portainer.Error("Invalid build context path")
+	// ErrEmptyBuildContext defines an error raised when a multipart build request does not contain any file
+	ErrEmptyBuildContext = portainer.Error("Build context is empty")
+)
+
 type postDockerfileRequest struct {
-	Content string
+	Content                  string
+	RepositoryURL            string
+	RepositoryReferenceName  string
+	RepositoryAuthentication bool
+	RepositoryUsername       string
+	RepositoryPassword       string
+	RepositoryContextPath    string
+	Tags                     []string
+	BuildArgs                map[string]string
+	Target                   string
 }
 
 // buildOperation inspects the "Content-Type" header to determine if it needs to alter the request.
 // If the value of the header is empty, it means that a Dockerfile is posted via upload, the function
 // will extract the file content from the request body, tar it, and rewrite the body.
-// If the value of the header contains "application/json", it means that the content of a Dockerfile is posted
-// in the request payload as JSON, the function will create a new file called Dockerfile inside a tar archive and
-// rewrite the body of the request.
+// If the value of the header contains "application/json", the payload either contains the content of a Dockerfile,
+// in which case the function will create a new file called Dockerfile inside a tar archive, or the URL of a git
+// repository that will be cloned and streamed as the build context.
+// If the value of the header contains "multipart/form-data", the uploaded files are used as the build context.
+// A single uploaded tarball is used as is.
 // In any other case, it will leave the request unaltered.
-func buildOperation(request *http.Request) error {
+func (p *proxyTransport) buildOperation(request *http.Request) error {
 	contentTypeHeader := request.Header.Get("Content-Type")
-	if contentTypeHeader != "" && !strings.Contains(contentTypeHeader, "application/json") {
-		return nil
-	}
 
-	var dockerfileContent []byte
+	var buffer []byte
+	var err error
 
-	if contentTypeHeader == "" {
+	switch {
+	case contentTypeHeader == "":
 		body, err := ioutil.ReadAll(request.Body)
 		if err != nil {
 			return err
 		}
-		dockerfileContent = body
-	} else {
+
+		buffer, err = archive.TarFileInBuffer(body, "Dockerfile")
+		if err != nil {
+			return err
+		}
+	case strings.Contains(contentTypeHeader, "application/json"):
 		var req postDockerfileRequest
 		if err := json.NewDecoder(request.Body).Decode(&req); err != nil {
 			return err
 		}
-		dockerfileContent = []byte(req.Content)
-	}
 
-	buffer, err := archive.TarFileInBuffer(dockerfileContent, "Dockerfile")
-	if err != nil {
-		return err
+		applyBuildOptions(request, req.Tags, req.BuildArgs, req.Target)
+
+		if req.RepositoryURL != "" {
+			buildContext, err := p.buildContextFromRepository(&req)
+			if err != nil {
+				return err
+			}
+
+			// The length of the context is unknown, the request body is sent using chunked encoding
+			request.Body = buildContext
+			request.ContentLength = -1
+			request.Header.Set("Content-Type", "application/x-tar")
+			return nil
+		}
+
+		buffer, err = archive.TarFileInBuffer([]byte(req.Content), "Dockerfile")
+		if err != nil {
+			return err
+		}
+	case strings.Contains(contentTypeHeader, "multipart/form-data"):
+		buffer, err = buildContextFromMultipartForm(request)
+		if err != nil {
+			return err
+		}
+	default:
+		return nil
 	}
 
 	request.Body = ioutil.NopCloser(bytes.NewReader(buffer))
@@ -54,3 +103,162 @@ func buildOperation(request *http.Request) error {
 
 	return nil
 }
+
+// buildContextFromRepository clones a git repository and returns a reader streaming the build context
+// as a tar archive. The clone is removed once the context has been streamed or the reader is closed.
+func (p *proxyTransport) buildContextFromRepository(req *postDockerfileRequest) (io.ReadCloser, error) {
+	projectPath, err := ioutil.TempDir("", "portainer-build-")
+	if err != nil {
+		return nil, err
+	}
+
+	if req.RepositoryAuthentication {
+		err = p.GitService.ClonePrivateRepositoryWithBasicAuth(req.RepositoryURL, req.RepositoryReferenceName, projectPath, req.RepositoryUsername, req.RepositoryPassword)
+	} else {
+		err = p.GitService.ClonePublicRepository(req.RepositoryURL, req.RepositoryReferenceName, projectPath)
+	}
+	if err != nil {
+		os.RemoveAll(projectPath)
+		return nil, err
+	}
+
+	contextPath, err := resolveBuildContextPath(projectPath, req.RepositoryContextPath)
+	if err != nil {
+		os.RemoveAll(projectPath)
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		defer os.RemoveAll(projectPath)
+		writer.CloseWithError(archive.TarDirectory(writer, contextPath, []string{".git"}))
+	}()
+
+	return reader, nil
+}
+
+// resolveBuildContextPath returns the path of the build context inside a cloned repository.
+// Symbolic links are resolved before ensuring that the context is located inside the repository,
+// a repository could otherwise use a link to send any directory of the Portainer host to the endpoint.
+func resolveBuildContextPath(projectPath, contextPath string) (string, error) {
+	projectPath, err := filepath.EvalSymlinks(projectPath)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedPath, err := filepath.EvalSymlinks(filepath.Join(projectPath, filepath.FromSlash(contextPath)))
+	if err != nil {
+		return "", ErrInvalidBuildContextPath
+	}
+
+	if resolvedPath != projectPath && !strings.HasPrefix(resolvedPath, projectPath+string(filepath.Separator)) {
+		return "", ErrInvalidBuildContextPath
+	}
+
+	return resolvedPath, nil
+}
+
+func buildContextFromMultipartForm(request *http.Request) ([]byte, error) {
+	reader, err := request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	var tags []string
+	var buildArgs map[string]string
+	var target string
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		content, err := ioutil.ReadAll(part)
+		part.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		fileName := multipartFileName(part)
+		if fileName == "" {
+			switch part.FormName() {
+			case "Tags":
+				err = json.Unmarshal(content, &tags)
+			case "BuildArgs":
+				err = json.Unmarshal(content, &buildArgs)
+			case "Target":
+				target = string(content)
+			}
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		filePath := path.Clean("/" + fileName)[1:]
+		if filePath == "" {
+			return nil, ErrInvalidBuildContextPath
+		}
+		files[filePath] = content
+	}
+
+	applyBuildOptions(request, tags, buildArgs, target)
+
+	if len(files) == 0 {
+		return nil, ErrEmptyBuildContext
+	}
+
+	if len(files) == 1 {
+		for name, content := range files {
+			if isTarball(name) {
+				return content, nil
+			}
+		}
+	}
+
+	return archive.TarFilesInBuffer(files)
+}
+
+// multipartFileName returns the file name of a multipart part, including the relative path
+// sent by clients uploading a directory (multipart.Part.FileName only returns the base name).
+func multipartFileName(part *multipart.Part) string {
+	_, params, err := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+	return filepath.ToSlash(params["filename"])
+}
+
+func isTarball(fileName string) bool {
+	for _, extension := range []string{".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tar.xz"} {
+		if strings.HasSuffix(fileName, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBuildOptions translates the build options specified in the request payload into
+// the query parameters of the Docker build API.
+func applyBuildOptions(request *http.Request, tags []string, buildArgs map[string]string, target string) {
+	query := request.URL.Query()
+
+	for _, tag := range tags {
+		query.Add("t", tag)
+	}
+
+	if len(buildArgs) > 0 {
+		data, _ := json.Marshal(buildArgs)
+		query.Set("buildargs", string(data))
+	}
+
+	if target != "" {
+		query.Set("target", target)
+	}
+
+	request.URL.RawQuery = query.Encode()
+}
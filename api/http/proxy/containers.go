@@ -42,6 +42,10 @@ func containerListOperation(response *http.Response, executor *operationExecutor
 		}
 	}
 
+	if executor.imageUpdateReport != nil {
+		responseArray = decorateListWithImageUpdates(responseArray, containerIdentifier, executor.imageUpdateReport.Containers)
+	}
+
 	return rewriteResponse(response, responseArray, http.StatusOK)
 }
 
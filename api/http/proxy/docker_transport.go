@@ -3,27 +3,41 @@ package proxy
 import (
 	"encoding/base64"
 	"encoding/json"
+	"log"
 	"net/http"
 	"path"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/portainer/portainer"
 	"github.com/portainer/portainer/http/security"
+	"github.com/portainer/portainer/registry"
 )
 
 var apiVersionRe = regexp.MustCompile(`(/v[0-9]\.[0-9]*)?`)
 
+// swarmIdentifierCacheDuration is the duration during which the identifier of the Swarm cluster of an endpoint is cached.
+const swarmIdentifierCacheDuration = 5 * time.Minute
+
 type (
 	proxyTransport struct {
 		dockerTransport        *http.Transport
 		enableSignature        bool
+		endpointIdentifier     portainer.EndpointID
+		swarmIdentifier        string
+		swarmRetrievedAt       time.Time
+		swarmMutex             sync.Mutex
 		ResourceControlService portainer.ResourceControlService
 		TeamMembershipService  portainer.TeamMembershipService
 		RegistryService        portainer.RegistryService
 		DockerHubService       portainer.DockerHubService
 		SettingsService        portainer.SettingsService
 		SignatureService       portainer.DigitalSignatureService
+		CredentialsService     portainer.RegistryCredentialsService
+		ImageUpdateService     portainer.ImageUpdateService
+		GitService             portainer.GitService
 	}
 	restrictedOperationContext struct {
 		isAdmin          bool
@@ -44,8 +58,9 @@ type (
 		Serveraddress string `json:"serveraddress"`
 	}
 	operationExecutor struct {
-		operationContext *restrictedOperationContext
-		labelBlackList   []portainer.Pair
+		operationContext  *restrictedOperationContext
+		labelBlackList    []portainer.Pair
+		imageUpdateReport *portainer.ImageUpdateReport
 	}
 	restrictedOperationRequest func(*http.Response, *operationExecutor) error
 	operationRequest           func(*http.Request) error
@@ -96,6 +111,8 @@ func (p *proxyTransport) proxyDockerRequest(request *http.Request) (*http.Respon
 		return p.proxyBuildRequest(request)
 	case strings.HasPrefix(path, "/images"):
 		return p.proxyImageRequest(request)
+	case strings.HasPrefix(path, "/plugins"):
+		return p.proxyPluginRequest(request)
 	default:
 		return p.executeDockerRequest(request)
 	}
@@ -153,7 +170,11 @@ func (p *proxyTransport) proxyContainerRequest(request *http.Request) (*http.Res
 func (p *proxyTransport) proxyServiceRequest(request *http.Request) (*http.Response, error) {
 	switch requestPath := request.URL.Path; requestPath {
 	case "/services/create":
-		return p.replaceRegistryAuthenticationHeader(request)
+		image, err := extractServiceImage(request)
+		if err != nil {
+			return nil, err
+		}
+		return p.replaceRegistryAuthenticationHeader(request, image)
 
 	case "/services":
 		return p.rewriteOperation(request, serviceListOperation)
@@ -163,6 +184,10 @@ func (p *proxyTransport) proxyServiceRequest(request *http.Request) (*http.Respo
 		if match, _ := path.Match("/services/*/*", requestPath); match {
 			// Handle /services/{id}/{action} requests
 			serviceID := path.Base(path.Dir(requestPath))
+
+			if path.Base(requestPath) == "update" && request.Method == http.MethodPost {
+				return p.restrictedServiceUpdate(request, serviceID)
+			}
 			return p.restrictedOperation(request, serviceID)
 		} else if match, _ := path.Match("/services/*", requestPath); match {
 			// Handle /services/{id} requests
@@ -266,90 +291,136 @@ func (p *proxyTransport) proxyTaskRequest(request *http.Request) (*http.Response
 }
 
 func (p *proxyTransport) proxyBuildRequest(request *http.Request) (*http.Response, error) {
-	return p.interceptAndRewriteRequest(request, buildOperation)
+	return p.interceptAndRewriteRequest(request, p.buildOperation)
 }
 
 func (p *proxyTransport) proxyImageRequest(request *http.Request) (*http.Response, error) {
 	switch requestPath := request.URL.Path; requestPath {
 	case "/images/create":
-		return p.replaceRegistryAuthenticationHeader(request)
+		image := request.URL.Query().Get("fromImage")
+		if image == "" {
+			// Import from a tarball or a URL
+			return p.executeDockerRequest(request)
+		}
+		return p.replaceRegistryAuthenticationHeader(request, image)
 	default:
 		if path.Base(requestPath) == "push" && request.Method == http.MethodPost {
-			return p.replaceRegistryAuthenticationHeader(request)
+			image := strings.TrimSuffix(strings.TrimPrefix(requestPath, "/images/"), "/push")
+			return p.replaceRegistryAuthenticationHeader(request, image)
 		}
 		return p.executeDockerRequest(request)
 	}
 }
 
-func (p *proxyTransport) replaceRegistryAuthenticationHeader(request *http.Request) (*http.Response, error) {
+func (p *proxyTransport) proxyPluginRequest(request *http.Request) (*http.Response, error) {
+	switch requestPath := request.URL.Path; requestPath {
+	case "/plugins/pull":
+		return p.replaceRegistryAuthenticationHeader(request, request.URL.Query().Get("remote"))
+	default:
+		return p.executeDockerRequest(request)
+	}
+}
+
+// replaceRegistryAuthenticationHeader resolves the registry hosting the image from the image reference
+// and replaces the X-Registry-Auth header with the credentials of this registry.
+func (p *proxyTransport) replaceRegistryAuthenticationHeader(request *http.Request, image string) (*http.Response, error) {
+	// Let the Docker engine report invalid or missing image references
+	if _, err := registry.ImageDomain(image); err != nil {
+		request.Header.Del("X-Registry-Auth")
+		return p.executeDockerRequest(request)
+	}
+
 	accessContext, err := p.createRegistryAccessContext(request)
 	if err != nil {
 		return nil, err
 	}
 
-	originalHeader := request.Header.Get("X-Registry-Auth")
-
-	if originalHeader != "" {
+	authenticationHeader, err := createRegistryAuthenticationHeader(image, accessContext, p.CredentialsService)
+	if err == portainer.ErrRegistryAccessDenied {
+		domain, _ := registry.ImageDomain(image)
+		return writeErrorResponse(http.StatusForbidden, portainer.ErrRegistryAccessDenied.Error()+": "+domain)
+	} else if err != nil {
+		return nil, err
+	}
 
-		decodedHeaderData, err := base64.StdEncoding.DecodeString(originalHeader)
-		if err != nil {
-			return nil, err
-		}
+	headerData, err := json.Marshal(authenticationHeader)
+	if err != nil {
+		return nil, err
+	}
 
-		var originalHeaderData registryAuthenticationHeader
-		err = json.Unmarshal(decodedHeaderData, &originalHeaderData)
-		if err != nil {
-			return nil, err
-		}
+	header := base64.StdEncoding.EncodeToString(headerData)
 
-		authenticationHeader := createRegistryAuthenticationHeader(originalHeaderData.Serveraddress, accessContext)
+	request.Header.Set("X-Registry-Auth", header)
 
-		headerData, err := json.Marshal(authenticationHeader)
-		if err != nil {
-			return nil, err
-		}
+	return p.executeDockerRequest(request)
+}
 
-		header := base64.StdEncoding.EncodeToString(headerData)
+// restrictedOperation ensures that the current user has the required authorizations
+// before executing the original request.
+func (p *proxyTransport) restrictedOperation(request *http.Request, resourceID string) (*http.Response, error) {
+	access, err := p.hasResourceAccess(request, resourceID)
+	if err != nil {
+		return nil, err
+	}
 
-		request.Header.Set("X-Registry-Auth", header)
+	if !access {
+		return writeAccessDeniedResponse()
 	}
 
 	return p.executeDockerRequest(request)
 }
 
-// restrictedOperation ensures that the current user has the required authorizations
-// before executing the original request.
-func (p *proxyTransport) restrictedOperation(request *http.Request, resourceID string) (*http.Response, error) {
-	var err error
-	tokenData, err := security.RetrieveTokenData(request)
+// restrictedServiceUpdate ensures that the current user has the required authorizations
+// before updating the service with the credentials of the registry hosting the service image.
+func (p *proxyTransport) restrictedServiceUpdate(request *http.Request, serviceID string) (*http.Response, error) {
+	access, err := p.hasResourceAccess(request, serviceID)
 	if err != nil {
 		return nil, err
 	}
 
-	if tokenData.Role != portainer.AdministratorRole {
+	if !access {
+		return writeAccessDeniedResponse()
+	}
 
-		teamMemberships, err := p.TeamMembershipService.TeamMembershipsByUserID(tokenData.ID)
-		if err != nil {
-			return nil, err
-		}
+	image, err := extractServiceImage(request)
+	if err != nil {
+		return nil, err
+	}
 
-		userTeamIDs := make([]portainer.TeamID, 0)
-		for _, membership := range teamMemberships {
-			userTeamIDs = append(userTeamIDs, membership.TeamID)
-		}
+	return p.replaceRegistryAuthenticationHeader(request, image)
+}
 
-		resourceControls, err := p.ResourceControlService.ResourceControls()
-		if err != nil {
-			return nil, err
-		}
+func (p *proxyTransport) hasResourceAccess(request *http.Request, resourceID string) (bool, error) {
+	tokenData, err := security.RetrieveTokenData(request)
+	if err != nil {
+		return false, err
+	}
 
-		resourceControl := getResourceControlByResourceID(resourceID, resourceControls)
-		if resourceControl != nil && !canUserAccessResource(tokenData.ID, userTeamIDs, resourceControl) {
-			return writeAccessDeniedResponse()
-		}
+	if tokenData.Role == portainer.AdministratorRole {
+		return true, nil
 	}
 
-	return p.executeDockerRequest(request)
+	teamMemberships, err := p.TeamMembershipService.TeamMembershipsByUserID(tokenData.ID)
+	if err != nil {
+		return false, err
+	}
+
+	userTeamIDs := make([]portainer.TeamID, 0)
+	for _, membership := range teamMemberships {
+		userTeamIDs = append(userTeamIDs, membership.TeamID)
+	}
+
+	resourceControls, err := p.ResourceControlService.ResourceControls()
+	if err != nil {
+		return false, err
+	}
+
+	resourceControl := getResourceControlByResourceID(resourceID, resourceControls)
+	if resourceControl != nil && !canUserAccessResource(tokenData.ID, userTeamIDs, resourceControl) {
+		return false, nil
+	}
+
+	return true, nil
 }
 
 // rewriteOperationWithLabelFiltering will create a new operation context with data that will be used
@@ -367,8 +438,9 @@ func (p *proxyTransport) rewriteOperationWithLabelFiltering(request *http.Reques
 	}
 
 	executor := &operationExecutor{
-		operationContext: operationContext,
-		labelBlackList:   settings.BlackListedLabels,
+		operationContext:  operationContext,
+		labelBlackList:    settings.BlackListedLabels,
+		imageUpdateReport: p.ImageUpdateService.EndpointReport(p.endpointIdentifier),
 	}
 
 	return p.executeRequestAndRewriteResponse(request, operation, executor)
@@ -383,7 +455,8 @@ func (p *proxyTransport) rewriteOperation(request *http.Request, operation restr
 	}
 
 	executor := &operationExecutor{
-		operationContext: operationContext,
+		operationContext:  operationContext,
+		imageUpdateReport: p.ImageUpdateService.EndpointReport(p.endpointIdentifier),
 	}
 
 	return p.executeRequestAndRewriteResponse(request, operation, executor)
@@ -472,6 +545,12 @@ func (p *proxyTransport) createOperationContext(request *http.Request) (*restric
 		return nil, err
 	}
 
+	swarmID, err := p.retrieveSwarmIdentifier(request)
+	if err != nil {
+		log.Printf("http error: Unable to retrieve the Swarm identifier of the endpoint, only the endpoint stack resource controls are used (endpoint=%d) (err=%s)\n", p.endpointIdentifier, err)
+	}
+	scopeStackResourceControls(resourceControls, p.endpointIdentifier, swarmID)
+
 	operationContext := &restrictedOperationContext{
 		isAdmin:          true,
 		userID:           tokenData.ID,
@@ -495,3 +574,57 @@ func (p *proxyTransport) createOperationContext(request *http.Request) (*restric
 
 	return operationContext, nil
 }
+
+// retrieveSwarmIdentifier returns the identifier of the Swarm cluster the endpoint is part of.
+// An empty identifier is returned when the endpoint is not part of a Swarm cluster or when the identifier
+// cannot be retrieved. The identifier is cached for swarmIdentifierCacheDuration, failures are not cached.
+// The proxy, and therefore the cache, is recreated when the endpoint is updated.
+func (p *proxyTransport) retrieveSwarmIdentifier(request *http.Request) (string, error) {
+	p.swarmMutex.Lock()
+	defer p.swarmMutex.Unlock()
+
+	if time.Since(p.swarmRetrievedAt) < swarmIdentifierCacheDuration {
+		return p.swarmIdentifier, nil
+	}
+
+	infoURL := *request.URL
+	infoURL.Path = "/info"
+	infoURL.RawQuery = ""
+
+	infoRequest, err := http.NewRequest(http.MethodGet, infoURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	infoRequest.Header.Set(portainer.PortainerAgentPublicKeyHeader, request.Header.Get(portainer.PortainerAgentPublicKeyHeader))
+	infoRequest.Header.Set(portainer.PortainerAgentSignatureHeader, request.Header.Get(portainer.PortainerAgentSignatureHeader))
+
+	response, err := p.executeDockerRequest(infoRequest)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", portainer.Error("Unable to retrieve Docker information, received " + response.Status)
+	}
+
+	var info struct {
+		Swarm struct {
+			Cluster *struct {
+				ID string
+			}
+		}
+	}
+	err = json.NewDecoder(response.Body).Decode(&info)
+	if err != nil {
+		return "", err
+	}
+
+	p.swarmIdentifier = ""
+	if info.Swarm.Cluster != nil {
+		p.swarmIdentifier = info.Swarm.Cluster.ID
+	}
+	p.swarmRetrievedAt = time.Now()
+
+	return p.swarmIdentifier, nil
+}
@@ -5,13 +5,17 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"time"
 
 	"github.com/portainer/portainer"
 	"github.com/portainer/portainer/crypto"
 )
 
-// AzureAPIBaseURL is the URL where Azure API requests will be proxied.
-const AzureAPIBaseURL = "https://management.azure.com"
+const (
+	// AzureAPIBaseURL is the URL where Azure API requests will be proxied.
+	AzureAPIBaseURL             = "https://management.azure.com"
+	dockerResponseFlushInterval = 100 * time.Millisecond
+)
 
 // proxyFactory is a factory to create reverse proxies to Docker endpoints
 type proxyFactory struct {
@@ -21,6 +25,9 @@ type proxyFactory struct {
 	RegistryService        portainer.RegistryService
 	DockerHubService       portainer.DockerHubService
 	SignatureService       portainer.DigitalSignatureService
+	CredentialsService     portainer.RegistryCredentialsService
+	ImageUpdateService     portainer.ImageUpdateService
+	GitService             portainer.GitService
 }
 
 func (factory *proxyFactory) newHTTPProxy(u *url.URL) http.Handler {
@@ -40,10 +47,10 @@ func newAzureProxy(credentials *portainer.AzureCredentials) (http.Handler, error
 	return proxy, nil
 }
 
-func (factory *proxyFactory) newDockerHTTPSProxy(u *url.URL, tlsConfig *portainer.TLSConfiguration, enableSignature bool) (http.Handler, error) {
+func (factory *proxyFactory) newDockerHTTPSProxy(u *url.URL, tlsConfig *portainer.TLSConfiguration, enableSignature bool, endpointID portainer.EndpointID) (http.Handler, error) {
 	u.Scheme = "https"
 
-	proxy := factory.createDockerReverseProxy(u, enableSignature)
+	proxy := factory.createDockerReverseProxy(u, enableSignature, endpointID)
 	config, err := crypto.CreateTLSConfigurationFromDisk(tlsConfig.TLSCACertPath, tlsConfig.TLSCertPath, tlsConfig.TLSKeyPath, tlsConfig.TLSSkipVerify)
 	if err != nil {
 		return nil, err
@@ -53,20 +60,26 @@ func (factory *proxyFactory) newDockerHTTPSProxy(u *url.URL, tlsConfig *portaine
 	return proxy, nil
 }
 
-func (factory *proxyFactory) newDockerHTTPProxy(u *url.URL, enableSignature bool) http.Handler {
+func (factory *proxyFactory) newDockerHTTPProxy(u *url.URL, enableSignature bool, endpointID portainer.EndpointID) http.Handler {
 	u.Scheme = "http"
-	return factory.createDockerReverseProxy(u, enableSignature)
+	return factory.createDockerReverseProxy(u, enableSignature, endpointID)
 }
 
-func (factory *proxyFactory) createDockerReverseProxy(u *url.URL, enableSignature bool) *httputil.ReverseProxy {
+func (factory *proxyFactory) createDockerReverseProxy(u *url.URL, enableSignature bool, endpointID portainer.EndpointID) *httputil.ReverseProxy {
 	proxy := newSingleHostReverseProxyWithHostHeader(u)
+	// Periodically flush streamed responses such as build or pull progress
+	proxy.FlushInterval = dockerResponseFlushInterval
 	transport := &proxyTransport{
 		enableSignature:        enableSignature,
+		endpointIdentifier:     endpointID,
 		ResourceControlService: factory.ResourceControlService,
 		TeamMembershipService:  factory.TeamMembershipService,
 		SettingsService:        factory.SettingsService,
 		RegistryService:        factory.RegistryService,
 		DockerHubService:       factory.DockerHubService,
+		CredentialsService:     factory.CredentialsService,
+		ImageUpdateService:     factory.ImageUpdateService,
+		GitService:             factory.GitService,
 		dockerTransport:        &http.Transport{},
 	}
 
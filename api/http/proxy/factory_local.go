@@ -4,17 +4,23 @@ package proxy
 
 import (
 	"net/http"
+
+	"github.com/portainer/portainer"
 )
 
-func (factory *proxyFactory) newLocalProxy(path string) http.Handler {
+func (factory *proxyFactory) newLocalProxy(path string, endpointID portainer.EndpointID) http.Handler {
 	proxy := &localProxy{}
 	transport := &proxyTransport{
 		enableSignature:        false,
+		endpointIdentifier:     endpointID,
 		ResourceControlService: factory.ResourceControlService,
 		TeamMembershipService:  factory.TeamMembershipService,
 		SettingsService:        factory.SettingsService,
 		RegistryService:        factory.RegistryService,
 		DockerHubService:       factory.DockerHubService,
+		CredentialsService:     factory.CredentialsService,
+		ImageUpdateService:     factory.ImageUpdateService,
+		GitService:             factory.GitService,
 		dockerTransport:        newSocketTransport(path),
 	}
 	proxy.Transport = transport
@@ -7,17 +7,22 @@ import (
 	"net/http"
 
 	"github.com/Microsoft/go-winio"
+	"github.com/portainer/portainer"
 )
 
-func (factory *proxyFactory) newLocalProxy(path string) http.Handler {
+func (factory *proxyFactory) newLocalProxy(path string, endpointID portainer.EndpointID) http.Handler {
 	proxy := &localProxy{}
 	transport := &proxyTransport{
 		enableSignature:        false,
+		endpointIdentifier:     endpointID,
 		ResourceControlService: factory.ResourceControlService,
 		TeamMembershipService:  factory.TeamMembershipService,
 		SettingsService:        factory.SettingsService,
 		RegistryService:        factory.RegistryService,
 		DockerHubService:       factory.DockerHubService,
+		CredentialsService:     factory.CredentialsService,
+		ImageUpdateService:     factory.ImageUpdateService,
+		GitService:             factory.GitService,
 		dockerTransport:        newNamedPipeTransport(path),
 	}
 	proxy.Transport = transport
@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"github.com/portainer/portainer"
+)
+
+// decorateListWithImageUpdates adds the UpdateAvailable flag computed by the image update job inside
+// the Portainer metadata of each resource. Resources that are not part of the report are left untouched.
+func decorateListWithImageUpdates(resourceData []interface{}, resourceIdentifier string, statuses []portainer.ImageUpdateStatus) []interface{} {
+	if len(statuses) == 0 {
+		return resourceData
+	}
+
+	updates := make(map[string]bool)
+	for _, status := range statuses {
+		updates[status.ID] = status.UpdateAvailable
+	}
+
+	for _, resource := range resourceData {
+		resourceObject := resource.(map[string]interface{})
+
+		resourceID, ok := resourceObject[resourceIdentifier].(string)
+		if !ok {
+			continue
+		}
+
+		updateAvailable, ok := updates[resourceID]
+		if !ok {
+			continue
+		}
+
+		if resourceObject["Portainer"] == nil {
+			resourceObject["Portainer"] = make(map[string]interface{})
+		}
+		portainerMetadata := resourceObject["Portainer"].(map[string]interface{})
+		portainerMetadata["ImageUpdateAvailable"] = updateAvailable
+	}
+
+	return resourceData
+}
@@ -37,7 +37,28 @@ func (proxy *localProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(res.StatusCode)
 
-	if _, err := io.Copy(w, res.Body); err != nil {
+	if _, err := io.Copy(newFlushWriter(w), res.Body); err != nil {
 		log.Printf("proxy error: %s\n", err)
 	}
 }
+
+// flushWriter flushes the response after each write so that streamed responses
+// (e.g. build or pull progress) are sent to the client as soon as they are received.
+type flushWriter struct {
+	writer  io.Writer
+	flusher http.Flusher
+}
+
+func newFlushWriter(w http.ResponseWriter) io.Writer {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return w
+	}
+	return &flushWriter{writer: w, flusher: flusher}
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.writer.Write(p)
+	fw.flusher.Flush()
+	return n, err
+}
@@ -1,37 +1,103 @@
 package proxy
 
 import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
 	"github.com/portainer/portainer"
 	"github.com/portainer/portainer/http/security"
+	"github.com/portainer/portainer/registry"
 )
 
-func createRegistryAuthenticationHeader(serverAddress string, accessContext *registryAccessContext) *registryAuthenticationHeader {
-	var authenticationHeader *registryAuthenticationHeader
-
-	if serverAddress == "" {
-		authenticationHeader = &registryAuthenticationHeader{
-			Username:      accessContext.dockerHub.Username,
-			Password:      accessContext.dockerHub.Password,
-			Serveraddress: "docker.io",
+type serviceSpecImage struct {
+	TaskTemplate struct {
+		ContainerSpec struct {
+			Image string
 		}
-	} else {
-		var matchingRegistry *portainer.Registry
-		for _, registry := range accessContext.registries {
-			if registry.URL == serverAddress &&
-				(accessContext.isAdmin || (!accessContext.isAdmin && security.AuthorizedRegistryAccess(&registry, accessContext.userID, accessContext.teamMemberships))) {
-				matchingRegistry = &registry
-				break
+	}
+}
+
+// createRegistryAuthenticationHeader resolves the registry hosting the specified image and returns the
+// authentication header associated to this registry. Registries not authorized for the user are ignored and
+// ErrRegistryAccessDenied is returned when the image is only hosted on such registries. Images hosted on an
+// unknown registry are pulled anonymously, except Docker Hub images that use the Docker Hub credentials.
+func createRegistryAuthenticationHeader(image string, accessContext *registryAccessContext, credentialsService portainer.RegistryCredentialsService) (*registryAuthenticationHeader, error) {
+	domain, err := registry.ImageDomain(image)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizedRegistries := accessContext.registries
+	if !accessContext.isAdmin {
+		authorizedRegistries = make([]portainer.Registry, 0)
+		for _, r := range accessContext.registries {
+			if security.AuthorizedRegistryAccess(&r, accessContext.userID, accessContext.teamMemberships) {
+				authorizedRegistries = append(authorizedRegistries, r)
 			}
 		}
+	}
 
-		if matchingRegistry != nil {
-			authenticationHeader = &registryAuthenticationHeader{
-				Username:      matchingRegistry.Username,
-				Password:      matchingRegistry.Password,
-				Serveraddress: matchingRegistry.URL,
-			}
+	matchingRegistry, err := registry.MatchImageRegistry(image, authorizedRegistries)
+	if err != nil {
+		return nil, err
+	}
+
+	if matchingRegistry != nil {
+		credentials, err := credentialsService.Credentials(matchingRegistry)
+		if err != nil {
+			return nil, err
 		}
+
+		return &registryAuthenticationHeader{
+			Username:      credentials.Username,
+			Password:      credentials.Password,
+			Serveraddress: domain,
+		}, nil
+	}
+
+	restrictedRegistry, err := registry.MatchImageRegistry(image, accessContext.registries)
+	if err != nil {
+		return nil, err
+	}
+
+	if restrictedRegistry != nil {
+		return nil, portainer.ErrRegistryAccessDenied
+	}
+
+	authenticationHeader := &registryAuthenticationHeader{
+		Serveraddress: domain,
+	}
+
+	if registry.IsDockerHubImage(image) && accessContext.dockerHub.Authentication {
+		authenticationHeader.Username = accessContext.dockerHub.Username
+		authenticationHeader.Password = accessContext.dockerHub.Password
+	}
+
+	return authenticationHeader, nil
+}
+
+// extractServiceImage returns the image specified in a service create or update request body.
+// The body is restored so that the request can be forwarded. A body that cannot be decoded is
+// forwarded as is, so that the Docker engine reports the validation error.
+func extractServiceImage(request *http.Request) (string, error) {
+	if request.Body == nil {
+		return "", nil
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return "", err
+	}
+	request.Body.Close()
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var spec serviceSpecImage
+	err = json.Unmarshal(body, &spec)
+	if err != nil {
+		return "", nil
 	}
 
-	return authenticationHeader
+	return spec.TaskTemplate.ContainerSpec.Image, nil
 }
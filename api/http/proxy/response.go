@@ -86,6 +86,14 @@ func writeAccessDeniedResponse() (*http.Response, error) {
 	return response, err
 }
 
+// writeErrorResponse writes an error using the format of the Docker API errors so that it can be
+// displayed by Docker clients.
+func writeErrorResponse(statusCode int, message string) (*http.Response, error) {
+	response := &http.Response{}
+	err := rewriteResponse(response, map[string]string{"message": message}, statusCode)
+	return response, err
+}
+
 func rewriteAccessDeniedResponse(response *http.Response) error {
 	return rewriteResponse(response, portainer.ErrResourceAccessDenied, http.StatusForbidden)
 }
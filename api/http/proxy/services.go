@@ -33,6 +33,10 @@ func serviceListOperation(response *http.Response, executor *operationExecutor)
 		return err
 	}
 
+	if executor.imageUpdateReport != nil {
+		responseArray = decorateListWithImageUpdates(responseArray, serviceIdentifier, executor.imageUpdateReport.Services)
+	}
+
 	return rewriteResponse(response, responseArray, http.StatusOK)
 }
 
@@ -191,6 +191,30 @@ func AuthorizedRegistryAccess(registry *portainer.Registry, userID portainer.Use
 	return authorizedAccess(userID, memberships, registry.AuthorizedUsers, registry.AuthorizedTeams)
 }
 
+// AuthorizedTemplateAccess ensure that the user can access the specified template.
+// Templates without authorized users and teams are available to every user, otherwise it will check
+// if the user is part of the authorized users or part of a team that is listed in the authorized teams.
+func AuthorizedTemplateAccess(template *portainer.Template, userID portainer.UserID, memberships []portainer.TeamMembership) bool {
+	if len(template.AuthorizedUsers) == 0 && len(template.AuthorizedTeams) == 0 {
+		return true
+	}
+	return authorizedAccess(userID, memberships, template.AuthorizedUsers, template.AuthorizedTeams)
+}
+
+// AuthorizedTemplateEndpoint ensure that the specified template can be used on the endpoint.
+// Templates without endpoint groups can be used on every endpoint.
+func AuthorizedTemplateEndpoint(template *portainer.Template, endpoint *portainer.Endpoint) bool {
+	if len(template.EndpointGroups) == 0 {
+		return true
+	}
+	for _, groupID := range template.EndpointGroups {
+		if groupID == endpoint.GroupID {
+			return true
+		}
+	}
+	return false
+}
+
 func authorizedAccess(userID portainer.UserID, memberships []portainer.TeamMembership, authorizedUsers []portainer.UserID, authorizedTeams []portainer.TeamID) bool {
 	for _, authorizedUserID := range authorizedUsers {
 		if authorizedUserID == userID {
@@ -206,3 +230,17 @@ func authorizedAccess(userID portainer.UserID, memberships []portainer.TeamMembe
 	}
 	return false
 }
+
+// AuthorizedEnvironmentSetEndpoint ensure that the specified environment set can be used by the stacks of the endpoint.
+// Global environment sets can be used on every endpoint.
+func AuthorizedEnvironmentSetEndpoint(set *portainer.EnvironmentSet, endpoint *portainer.Endpoint) bool {
+	switch set.Scope {
+	case portainer.GlobalEnvironmentSet:
+		return true
+	case portainer.EndpointGroupEnvironmentSet:
+		return set.EndpointGroupID == endpoint.GroupID
+	case portainer.EndpointEnvironmentSet:
+		return set.EndpointID == endpoint.ID
+	}
+	return false
+}
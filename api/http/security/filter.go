@@ -77,8 +77,9 @@ func FilterRegistries(registries []portainer.Registry, context *RestrictedReques
 	return filteredRegistries
 }
 
-// FilterTemplates filters templates based on the user role.
-// Non-administrato template do not have access to templates where the AdministratorOnly flag is set to true.
+// FilterTemplates filters templates based on the user role and team memberships.
+// Non-administrator users do not have access to templates where the AdministratorOnly flag is set to true
+// and only have access to authorized templates.
 func FilterTemplates(templates []portainer.Template, context *RestrictedRequestContext) []portainer.Template {
 	filteredTemplates := templates
 
@@ -86,7 +87,7 @@ func FilterTemplates(templates []portainer.Template, context *RestrictedRequestC
 		filteredTemplates = make([]portainer.Template, 0)
 
 		for _, template := range templates {
-			if !template.AdministratorOnly {
+			if !template.AdministratorOnly && AuthorizedTemplateAccess(&template, context.UserID, context.UserMemberships) {
 				filteredTemplates = append(filteredTemplates, template)
 			}
 		}
@@ -95,6 +96,19 @@ func FilterTemplates(templates []portainer.Template, context *RestrictedRequestC
 	return filteredTemplates
 }
 
+// FilterTemplatesByEndpoint filters templates based on the group of the endpoint.
+func FilterTemplatesByEndpoint(templates []portainer.Template, endpoint *portainer.Endpoint) []portainer.Template {
+	filteredTemplates := make([]portainer.Template, 0)
+
+	for _, template := range templates {
+		if AuthorizedTemplateEndpoint(&template, endpoint) {
+			filteredTemplates = append(filteredTemplates, template)
+		}
+	}
+
+	return filteredTemplates
+}
+
 // FilterEndpoints filters endpoints based on user role and team memberships.
 // Non administrator users only have access to authorized endpoints (can be inherited via endoint groups).
 func FilterEndpoints(endpoints []portainer.Endpoint, groups []portainer.EndpointGroup, context *RestrictedRequestContext) []portainer.Endpoint {
@@ -133,6 +147,43 @@ func FilterEndpointGroups(endpointGroups []portainer.EndpointGroup, context *Res
 	return filteredEndpointGroups
 }
 
+// FilterEnvironmentSets filters environment sets based on user role and team memberships.
+// Non administrator users only have access to global environment sets and to the environment sets
+// of the endpoint groups and endpoints they are authorized to access.
+func FilterEnvironmentSets(sets []portainer.EnvironmentSet, endpoints []portainer.Endpoint, groups []portainer.EndpointGroup, context *RestrictedRequestContext) []portainer.EnvironmentSet {
+	filteredSets := sets
+
+	if !context.IsAdmin {
+		filteredSets = make([]portainer.EnvironmentSet, 0)
+
+		authorizedEndpoints := FilterEndpoints(endpoints, groups, context)
+		authorizedGroups := FilterEndpointGroups(groups, context)
+
+		for _, set := range sets {
+			switch set.Scope {
+			case portainer.GlobalEnvironmentSet:
+				filteredSets = append(filteredSets, set)
+			case portainer.EndpointGroupEnvironmentSet:
+				for _, group := range authorizedGroups {
+					if group.ID == set.EndpointGroupID {
+						filteredSets = append(filteredSets, set)
+						break
+					}
+				}
+			case portainer.EndpointEnvironmentSet:
+				for _, endpoint := range authorizedEndpoints {
+					if endpoint.ID == set.EndpointID {
+						filteredSets = append(filteredSets, set)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return filteredSets
+}
+
 func getAssociatedGroup(endpoint *portainer.Endpoint, groups []portainer.EndpointGroup) *portainer.EndpointGroup {
 	for _, group := range groups {
 		if group.ID == endpoint.GroupID {
@@ -4,15 +4,21 @@ import (
 	"time"
 
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/docker"
 	"github.com/portainer/portainer/http/handler"
 	"github.com/portainer/portainer/http/handler/auth"
+	"github.com/portainer/portainer/http/handler/cleanuppolicies"
 	"github.com/portainer/portainer/http/handler/dockerhub"
 	"github.com/portainer/portainer/http/handler/endpointgroups"
 	"github.com/portainer/portainer/http/handler/endpointproxy"
 	"github.com/portainer/portainer/http/handler/endpoints"
+	"github.com/portainer/portainer/http/handler/environmentsets"
 	"github.com/portainer/portainer/http/handler/file"
+	"github.com/portainer/portainer/http/handler/jobs"
 	"github.com/portainer/portainer/http/handler/registries"
 	"github.com/portainer/portainer/http/handler/resourcecontrols"
+	"github.com/portainer/portainer/http/handler/schedules"
+	"github.com/portainer/portainer/http/handler/sessionrecordings"
 	"github.com/portainer/portainer/http/handler/settings"
 	"github.com/portainer/portainer/http/handler/stacks"
 	"github.com/portainer/portainer/http/handler/status"
@@ -32,37 +38,52 @@ import (
 
 // Server implements the portainer.Server interface
 type Server struct {
-	BindAddress            string
-	AssetsPath             string
-	AuthDisabled           bool
-	EndpointManagement     bool
-	Status                 *portainer.Status
-	ComposeStackManager    portainer.ComposeStackManager
-	CryptoService          portainer.CryptoService
-	SignatureService       portainer.DigitalSignatureService
-	JobScheduler           portainer.JobScheduler
-	Snapshotter            portainer.Snapshotter
-	DockerHubService       portainer.DockerHubService
-	EndpointService        portainer.EndpointService
-	EndpointGroupService   portainer.EndpointGroupService
-	FileService            portainer.FileService
-	GitService             portainer.GitService
-	JWTService             portainer.JWTService
-	LDAPService            portainer.LDAPService
-	RegistryService        portainer.RegistryService
-	ResourceControlService portainer.ResourceControlService
-	SettingsService        portainer.SettingsService
-	StackService           portainer.StackService
-	SwarmStackManager      portainer.SwarmStackManager
-	TagService             portainer.TagService
-	TeamService            portainer.TeamService
-	TeamMembershipService  portainer.TeamMembershipService
-	TemplateService        portainer.TemplateService
-	UserService            portainer.UserService
-	Handler                *handler.Handler
-	SSL                    bool
-	SSLCert                string
-	SSLKey                 string
+	BindAddress              string
+	AssetsPath               string
+	AuthDisabled             bool
+	EndpointManagement       bool
+	Status                   *portainer.Status
+	CleanupPolicyService     portainer.CleanupPolicyService
+	CleanupRunService        portainer.CleanupRunService
+	EndpointCleaner          portainer.EndpointCleaner
+	ComposeStackManager      portainer.ComposeStackManager
+	CredentialsService       portainer.RegistryCredentialsService
+	ConnectivityService      portainer.RegistryConnectivityService
+	ImageUpdateService       portainer.ImageUpdateService
+	DockerClientFactory      *docker.ClientFactory
+	CryptoService            portainer.CryptoService
+	EncryptionService        portainer.EncryptionService
+	SignatureService         portainer.DigitalSignatureService
+	JobScheduler             portainer.JobScheduler
+	Snapshotter              portainer.Snapshotter
+	DockerHubService         portainer.DockerHubService
+	EndpointService          portainer.EndpointService
+	EndpointGroupService     portainer.EndpointGroupService
+	EnvironmentSetService    portainer.EnvironmentSetService
+	FileService              portainer.FileService
+	GitService               portainer.GitService
+	JWTService               portainer.JWTService
+	LDAPService              portainer.LDAPService
+	RegistryService          portainer.RegistryService
+	ResourceControlService   portainer.ResourceControlService
+	ScheduleService          portainer.ScheduleService
+	ScheduleExecutionService portainer.ScheduleExecutionService
+	ScheduleRunner           portainer.ScheduleRunner
+	SessionRecordingService  portainer.SessionRecordingService
+	SettingsService          portainer.SettingsService
+	StackService             portainer.StackService
+	StackJobService          portainer.StackJobService
+	SwarmStackManager        portainer.SwarmStackManager
+	TagService               portainer.TagService
+	TeamService              portainer.TeamService
+	TeamMembershipService    portainer.TeamMembershipService
+	TemplateService          portainer.TemplateService
+	TemplateSourceService    portainer.TemplateSourceService
+	UserService              portainer.UserService
+	Handler                  *handler.Handler
+	SSL                      bool
+	SSLCert                  string
+	SSLKey                   string
 }
 
 // Start starts the HTTP server
@@ -82,6 +103,9 @@ func (server *Server) Start() error {
 		RegistryService:        server.RegistryService,
 		DockerHubService:       server.DockerHubService,
 		SignatureService:       server.SignatureService,
+		CredentialsService:     server.CredentialsService,
+		ImageUpdateService:     server.ImageUpdateService,
+		GitService:             server.GitService,
 	}
 	proxyManager := proxy.NewManager(proxyManagerParameters)
 	rateLimiter := security.NewRateLimiter(10, 1*time.Second, 1*time.Hour)
@@ -95,6 +119,14 @@ func (server *Server) Start() error {
 	authHandler.TeamService = server.TeamService
 	authHandler.TeamMembershipService = server.TeamMembershipService
 
+	var cleanupPolicyHandler = cleanuppolicies.NewHandler(requestBouncer)
+	cleanupPolicyHandler.CleanupPolicyService = server.CleanupPolicyService
+	cleanupPolicyHandler.CleanupRunService = server.CleanupRunService
+	cleanupPolicyHandler.EndpointCleaner = server.EndpointCleaner
+	cleanupPolicyHandler.EndpointService = server.EndpointService
+	cleanupPolicyHandler.EndpointGroupService = server.EndpointGroupService
+	cleanupPolicyHandler.JobScheduler = server.JobScheduler
+
 	var dockerHubHandler = dockerhub.NewHandler(requestBouncer)
 	dockerHubHandler.DockerHubService = server.DockerHubService
 
@@ -104,6 +136,7 @@ func (server *Server) Start() error {
 	endpointHandler.FileService = server.FileService
 	endpointHandler.ProxyManager = proxyManager
 	endpointHandler.Snapshotter = server.Snapshotter
+	endpointHandler.ImageUpdateService = server.ImageUpdateService
 
 	var endpointGroupHandler = endpointgroups.NewHandler(requestBouncer)
 	endpointGroupHandler.EndpointGroupService = server.EndpointGroupService
@@ -113,13 +146,37 @@ func (server *Server) Start() error {
 	endpointProxyHandler.EndpointService = server.EndpointService
 	endpointProxyHandler.ProxyManager = proxyManager
 
+	var environmentSetHandler = environmentsets.NewHandler(requestBouncer)
+	environmentSetHandler.EnvironmentSetService = server.EnvironmentSetService
+	environmentSetHandler.EndpointService = server.EndpointService
+	environmentSetHandler.EndpointGroupService = server.EndpointGroupService
+	environmentSetHandler.StackService = server.StackService
+	environmentSetHandler.EncryptionService = server.EncryptionService
+
 	var fileHandler = file.NewHandler(filepath.Join(server.AssetsPath, "public"))
 
+	var jobHandler = jobs.NewHandler(requestBouncer)
+	jobHandler.JobScheduler = server.JobScheduler
+
 	var registryHandler = registries.NewHandler(requestBouncer)
 	registryHandler.RegistryService = server.RegistryService
+	registryHandler.ConnectivityService = server.ConnectivityService
 
 	var resourceControlHandler = resourcecontrols.NewHandler(requestBouncer)
 	resourceControlHandler.ResourceControlService = server.ResourceControlService
+	resourceControlHandler.StackService = server.StackService
+
+	var scheduleHandler = schedules.NewHandler(requestBouncer)
+	scheduleHandler.ScheduleService = server.ScheduleService
+	scheduleHandler.ScheduleExecutionService = server.ScheduleExecutionService
+	scheduleHandler.ScheduleRunner = server.ScheduleRunner
+	scheduleHandler.EndpointService = server.EndpointService
+	scheduleHandler.FileService = server.FileService
+	scheduleHandler.JobScheduler = server.JobScheduler
+
+	var sessionRecordingHandler = sessionrecordings.NewHandler(requestBouncer)
+	sessionRecordingHandler.SessionRecordingService = server.SessionRecordingService
+	sessionRecordingHandler.FileService = server.FileService
 
 	var settingsHandler = settings.NewHandler(requestBouncer)
 	settingsHandler.SettingsService = server.SettingsService
@@ -130,6 +187,7 @@ func (server *Server) Start() error {
 	var stackHandler = stacks.NewHandler(requestBouncer)
 	stackHandler.FileService = server.FileService
 	stackHandler.StackService = server.StackService
+	stackHandler.StackJobService = server.StackJobService
 	stackHandler.EndpointService = server.EndpointService
 	stackHandler.ResourceControlService = server.ResourceControlService
 	stackHandler.SwarmStackManager = server.SwarmStackManager
@@ -137,6 +195,9 @@ func (server *Server) Start() error {
 	stackHandler.GitService = server.GitService
 	stackHandler.RegistryService = server.RegistryService
 	stackHandler.DockerHubService = server.DockerHubService
+	stackHandler.DockerClientFactory = server.DockerClientFactory
+	stackHandler.EnvironmentSetService = server.EnvironmentSetService
+	stackHandler.EncryptionService = server.EncryptionService
 
 	var tagHandler = tags.NewHandler(requestBouncer)
 	tagHandler.TagService = server.TagService
@@ -151,6 +212,15 @@ func (server *Server) Start() error {
 
 	var templatesHandler = templates.NewHandler(requestBouncer)
 	templatesHandler.TemplateService = server.TemplateService
+	templatesHandler.TemplateSourceService = server.TemplateSourceService
+	templatesHandler.SettingsService = server.SettingsService
+	templatesHandler.EndpointService = server.EndpointService
+	templatesHandler.ResourceControlService = server.ResourceControlService
+	templatesHandler.RegistryService = server.RegistryService
+	templatesHandler.DockerHubService = server.DockerHubService
+	templatesHandler.CredentialsService = server.CredentialsService
+	templatesHandler.DockerClientFactory = server.DockerClientFactory
+	templatesHandler.StackHandler = stackHandler
 
 	var uploadHandler = upload.NewHandler(requestBouncer)
 	uploadHandler.FileService = server.FileService
@@ -166,26 +236,37 @@ func (server *Server) Start() error {
 	var websocketHandler = websocket.NewHandler(requestBouncer)
 	websocketHandler.EndpointService = server.EndpointService
 	websocketHandler.SignatureService = server.SignatureService
+	websocketHandler.SettingsService = server.SettingsService
+	websocketHandler.SessionRecordingService = server.SessionRecordingService
+	websocketHandler.FileService = server.FileService
+	websocketHandler.ResourceControlService = server.ResourceControlService
+	websocketHandler.TeamMembershipService = server.TeamMembershipService
+	websocketHandler.ClientFactory = server.DockerClientFactory
 
 	server.Handler = &handler.Handler{
-		AuthHandler:            authHandler,
-		DockerHubHandler:       dockerHubHandler,
-		EndpointGroupHandler:   endpointGroupHandler,
-		EndpointHandler:        endpointHandler,
-		EndpointProxyHandler:   endpointProxyHandler,
-		FileHandler:            fileHandler,
-		RegistryHandler:        registryHandler,
-		ResourceControlHandler: resourceControlHandler,
-		SettingsHandler:        settingsHandler,
-		StatusHandler:          statusHandler,
-		StackHandler:           stackHandler,
-		TagHandler:             tagHandler,
-		TeamHandler:            teamHandler,
-		TeamMembershipHandler:  teamMembershipHandler,
-		TemplatesHandler:       templatesHandler,
-		UploadHandler:          uploadHandler,
-		UserHandler:            userHandler,
-		WebSocketHandler:       websocketHandler,
+		AuthHandler:             authHandler,
+		CleanupPolicyHandler:    cleanupPolicyHandler,
+		DockerHubHandler:        dockerHubHandler,
+		EndpointGroupHandler:    endpointGroupHandler,
+		EndpointHandler:         endpointHandler,
+		EndpointProxyHandler:    endpointProxyHandler,
+		EnvironmentSetHandler:   environmentSetHandler,
+		FileHandler:             fileHandler,
+		JobHandler:              jobHandler,
+		RegistryHandler:         registryHandler,
+		ResourceControlHandler:  resourceControlHandler,
+		ScheduleHandler:         scheduleHandler,
+		SessionRecordingHandler: sessionRecordingHandler,
+		SettingsHandler:         settingsHandler,
+		StatusHandler:           statusHandler,
+		StackHandler:            stackHandler,
+		TagHandler:              tagHandler,
+		TeamHandler:             teamHandler,
+		TeamMembershipHandler:   teamMembershipHandler,
+		TemplatesHandler:        templatesHandler,
+		UploadHandler:           uploadHandler,
+		UserHandler:             userHandler,
+		WebSocketHandler:        websocketHandler,
 	}
 
 	if server.SSL {
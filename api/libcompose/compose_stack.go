@@ -2,6 +2,7 @@ package libcompose
 
 import (
 	"context"
+	"io"
 	"path"
 	"path/filepath"
 
@@ -44,35 +45,50 @@ func createClient(endpoint *portainer.Endpoint) (client.Factory, error) {
 	return client.NewDefaultFactory(clientOpts)
 }
 
-// Up will deploy a compose stack (equivalent of docker-compose up)
-func (manager *ComposeStackManager) Up(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
-
-	clientFactory, err := createClient(endpoint)
-	if err != nil {
-		return err
+// composeFilePaths returns the path of the entry point of the stack followed by the path of its additional files.
+func composeFilePaths(stack *portainer.Stack) []string {
+	filePaths := []string{path.Join(stack.ProjectPath, stack.EntryPoint)}
+	for _, file := range stack.AdditionalFiles {
+		filePaths = append(filePaths, path.Join(stack.ProjectPath, file))
 	}
+	return filePaths
+}
 
+// environmentLookup returns the lookup used to resolve the variables of the Compose files of the stack,
+// from the .env file of the project and from the environment of the stack.
+func environmentLookup(stack *portainer.Stack) config.EnvironmentLookup {
 	env := make(map[string]string)
 	for _, envvar := range stack.Env {
 		env[envvar.Name] = envvar.Value
 	}
 
-	composeFilePath := path.Join(stack.ProjectPath, stack.EntryPoint)
+	return &lookup.ComposableEnvLookup{
+		Lookups: []config.EnvironmentLookup{
+			&lookup.EnvfileLookup{
+				Path: filepath.Join(stack.ProjectPath, ".env"),
+			},
+			&lookup.MapLookup{
+				Vars: env,
+			},
+		},
+	}
+}
+
+// Up will deploy a compose stack (equivalent of docker-compose up)
+// The deployment output is not available with libcompose, output is ignored.
+func (manager *ComposeStackManager) Up(stack *portainer.Stack, endpoint *portainer.Endpoint, output io.Writer) error {
+
+	clientFactory, err := createClient(endpoint)
+	if err != nil {
+		return err
+	}
+
 	proj, err := docker.NewProject(&ctx.Context{
 		ConfigDir: manager.dataPath,
 		Context: project.Context{
-			ComposeFiles: []string{composeFilePath},
-			EnvironmentLookup: &lookup.ComposableEnvLookup{
-				Lookups: []config.EnvironmentLookup{
-					&lookup.EnvfileLookup{
-						Path: filepath.Join(stack.ProjectPath, ".env"),
-					},
-					&lookup.MapLookup{
-						Vars: env,
-					},
-				},
-			},
-			ProjectName: stack.Name,
+			ComposeFiles:      composeFilePaths(stack),
+			EnvironmentLookup: environmentLookup(stack),
+			ProjectName:       stack.Name,
 		},
 		ClientFactory: clientFactory,
 	}, nil)
@@ -84,17 +100,18 @@ func (manager *ComposeStackManager) Up(stack *portainer.Stack, endpoint *portain
 }
 
 // Down will shutdown a compose stack (equivalent of docker-compose down)
-func (manager *ComposeStackManager) Down(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+// The output is ignored, see Up.
+func (manager *ComposeStackManager) Down(stack *portainer.Stack, endpoint *portainer.Endpoint, output io.Writer) error {
 	clientFactory, err := createClient(endpoint)
 	if err != nil {
 		return err
 	}
 
-	composeFilePath := path.Join(stack.ProjectPath, stack.EntryPoint)
 	proj, err := docker.NewProject(&ctx.Context{
 		Context: project.Context{
-			ComposeFiles: []string{composeFilePath},
-			ProjectName:  stack.Name,
+			ComposeFiles:      composeFilePaths(stack),
+			EnvironmentLookup: environmentLookup(stack),
+			ProjectName:       stack.Name,
 		},
 		ClientFactory: clientFactory,
 	}, nil)
@@ -1,5 +1,7 @@
 package portainer
 
+import "io"
+
 type (
 	// Pair defines a key/value string pair
 	Pair struct {
@@ -9,30 +11,33 @@ type (
 
 	// CLIFlags represents the available flags on the CLI.
 	CLIFlags struct {
-		Addr              *string
-		AdminPassword     *string
-		AdminPasswordFile *string
-		Assets            *string
-		Data              *string
-		EndpointURL       *string
-		ExternalEndpoints *string
-		Labels            *[]Pair
-		Logo              *string
-		NoAuth            *bool
-		NoAnalytics       *bool
-		Templates         *string
-		TemplateFile      *string
-		TLS               *bool
-		TLSSkipVerify     *bool
-		TLSCacert         *string
-		TLSCert           *string
-		TLSKey            *string
-		SSL               *bool
-		SSLCert           *string
-		SSLKey            *string
-		SyncInterval      *string
-		Snapshot          *bool
-		SnapshotInterval  *string
+		Addr                     *string
+		AdminPassword            *string
+		AdminPasswordFile        *string
+		Assets                   *string
+		Data                     *string
+		EndpointURL              *string
+		ExternalEndpoints        *string
+		Labels                   *[]Pair
+		Logo                     *string
+		NoAuth                   *bool
+		NoAnalytics              *bool
+		Templates                *string
+		TemplateFile             *string
+		TLS                      *bool
+		TLSSkipVerify            *bool
+		TLSCacert                *string
+		TLSCert                  *string
+		TLSKey                   *string
+		SSL                      *bool
+		SSLCert                  *string
+		SSLKey                   *string
+		SyncInterval             *string
+		Snapshot                 *bool
+		SnapshotInterval         *string
+		ImageUpdate              *bool
+		ImageUpdateInterval      *string
+		TemplatesRefreshInterval *string
 	}
 
 	// Status represents the application status.
@@ -81,13 +86,16 @@ type (
 
 	// Settings represents the application settings.
 	Settings struct {
-		LogoURL                            string               `json:"LogoURL"`
-		BlackListedLabels                  []Pair               `json:"BlackListedLabels"`
-		AuthenticationMethod               AuthenticationMethod `json:"AuthenticationMethod"`
-		LDAPSettings                       LDAPSettings         `json:"LDAPSettings"`
-		AllowBindMountsForRegularUsers     bool                 `json:"AllowBindMountsForRegularUsers"`
-		AllowPrivilegedModeForRegularUsers bool                 `json:"AllowPrivilegedModeForRegularUsers"`
-		SnapshotInterval                   string               `json:"SnapshotInterval"`
+		LogoURL                            string                   `json:"LogoURL"`
+		BlackListedLabels                  []Pair                   `json:"BlackListedLabels"`
+		AuthenticationMethod               AuthenticationMethod     `json:"AuthenticationMethod"`
+		LDAPSettings                       LDAPSettings             `json:"LDAPSettings"`
+		AllowBindMountsForRegularUsers     bool                     `json:"AllowBindMountsForRegularUsers"`
+		AllowPrivilegedModeForRegularUsers bool                     `json:"AllowPrivilegedModeForRegularUsers"`
+		SnapshotInterval                   string                   `json:"SnapshotInterval"`
+		SessionRecording                   SessionRecordingSettings `json:"SessionRecording"`
+		ConsoleSessions                    ConsoleSessionSettings   `json:"ConsoleSessions"`
+		TemplateSources                    []TemplateSource         `json:"TemplateSources"`
 
 		// Deprecated fields
 		DisplayDonationHeader       bool
@@ -95,6 +103,56 @@ type (
 		TemplatesURL                string
 	}
 
+	// SessionRecordingSettings represents the settings used to record the websocket exec sessions.
+	// Recordings older than RetentionDays are removed, a value of 0 keeps them indefinitely.
+	SessionRecordingSettings struct {
+		Enabled       bool `json:"Enabled"`
+		RetentionDays int  `json:"RetentionDays"`
+	}
+
+	// ConsoleSessionSettings represents the settings applied to the exec and attach console sessions.
+	// IdleTimeout is a duration such as 30m, an empty value disables the timeout.
+	// A MaxSessionsPerUser value of 0 does not limit the number of concurrent sessions of a user.
+	ConsoleSessionSettings struct {
+		IdleTimeout        string `json:"IdleTimeout"`
+		MaxSessionsPerUser int    `json:"MaxSessionsPerUser"`
+	}
+
+	// ConsoleSession represents an active exec or attach session opened through a websocket connection.
+	// ExecID is empty for attach sessions.
+	ConsoleSession struct {
+		ID           ConsoleSessionID `json:"Id"`
+		UserID       UserID           `json:"UserId"`
+		Username     string           `json:"Username"`
+		EndpointID   EndpointID       `json:"EndpointId"`
+		ContainerID  string           `json:"ContainerId"`
+		ExecID       string           `json:"ExecId"`
+		NodeName     string           `json:"NodeName"`
+		StartTime    int64            `json:"StartTime"`
+		LastActivity int64            `json:"LastActivity"`
+	}
+
+	// ConsoleSessionID represents a console session identifier
+	ConsoleSessionID int
+
+	// SessionRecording represents the metadata of a recorded exec or attach session.
+	// The session itself is stored on disk in the asciicast v2 format. ExecID is empty for attach sessions.
+	SessionRecording struct {
+		ID          SessionRecordingID `json:"Id"`
+		UserID      UserID             `json:"UserId"`
+		Username    string             `json:"Username"`
+		EndpointID  EndpointID         `json:"EndpointId"`
+		ContainerID string             `json:"ContainerId"`
+		ExecID      string             `json:"ExecId"`
+		NodeName    string             `json:"NodeName"`
+		StartTime   int64              `json:"StartTime"`
+		EndTime     int64              `json:"EndTime"`
+		Size        int64              `json:"Size"`
+	}
+
+	// SessionRecordingID represents a session recording identifier
+	SessionRecordingID int
+
 	// User represents a user account.
 	User struct {
 		ID       UserID   `json:"Id"`
@@ -159,22 +217,250 @@ type (
 		EntryPoint  string     `json:"EntryPoint"`
 		Env         []Pair     `json:"Env"`
 		ProjectPath string
+
+		// Compose files applied in order on top of the entry point, relative to the project path
+		AdditionalFiles []string `json:"AdditionalFiles"`
+
+		// Environment sets merged with Env at deploy time
+		EnvironmentSets []EnvironmentSetID `json:"EnvironmentSets"`
+
+		Status StackStatus `json:"Status"`
+		// Replica counts of the replicated services of a stopped Swarm stack, indexed by service name
+		StoppedReplicas map[string]uint64 `json:"StoppedReplicas,omitempty"`
+	}
+
+	// StackStatus represents the status of a stack.
+	StackStatus int
+
+	// EnvironmentSetID represents an environment set identifier.
+	EnvironmentSetID int
+
+	// EnvironmentSetScope represents the scope in which an environment set can be used.
+	EnvironmentSetScope int
+
+	// EnvironmentSet represents a named list of environment variables that stacks can reference.
+	// Depending on its scope, it can be used by any stack, by the stacks of the endpoints of an
+	// endpoint group or by the stacks of an endpoint.
+	EnvironmentSet struct {
+		ID              EnvironmentSetID      `json:"Id"`
+		Name            string                `json:"Name"`
+		Scope           EnvironmentSetScope   `json:"Scope"`
+		EndpointGroupID EndpointGroupID       `json:"EndpointGroupId"`
+		EndpointID      EndpointID            `json:"EndpointId"`
+		Variables       []EnvironmentVariable `json:"Variables"`
+	}
+
+	// EnvironmentVariable represents a variable of an environment set.
+	// The value of a secret variable is stored encrypted.
+	EnvironmentVariable struct {
+		Name   string `json:"Name"`
+		Value  string `json:"Value"`
+		Secret bool   `json:"Secret"`
+	}
+
+	// CleanupPolicyID represents a cleanup policy identifier.
+	CleanupPolicyID int
+
+	// CleanupPolicy represents the unused Docker resources periodically removed from an endpoint
+	// or from the endpoints of an endpoint group.
+	CleanupPolicy struct {
+		ID              CleanupPolicyID `json:"Id"`
+		Name            string          `json:"Name"`
+		CronExpression  string          `json:"CronExpression"`
+		EndpointID      EndpointID      `json:"EndpointId"`
+		EndpointGroupID EndpointGroupID `json:"EndpointGroupId"`
+		// Remove the stopped containers. The containers, volumes and networks of stacks are never removed
+		// so that a stopped stack can be started again.
+		Containers bool `json:"Containers"`
+		// Remove the dangling images, or every image not used by a container when UnusedImages is set.
+		// Only the images created more than ImageRetentionDays days ago are removed.
+		Images             bool `json:"Images"`
+		UnusedImages       bool `json:"UnusedImages"`
+		ImageRetentionDays int  `json:"ImageRetentionDays"`
+		// Remove the volumes not used by a container, except the ones with the ExcludedVolumeLabel label
+		Volumes             bool   `json:"Volumes"`
+		ExcludedVolumeLabel string `json:"ExcludedVolumeLabel"`
+		// Remove the networks not used by a container, predefined and swarm networks are never removed
+		Networks bool `json:"Networks"`
+		// Remove the unused build cache
+		BuildCache bool `json:"BuildCache"`
+	}
+
+	// CleanupReport represents the resources removed, or that would be removed during a dry run,
+	// from an endpoint or from a node of an endpoint. Sizes are expressed in bytes.
+	CleanupReport struct {
+		EndpointID     EndpointID `json:"EndpointId"`
+		NodeName       string     `json:"NodeName"`
+		Containers     []string   `json:"Containers"`
+		Images         []string   `json:"Images"`
+		Volumes        []string   `json:"Volumes"`
+		Networks       []string   `json:"Networks"`
+		BuildCacheSize int64      `json:"BuildCacheSize"`
+		SpaceReclaimed int64      `json:"SpaceReclaimed"`
+		Errors         []string   `json:"Errors"`
+	}
+
+	// CleanupRunID represents a cleanup run identifier.
+	CleanupRunID int
+
+	// CleanupRun represents an execution of a cleanup policy.
+	CleanupRun struct {
+		ID             CleanupRunID    `json:"Id"`
+		PolicyID       CleanupPolicyID `json:"PolicyId"`
+		StartedAt      int64           `json:"StartedAt"`
+		FinishedAt     int64           `json:"FinishedAt"`
+		SpaceReclaimed int64           `json:"SpaceReclaimed"`
+		Reports        []CleanupReport `json:"Reports"`
+		Error          string          `json:"Error"`
+	}
+
+	// JobStatus represents the state of a job scheduled by the JobScheduler.
+	JobStatus struct {
+		Name     string `json:"Name"`
+		Schedule string `json:"Schedule"`
+		Running  bool   `json:"Running"`
+		LastRun  int64  `json:"LastRun"`
+		// Duration of the last run in milliseconds
+		LastDuration int64  `json:"LastDuration"`
+		LastError    string `json:"LastError"`
+		NextRun      int64  `json:"NextRun"`
+	}
+
+	// ScheduleID represents a schedule identifier.
+	ScheduleID int
+
+	// ScheduleJobType represents the type of job run by a schedule.
+	ScheduleJobType int
+
+	// Schedule represents a job run on a cron schedule on one or more endpoints.
+	// A container job runs a command inside a container created from an image,
+	// a script job runs a script on the host of the Docker engine.
+	Schedule struct {
+		ID             ScheduleID      `json:"Id"`
+		Name           string          `json:"Name"`
+		CronExpression string          `json:"CronExpression"`
+		JobType        ScheduleJobType `json:"JobType"`
+		Image          string          `json:"Image"`
+		Command        string          `json:"Command"`
+		ScriptPath     string          `json:"ScriptPath"`
+		Endpoints      []EndpointID    `json:"Endpoints"`
+		// Run the job on every node of the cluster of an agent endpoint
+		AllNodes bool  `json:"AllNodes"`
+		Created  int64 `json:"Created"`
+	}
+
+	// ScheduleExecutionID represents a schedule execution identifier.
+	ScheduleExecutionID int
+
+	// ScheduleExecutionStatus represents the status of a schedule execution.
+	ScheduleExecutionStatus int
+
+	// ScheduleExecution represents a run of the job of a schedule on an endpoint or on a node of an endpoint.
+	ScheduleExecution struct {
+		ID         ScheduleExecutionID     `json:"Id"`
+		ScheduleID ScheduleID              `json:"ScheduleId"`
+		EndpointID EndpointID              `json:"EndpointId"`
+		NodeName   string                  `json:"NodeName"`
+		Status     ScheduleExecutionStatus `json:"Status"`
+		Output     string                  `json:"Output"`
+		ExitCode   int                     `json:"ExitCode"`
+		Error      string                  `json:"Error"`
+		StartedAt  int64                   `json:"StartedAt"`
+		FinishedAt int64                   `json:"FinishedAt"`
+	}
+
+	// StackJobID represents a stack job identifier.
+	StackJobID int
+
+	// StackJobType represents the type of operation executed by a stack job.
+	StackJobType int
+
+	// StackJobStatus represents the status of a stack job.
+	StackJobStatus int
+
+	// StackJob represents an operation (creation, update or removal) executed in the background on a stack.
+	StackJob struct {
+		ID          StackJobID        `json:"Id"`
+		StackID     StackID           `json:"StackId"`
+		Type        StackJobType      `json:"Type"`
+		Status      StackJobStatus    `json:"Status"`
+		UserID      UserID            `json:"UserId"`
+		Output      string            `json:"Output"`
+		Error       string            `json:"Error"`
+		CreatedAt   int64             `json:"CreatedAt"`
+		StartedAt   int64             `json:"StartedAt"`
+		FinishedAt  int64             `json:"FinishedAt"`
+		Convergence *StackConvergence `json:"Convergence,omitempty"`
+	}
+
+	// StackConvergence represents the result of the verification executed after a stack deployment.
+	StackConvergence struct {
+		Converged  bool                 `json:"Converged"`
+		RolledBack bool                 `json:"RolledBack"`
+		Services   []StackServiceStatus `json:"Services"`
+		Error      string               `json:"Error,omitempty"`
+	}
+
+	// StackServiceStatus represents the status of a service of a stack after a deployment.
+	StackServiceStatus struct {
+		Name            string `json:"Name"`
+		DesiredReplicas int    `json:"DesiredReplicas"`
+		RunningReplicas int    `json:"RunningReplicas"`
+		Converged       bool   `json:"Converged"`
+		Message         string `json:"Message"`
 	}
 
 	// RegistryID represents a registry identifier.
 	RegistryID int
 
+	// RegistryType represents a type of registry.
+	RegistryType int
+
 	// Registry represents a Docker registry with all the info required
 	// to connect to it.
 	Registry struct {
-		ID              RegistryID `json:"Id"`
-		Name            string     `json:"Name"`
-		URL             string     `json:"URL"`
-		Authentication  bool       `json:"Authentication"`
-		Username        string     `json:"Username"`
-		Password        string     `json:"Password,omitempty"`
-		AuthorizedUsers []UserID   `json:"AuthorizedUsers"`
-		AuthorizedTeams []TeamID   `json:"AuthorizedTeams"`
+		ID              RegistryID        `json:"Id"`
+		Type            RegistryType      `json:"Type"`
+		Name            string            `json:"Name"`
+		URL             string            `json:"URL"`
+		Authentication  bool              `json:"Authentication"`
+		Username        string            `json:"Username"`
+		Password        string            `json:"Password,omitempty"`
+		Ecr             EcrRegistryData   `json:"Ecr"`
+		Azure           AzureRegistryData `json:"Azure"`
+		Mirrors         []string          `json:"Mirrors"`
+		AuthorizedUsers []UserID          `json:"AuthorizedUsers"`
+		AuthorizedTeams []TeamID          `json:"AuthorizedTeams"`
+	}
+
+	// EcrRegistryData represents the AWS specific configuration of an ECR registry.
+	// The access key ID and the secret access key are stored in the Username and
+	// Password fields of the registry.
+	EcrRegistryData struct {
+		Region string `json:"Region"`
+	}
+
+	// AzureRegistryData represents the Azure specific configuration of an ACR registry.
+	// The service principal application ID and key are stored in the Username and
+	// Password fields of the registry.
+	AzureRegistryData struct {
+		TenantID string `json:"TenantID"`
+	}
+
+	// RegistryCredentials represents the credentials that must be used to
+	// authenticate against a registry.
+	RegistryCredentials struct {
+		Username      string
+		Password      string
+		ServerAddress string
+	}
+
+	// RegistryConnectivityReport represents the result of a connectivity test against a registry.
+	RegistryConnectivityReport struct {
+		Reachable     bool   `json:"Reachable"`
+		Authenticated bool   `json:"Authenticated"`
+		APIVersion    string `json:"APIVersion"`
+		Error         string `json:"Error,omitempty"`
 	}
 
 	// DockerHub represents all the required information to connect and use the
@@ -197,20 +483,21 @@ type (
 	// Endpoint represents a Docker endpoint with all the info required
 	// to connect to it.
 	Endpoint struct {
-		ID               EndpointID          `json:"Id"`
-		Name             string              `json:"Name"`
-		Type             EndpointType        `json:"Type"`
-		URL              string              `json:"URL"`
-		GroupID          EndpointGroupID     `json:"GroupId"`
-		PublicURL        string              `json:"PublicURL"`
-		TLSConfig        TLSConfiguration    `json:"TLSConfig"`
-		AuthorizedUsers  []UserID            `json:"AuthorizedUsers"`
-		AuthorizedTeams  []TeamID            `json:"AuthorizedTeams"`
-		Extensions       []EndpointExtension `json:"Extensions"`
-		AzureCredentials AzureCredentials    `json:"AzureCredentials,omitempty"`
-		Tags             []string            `json:"Tags"`
-		Status           EndpointStatus      `json:"Status"`
-		Snapshots        []Snapshot          `json:"Snapshots"`
+		ID                 EndpointID          `json:"Id"`
+		Name               string              `json:"Name"`
+		Type               EndpointType        `json:"Type"`
+		URL                string              `json:"URL"`
+		GroupID            EndpointGroupID     `json:"GroupId"`
+		PublicURL          string              `json:"PublicURL"`
+		TLSConfig          TLSConfiguration    `json:"TLSConfig"`
+		AuthorizedUsers    []UserID            `json:"AuthorizedUsers"`
+		AuthorizedTeams    []TeamID            `json:"AuthorizedTeams"`
+		Extensions         []EndpointExtension `json:"Extensions"`
+		AzureCredentials   AzureCredentials    `json:"AzureCredentials,omitempty"`
+		Tags               []string            `json:"Tags"`
+		Status             EndpointStatus      `json:"Status"`
+		Snapshots          []Snapshot          `json:"Snapshots"`
+		ComposeStackEngine ComposeStackEngine  `json:"ComposeStackEngine"`
 
 		// Deprecated fields
 		// Deprecated in DBVersion == 4
@@ -220,6 +507,9 @@ type (
 		TLSKeyPath    string `json:"TLSKey,omitempty"`
 	}
 
+	// ComposeStackEngine represents the tool used to deploy Compose stacks on an endpoint.
+	ComposeStackEngine int
+
 	// AzureCredentials represents the credentials used to connect to an Azure
 	// environment.
 	AzureCredentials struct {
@@ -243,6 +533,24 @@ type (
 		StackCount            int    `json:"StackCount"`
 	}
 
+	// ImageUpdateReport represents the result of an image update check on a specific endpoint
+	ImageUpdateReport struct {
+		Time       int64               `json:"Time"`
+		Containers []ImageUpdateStatus `json:"Containers"`
+		Services   []ImageUpdateStatus `json:"Services"`
+	}
+
+	// ImageUpdateStatus represents the comparison between the digest of the image used by a container
+	// or a service and the digest currently associated to the same tag in the registry
+	ImageUpdateStatus struct {
+		ID              string `json:"Id"`
+		Image           string `json:"Image"`
+		LocalDigest     string `json:"LocalDigest"`
+		RemoteDigest    string `json:"RemoteDigest"`
+		UpdateAvailable bool   `json:"UpdateAvailable"`
+		Error           string `json:"Error,omitempty"`
+	}
+
 	// EndpointGroupID represents an endpoint group identifier.
 	EndpointGroupID int
 
@@ -334,12 +642,13 @@ type (
 		Repository TemplateRepository `json:"repository"`
 
 		// Optional stack/container fields
-		Name       string        `json:"name,omitempty"`
-		Logo       string        `json:"logo,omitempty"`
-		Env        []TemplateEnv `json:"env,omitempty"`
-		Note       string        `json:"note,omitempty"`
-		Platform   string        `json:"platform,omitempty"`
-		Categories []string      `json:"categories,omitempty"`
+		Name       string          `json:"name,omitempty"`
+		Logo       string          `json:"logo,omitempty"`
+		Env        []TemplateEnv   `json:"env,omitempty"`
+		Inputs     []TemplateInput `json:"inputs,omitempty"`
+		Note       string          `json:"note,omitempty"`
+		Platform   string          `json:"platform,omitempty"`
+		Categories []string        `json:"categories,omitempty"`
 
 		// Optional container fields
 		Registry      string           `json:"registry,omitempty"`
@@ -352,8 +661,39 @@ type (
 		Interactive   bool             `json:"interactive,omitempty"`
 		RestartPolicy string           `json:"restart_policy,omitempty"`
 		Hostname      string           `json:"hostname,omitempty"`
+
+		// Identifier of the template source the template was imported from, empty for templates created locally
+		SourceID TemplateSourceID `json:"source_id,omitempty"`
+
+		// Access restrictions, the template is available to every user when no authorized user or team is specified
+		// and is available on every endpoint when no endpoint group is specified
+		AuthorizedUsers []UserID          `json:"authorized_users,omitempty"`
+		AuthorizedTeams []TeamID          `json:"authorized_teams,omitempty"`
+		EndpointGroups  []EndpointGroupID `json:"endpoint_groups,omitempty"`
+	}
+
+	// TemplateSource represents a remote location from which templates are periodically imported.
+	// The templates file is either served over HTTP at URL or stored at FilePath inside the git repository located at URL.
+	TemplateSource struct {
+		ID                       TemplateSourceID   `json:"Id"`
+		Name                     string             `json:"Name"`
+		Type                     TemplateSourceType `json:"Type"`
+		URL                      string             `json:"URL"`
+		RepositoryReferenceName  string             `json:"RepositoryReferenceName"`
+		RepositoryAuthentication bool               `json:"RepositoryAuthentication"`
+		RepositoryUsername       string             `json:"RepositoryUsername"`
+		RepositoryPassword       string             `json:"RepositoryPassword,omitempty"`
+		FilePath                 string             `json:"FilePath"`
+		LastRefresh              int64              `json:"LastRefresh"`
+		LastError                string             `json:"LastError"`
 	}
 
+	// TemplateSourceID represents a template source identifier
+	TemplateSourceID int
+
+	// TemplateSourceType represents the type of a template source
+	TemplateSourceType int
+
 	// TemplateEnv represents a template environment variable configuration.
 	TemplateEnv struct {
 		Name        string              `json:"name"`
@@ -364,6 +704,33 @@ type (
 		Select      []TemplateEnvSelect `json:"select,omitempty"`
 	}
 
+	// TemplateInput represents a typed input of a template (schema v2). The value of the input
+	// is exposed to the container or the stack as an environment variable named after the input.
+	TemplateInput struct {
+		Name        string                  `json:"name"`
+		Label       string                  `json:"label,omitempty"`
+		Description string                  `json:"description,omitempty"`
+		Type        TemplateInputType       `json:"type"`
+		Default     string                  `json:"default,omitempty"`
+		Preset      bool                    `json:"preset,omitempty"`
+		Required    bool                    `json:"required,omitempty"`
+		Min         *int                    `json:"min,omitempty"`
+		Max         *int                    `json:"max,omitempty"`
+		Regex       string                  `json:"regex,omitempty"`
+		Options     []TemplateEnvSelect     `json:"options,omitempty"`
+		Condition   *TemplateInputCondition `json:"condition,omitempty"`
+	}
+
+	// TemplateInputType represents the type of the value of a template input.
+	TemplateInputType string
+
+	// TemplateInputCondition represents a condition on the value of another input of the template.
+	// An input associated to a condition is only used when the condition is met.
+	TemplateInputCondition struct {
+		Input string `json:"input"`
+		Value string `json:"value"`
+	}
+
 	// TemplateVolume represents a template volume configuration.
 	TemplateVolume struct {
 		Container string `json:"container"`
@@ -474,10 +841,27 @@ type (
 		DeleteRegistry(ID RegistryID) error
 	}
 
+	// RegistryCredentialsService represents a service used to retrieve the credentials
+	// of a registry, exchanging them for a short-lived token when required by the registry type.
+	RegistryCredentialsService interface {
+		Credentials(registry *Registry) (*RegistryCredentials, error)
+	}
+
+	// RegistryConnectivityService represents a service used to check that a registry
+	// is reachable through the v2 API and that its credentials are valid.
+	RegistryConnectivityService interface {
+		TestConnectivity(registry *Registry, tlsSkipVerify bool) *RegistryConnectivityReport
+	}
+
+	// RegistryManifestService represents a service used to retrieve image manifests information from a registry.
+	RegistryManifestService interface {
+		ImageDigest(image string, credentials *RegistryCredentials, insecure bool) (string, error)
+	}
+
 	// StackService represents a service for managing stack data.
 	StackService interface {
 		Stack(ID StackID) (*Stack, error)
-		StackByName(name string) (*Stack, error)
+		StackByName(endpointID EndpointID, name string) (*Stack, error)
 		Stacks() ([]Stack, error)
 		CreateStack(stack *Stack) error
 		UpdateStack(ID StackID, stack *Stack) error
@@ -485,6 +869,69 @@ type (
 		GetNextIdentifier() int
 	}
 
+	// EnvironmentSetService represents a service for managing environment set data.
+	EnvironmentSetService interface {
+		EnvironmentSet(ID EnvironmentSetID) (*EnvironmentSet, error)
+		EnvironmentSets() ([]EnvironmentSet, error)
+		CreateEnvironmentSet(set *EnvironmentSet) error
+		UpdateEnvironmentSet(ID EnvironmentSetID, set *EnvironmentSet) error
+		DeleteEnvironmentSet(ID EnvironmentSetID) error
+	}
+
+	// CleanupPolicyService represents a service for managing cleanup policy data.
+	CleanupPolicyService interface {
+		CleanupPolicy(ID CleanupPolicyID) (*CleanupPolicy, error)
+		CleanupPolicies() ([]CleanupPolicy, error)
+		CreateCleanupPolicy(policy *CleanupPolicy) error
+		UpdateCleanupPolicy(ID CleanupPolicyID, policy *CleanupPolicy) error
+		DeleteCleanupPolicy(ID CleanupPolicyID) error
+	}
+
+	// CleanupRunService represents a service for managing cleanup run data.
+	CleanupRunService interface {
+		CleanupRunsByPolicy(policyID CleanupPolicyID) ([]CleanupRun, error)
+		CreateCleanupRun(run *CleanupRun) error
+		DeleteCleanupRun(ID CleanupRunID) error
+	}
+
+	// EndpointCleaner represents a service used to remove the unused resources of the endpoints targeted by a cleanup policy.
+	EndpointCleaner interface {
+		Cleanup(policy *CleanupPolicy, dryRun bool) ([]CleanupReport, error)
+	}
+
+	// ScheduleService represents a service for managing schedule data.
+	ScheduleService interface {
+		Schedule(ID ScheduleID) (*Schedule, error)
+		Schedules() ([]Schedule, error)
+		CreateSchedule(schedule *Schedule) error
+		UpdateSchedule(ID ScheduleID, schedule *Schedule) error
+		DeleteSchedule(ID ScheduleID) error
+		GetNextIdentifier() int
+	}
+
+	// ScheduleExecutionService represents a service for managing schedule execution data.
+	ScheduleExecutionService interface {
+		ScheduleExecution(ID ScheduleExecutionID) (*ScheduleExecution, error)
+		ScheduleExecutionsBySchedule(scheduleID ScheduleID) ([]ScheduleExecution, error)
+		CreateScheduleExecution(execution *ScheduleExecution) error
+		UpdateScheduleExecution(ID ScheduleExecutionID, execution *ScheduleExecution) error
+		DeleteScheduleExecution(ID ScheduleExecutionID) error
+	}
+
+	// ScheduleRunner represents a service used to run the job of a schedule on its endpoints.
+	ScheduleRunner interface {
+		Run(schedule *Schedule) ([]ScheduleExecution, error)
+	}
+
+	// StackJobService represents a service for managing stack job data.
+	StackJobService interface {
+		StackJob(ID StackJobID) (*StackJob, error)
+		StackJobs() ([]StackJob, error)
+		CreateStackJob(job *StackJob) error
+		UpdateStackJob(ID StackJobID, job *StackJob) error
+		DeleteStackJob(ID StackJobID) error
+	}
+
 	// DockerHubService represents a service for managing the DockerHub object.
 	DockerHubService interface {
 		DockerHub() (*DockerHub, error)
@@ -529,12 +976,27 @@ type (
 		DeleteTemplate(ID TemplateID) error
 	}
 
+	// SessionRecordingService represents a service for managing session recording data.
+	SessionRecordingService interface {
+		SessionRecordings() ([]SessionRecording, error)
+		SessionRecording(ID SessionRecordingID) (*SessionRecording, error)
+		CreateSessionRecording(recording *SessionRecording) error
+		UpdateSessionRecording(ID SessionRecordingID, recording *SessionRecording) error
+		DeleteSessionRecording(ID SessionRecordingID) error
+	}
+
 	// CryptoService represents a service for encrypting/hashing data.
 	CryptoService interface {
 		Hash(data string) (string, error)
 		CompareHashAndData(hash string, data string) error
 	}
 
+	// EncryptionService represents a service for encrypting and decrypting sensitive data.
+	EncryptionService interface {
+		Encrypt(data string) (string, error)
+		Decrypt(data string) (string, error)
+	}
+
 	// DigitalSignatureService represents a service to manage digital signatures.
 	DigitalSignatureService interface {
 		ParseKeyPair(private, public []byte) error
@@ -560,12 +1022,21 @@ type (
 		DeleteTLSFile(folder string, fileType TLSFileType) error
 		DeleteTLSFiles(folder string) error
 		GetStackProjectPath(stackIdentifier string) string
+		StoreScheduleScriptFromBytes(scheduleIdentifier string, data []byte) (string, error)
+		GetScheduleFolder(scheduleIdentifier string) string
 		StoreStackFileFromBytes(stackIdentifier, fileName string, data []byte) (string, error)
+		DeleteStackFile(stackIdentifier, fileName string) error
+		CopyStackProject(projectPath, stackIdentifier string) (string, error)
 		KeyPairFilesExist() (bool, error)
 		StoreKeyPair(private, public []byte, privatePEMHeader, publicPEMHeader string) error
 		LoadKeyPair() ([]byte, []byte, error)
+		EncryptionKeyFileExists() (bool, error)
+		StoreEncryptionKey(key []byte, pemHeader string) error
+		LoadEncryptionKey() ([]byte, error)
 		WriteJSONToFile(path string, content interface{}) error
 		FileExists(path string) (bool, error)
+		GetSessionRecordingPath(recordingIdentifier string) string
+		DeleteSessionRecordingFile(recordingIdentifier string) error
 	}
 
 	// GitService represents a service for managing Git.
@@ -574,11 +1045,20 @@ type (
 		ClonePrivateRepositoryWithBasicAuth(repositoryURL, referenceName string, destination, username, password string) error
 	}
 
-	// JobScheduler represents a service to run jobs on a periodic basis.
+	// Job represents a task run periodically by the JobScheduler.
+	Job interface {
+		Run() error
+	}
+
+	// JobScheduler represents a service to run named jobs on a periodic basis. The schedule of a job
+	// uses the standard cron format or a descriptor such as @every 1h.
 	JobScheduler interface {
-		ScheduleEndpointSyncJob(endpointFilePath, interval string) error
-		ScheduleSnapshotJob(interval string) error
-		UpdateSnapshotJob(interval string)
+		ScheduleJob(name, schedule string, job Job) error
+		RescheduleJob(name, schedule string) error
+		UnscheduleJob(name string)
+		RunJob(name string) error
+		JobStatus(name string) (*JobStatus, error)
+		JobStatuses() []JobStatus
 		Start()
 	}
 
@@ -587,6 +1067,12 @@ type (
 		CreateSnapshot(endpoint *Endpoint) (*Snapshot, error)
 	}
 
+	// ImageUpdateService represents a service used to detect containers and services running an outdated image.
+	ImageUpdateService interface {
+		CheckEndpoint(endpoint *Endpoint) (*ImageUpdateReport, error)
+		EndpointReport(endpointID EndpointID) *ImageUpdateReport
+	}
+
 	// LDAPService represents a service used to authenticate users against a LDAP/AD.
 	LDAPService interface {
 		AuthenticateUser(username, password string, settings *LDAPSettings) error
@@ -594,18 +1080,24 @@ type (
 		GetUserGroups(username string, settings *LDAPSettings) ([]string, error)
 	}
 
+	// TemplateSourceService represents a service used to import the templates of the template sources.
+	TemplateSourceService interface {
+		RefreshTemplateSources() error
+		RefreshTemplateSource(ID TemplateSourceID) error
+	}
+
 	// SwarmStackManager represents a service to manage Swarm stacks.
 	SwarmStackManager interface {
 		Login(dockerhub *DockerHub, registries []Registry, endpoint *Endpoint)
 		Logout(endpoint *Endpoint) error
-		Deploy(stack *Stack, prune bool, endpoint *Endpoint) error
-		Remove(stack *Stack, endpoint *Endpoint) error
+		Deploy(stack *Stack, prune bool, endpoint *Endpoint, output io.Writer) error
+		Remove(stack *Stack, endpoint *Endpoint, output io.Writer) error
 	}
 
 	// ComposeStackManager represents a service to manage Compose stacks.
 	ComposeStackManager interface {
-		Up(stack *Stack, endpoint *Endpoint) error
-		Down(stack *Stack, endpoint *Endpoint) error
+		Up(stack *Stack, endpoint *Endpoint, output io.Writer) error
+		Down(stack *Stack, endpoint *Endpoint, output io.Writer) error
 	}
 )
 
@@ -613,7 +1105,9 @@ const (
 	// APIVersion is the version number of the Portainer API.
 	APIVersion = "1.19.1-custom4"
 	// DBVersion is the version number of the Portainer database.
-	DBVersion = 13
+	DBVersion = 22
+	// SecretValueMask represents the value returned by the API in place of the value of a secret environment variable
+	SecretValueMask = "********"
 	// PortainerAgentHeader represents the name of the header available in any agent response
 	PortainerAgentHeader = "Portainer-Agent"
 	// PortainerAgentTargetHeader represent the name of the header containing the target node name.
@@ -692,6 +1186,14 @@ const (
 	StoridgeEndpointExtension
 )
 
+const (
+	_ ComposeStackEngine = iota
+	// LibComposeEngine represents an endpoint deploying Compose stacks with libcompose
+	LibComposeEngine
+	// DockerComposeEngine represents an endpoint deploying Compose stacks with the compose subcommand of the Docker CLI
+	DockerComposeEngine
+)
+
 const (
 	_ EndpointType = iota
 	// DockerEnvironment represents an endpoint connected to a Docker environment
@@ -702,6 +1204,20 @@ const (
 	AzureEnvironment
 )
 
+const (
+	_ RegistryType = iota
+	// QuayRegistry represents a Quay.io registry
+	QuayRegistry
+	// AzureRegistry represents an ACR registry authenticated with an Azure service principal
+	AzureRegistry
+	// CustomRegistry represents a custom registry using static credentials
+	CustomRegistry
+	// GitlabRegistry represents a Gitlab registry authenticated with a deploy token
+	GitlabRegistry
+	// EcrRegistry represents an AWS ECR registry authenticated with an access key
+	EcrRegistry
+)
+
 const (
 	_ StackType = iota
 	// DockerSwarmStack represents a stack managed via docker stack
@@ -710,6 +1226,64 @@ const (
 	DockerComposeStack
 )
 
+const (
+	_ StackStatus = iota
+	// StackStatusActive represents a stack whose services are running
+	StackStatusActive
+	// StackStatusInactive represents a stopped stack
+	StackStatusInactive
+)
+
+const (
+	_ EnvironmentSetScope = iota
+	// GlobalEnvironmentSet represents an environment set available to every stack
+	GlobalEnvironmentSet
+	// EndpointGroupEnvironmentSet represents an environment set available to the stacks of the endpoints of an endpoint group
+	EndpointGroupEnvironmentSet
+	// EndpointEnvironmentSet represents an environment set available to the stacks of an endpoint
+	EndpointEnvironmentSet
+)
+
+const (
+	_ ScheduleJobType = iota
+	// ContainerScheduleJob represents a job running a command inside a container
+	ContainerScheduleJob
+	// ScriptScheduleJob represents a job running a script on the host of the Docker engine
+	ScriptScheduleJob
+)
+
+const (
+	_ ScheduleExecutionStatus = iota
+	// ScheduleExecutionRunning represents an execution in progress
+	ScheduleExecutionRunning
+	// ScheduleExecutionSucceeded represents an execution whose job exited with a zero exit code
+	ScheduleExecutionSucceeded
+	// ScheduleExecutionFailed represents an execution that could not be run or whose job exited with a non-zero exit code
+	ScheduleExecutionFailed
+)
+
+const (
+	_ StackJobType = iota
+	// StackJobCreate represents a job deploying a new stack
+	StackJobCreate
+	// StackJobUpdate represents a job redeploying an existing stack
+	StackJobUpdate
+	// StackJobDelete represents a job removing a stack
+	StackJobDelete
+)
+
+const (
+	_ StackJobStatus = iota
+	// StackJobQueued represents a job waiting for the completion of the other stack jobs
+	StackJobQueued
+	// StackJobRunning represents a job being executed
+	StackJobRunning
+	// StackJobSucceeded represents a job that completed successfully
+	StackJobSucceeded
+	// StackJobFailed represents a job that completed with an error
+	StackJobFailed
+)
+
 const (
 	_ TemplateType = iota
 	// ContainerTemplate represents a container template
@@ -720,6 +1294,29 @@ const (
 	ComposeStackTemplate
 )
 
+const (
+	// StringTemplateInput represents a single line text input
+	StringTemplateInput TemplateInputType = "string"
+	// IntTemplateInput represents an integer input
+	IntTemplateInput TemplateInputType = "int"
+	// BoolTemplateInput represents a boolean input, its value is either true or false
+	BoolTemplateInput TemplateInputType = "bool"
+	// PasswordTemplateInput represents a secret input, its value is never displayed
+	PasswordTemplateInput TemplateInputType = "password"
+	// SelectTemplateInput represents an input whose value must be one of its options
+	SelectTemplateInput TemplateInputType = "select"
+	// MultilineTemplateInput represents a multi-line text input
+	MultilineTemplateInput TemplateInputType = "multiline"
+)
+
+const (
+	_ TemplateSourceType = iota
+	// URLTemplateSource represents a templates file served over HTTP
+	URLTemplateSource
+	// GitTemplateSource represents a templates file stored inside a git repository
+	GitTemplateSource
+)
+
 const (
 	_ EndpointStatus = iota
 	// EndpointStatusUp is used to represent an available endpoint
@@ -0,0 +1,109 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+// azureRegistryTokenUsername is the username that must be used when authenticating
+// against an ACR registry with a refresh token.
+const azureRegistryTokenUsername = "00000000-0000-0000-0000-000000000000"
+
+type (
+	azureRegistryExchangeResponse struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	jwtExpirationClaim struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+)
+
+// exchangeAzureCredentials authenticates the service principal against Azure AD and exchanges
+// the resulting access token for an ACR refresh token.
+func (service *Service) exchangeAzureCredentials(registry *portainer.Registry) (*portainer.RegistryCredentials, time.Time, error) {
+	azureCredentials := &portainer.AzureCredentials{
+		ApplicationID:     registry.Username,
+		TenantID:          registry.Azure.TenantID,
+		AuthenticationKey: registry.Password,
+	}
+
+	azureToken, err := service.client.ExecuteAzureAuthenticationRequest(azureCredentials)
+	if err == portainer.ErrAzureInvalidCredentials {
+		return nil, time.Time{}, portainer.ErrRegistryInvalidCredentials
+	} else if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	host := registryHost(registry.URL)
+	params := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {host},
+		"tenant":       {registry.Azure.TenantID},
+		"access_token": {azureToken.AccessToken},
+	}
+
+	response, err := service.client.PostForm("https://"+host+"/oauth2/exchange", params)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusUnauthorized {
+		return nil, time.Time{}, portainer.ErrRegistryInvalidCredentials
+	} else if response.StatusCode != http.StatusOK {
+		return nil, time.Time{}, portainer.ErrRegistryTokenExchangeFailure
+	}
+
+	var exchangeResponse azureRegistryExchangeResponse
+	err = json.NewDecoder(response.Body).Decode(&exchangeResponse)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	credentials := &portainer.RegistryCredentials{
+		Username:      azureRegistryTokenUsername,
+		Password:      exchangeResponse.RefreshToken,
+		ServerAddress: registry.URL,
+	}
+
+	expirationTime := tokenExpirationTime(exchangeResponse.RefreshToken)
+	if expirationTime.IsZero() {
+		expiresOn, err := strconv.ParseInt(azureToken.ExpiresOn, 10, 64)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		expirationTime = time.Unix(expiresOn, 0)
+	}
+
+	return credentials, expirationTime, nil
+}
+
+// tokenExpirationTime reads the expiration claim of a JWT token without verifying its signature.
+// It returns a zero time if the claim cannot be read.
+func tokenExpirationTime(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claim jwtExpirationClaim
+	err = json.Unmarshal(payload, &claim)
+	if err != nil || claim.ExpiresAt == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(claim.ExpiresAt, 0)
+}
@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	ecrServiceName    = "ecr"
+	ecrTargetAction   = "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken"
+	ecrContentType    = "application/x-amz-json-1.1"
+	awsSigningAlgo    = "AWS4-HMAC-SHA256"
+	awsDateTimeFormat = "20060102T150405Z"
+	awsDateFormat     = "20060102"
+)
+
+var ecrRegistryURLRe = regexp.MustCompile(`\.dkr\.ecr\.([a-z0-9-]+)\.amazonaws\.com`)
+
+type (
+	ecrAuthorizationTokenResponse struct {
+		AuthorizationData []ecrAuthorizationData `json:"authorizationData"`
+	}
+
+	ecrAuthorizationData struct {
+		AuthorizationToken string  `json:"authorizationToken"`
+		ExpiresAt          float64 `json:"expiresAt"`
+		ProxyEndpoint      string  `json:"proxyEndpoint"`
+	}
+)
+
+// EcrRegionFromURL extracts the AWS region from the URL of an ECR registry
+// (e.g. 123456789012.dkr.ecr.eu-west-1.amazonaws.com). It returns an empty
+// string if the URL does not match the ECR naming scheme.
+func EcrRegionFromURL(registryURL string) string {
+	matches := ecrRegistryURLRe.FindStringSubmatch(registryURL)
+	if len(matches) != 2 {
+		return ""
+	}
+	return matches[1]
+}
+
+// exchangeEcrCredentials exchanges an AWS access key for a registry authorization token
+// using the ECR GetAuthorizationToken API.
+func (service *Service) exchangeEcrCredentials(registry *portainer.Registry) (*portainer.RegistryCredentials, time.Time, error) {
+	region := registry.Ecr.Region
+	if region == "" {
+		region = EcrRegionFromURL(registry.URL)
+	}
+
+	request, err := newEcrAuthorizationTokenRequest(region, registry.Username, registry.Password, time.Now().UTC())
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	response, err := service.client.Do(request)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusBadRequest || response.StatusCode == http.StatusForbidden {
+		return nil, time.Time{}, portainer.ErrRegistryInvalidCredentials
+	} else if response.StatusCode != http.StatusOK {
+		return nil, time.Time{}, portainer.ErrRegistryTokenExchangeFailure
+	}
+
+	var tokenResponse ecrAuthorizationTokenResponse
+	err = json.NewDecoder(response.Body).Decode(&tokenResponse)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if len(tokenResponse.AuthorizationData) == 0 {
+		return nil, time.Time{}, portainer.ErrRegistryTokenExchangeFailure
+	}
+	data := tokenResponse.AuthorizationData[0]
+
+	decodedToken, err := base64.StdEncoding.DecodeString(data.AuthorizationToken)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	tokenParts := strings.SplitN(string(decodedToken), ":", 2)
+	if len(tokenParts) != 2 {
+		return nil, time.Time{}, portainer.ErrRegistryTokenExchangeFailure
+	}
+
+	credentials := &portainer.RegistryCredentials{
+		Username:      tokenParts[0],
+		Password:      tokenParts[1],
+		ServerAddress: registry.URL,
+	}
+
+	return credentials, time.Unix(int64(data.ExpiresAt), 0), nil
+}
+
+// newEcrAuthorizationTokenRequest creates a GetAuthorizationToken request signed
+// with the AWS Signature Version 4 process.
+func newEcrAuthorizationTokenRequest(region, accessKeyID, secretAccessKey string, now time.Time) (*http.Request, error) {
+	host := fmt.Sprintf("api.ecr.%s.amazonaws.com", region)
+	payload := []byte("{}")
+
+	request, err := http.NewRequest(http.MethodPost, "https://"+host+"/", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	amzDate := now.Format(awsDateTimeFormat)
+	request.Header.Set("Content-Type", ecrContentType)
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Target", ecrTargetAction)
+
+	signedHeaders := "content-type;host;x-amz-date;x-amz-target"
+	canonicalHeaders := "content-type:" + ecrContentType + "\n" +
+		"host:" + host + "\n" +
+		"x-amz-date:" + amzDate + "\n" +
+		"x-amz-target:" + ecrTargetAction + "\n"
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodPost,
+		"/",
+		"",
+		canonicalHeaders,
+		signedHeaders,
+		hexSHA256(payload),
+	}, "\n")
+
+	credentialScope := strings.Join([]string{now.Format(awsDateFormat), region, ecrServiceName, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		awsSigningAlgo,
+		amzDate,
+		credentialScope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+secretAccessKey), now.Format(awsDateFormat))
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, ecrServiceName)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsSigningAlgo, accessKeyID, credentialScope, signedHeaders, signature))
+
+	return request, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
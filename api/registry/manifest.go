@@ -0,0 +1,110 @@
+package registry
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/portainer/portainer"
+)
+
+const registryContentDigestHeader = "Docker-Content-Digest"
+
+// manifestMediaTypes are the manifest formats accepted when resolving a tag. Multi-platform
+// formats are listed first so that the returned digest matches the one recorded by the Docker engine on pull.
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// ImageDigest returns the digest of the manifest currently associated to the tag of an image in its registry.
+// The latest tag is used when the image reference does not specify one. The registry is reached using HTTPS
+// unless the URL of the registry associated to the credentials uses HTTP. The certificate of an insecure registry
+// is not verified and HTTP is used when HTTPS is not available, as done by the Docker engine.
+func (service *Service) ImageDigest(image string, credentials *portainer.RegistryCredentials, insecure bool) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+
+	tagged, ok := reference.TagNameOnly(named).(reference.Tagged)
+	if !ok {
+		return "", portainer.ErrImageReferenceNotTagged
+	}
+
+	scheme := "https"
+	if credentials != nil && strings.HasPrefix(credentials.ServerAddress, "http://") {
+		scheme = "http"
+	}
+
+	repository := reference.Path(named)
+	manifestPath := "/v2/" + repository + "/manifests/" + tagged.Tag()
+	manifestURL := registryBaseURL(scheme+"://"+reference.Domain(named)) + manifestPath
+	httpClient := newRegistryHTTPClient(insecure)
+
+	response, err := headManifest(httpClient, manifestURL, "")
+	if err != nil && insecure && scheme == "https" {
+		manifestURL = registryBaseURL("http://"+reference.Domain(named)) + manifestPath
+		response, err = headManifest(httpClient, manifestURL, "")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if response.StatusCode == http.StatusUnauthorized {
+		if credentials == nil {
+			credentials = &portainer.RegistryCredentials{}
+		}
+
+		authorization, err := authorize(httpClient, response.Header.Get("WWW-Authenticate"), credentials, "repository:"+repository+":pull")
+		if err != nil {
+			return "", err
+		}
+
+		response, err = headManifest(httpClient, manifestURL, authorization)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", portainer.ErrRegistryManifestNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", portainer.ErrRegistryInvalidCredentials
+	default:
+		return "", portainer.Error("Unexpected registry response: " + response.Status)
+	}
+
+	digest := response.Header.Get(registryContentDigestHeader)
+	if digest == "" {
+		return "", portainer.ErrRegistryManifestNotFound
+	}
+
+	return digest, nil
+}
+
+func headManifest(httpClient *http.Client, manifestURL, authorization string) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mediaType := range manifestMediaTypes {
+		request.Header.Add("Accept", mediaType)
+	}
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+
+	return response, nil
+}
@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/portainer/portainer"
+)
+
+const dockerHubDomain = "docker.io"
+
+// IsDockerHubImage returns true when the image is hosted on the Docker Hub.
+func IsDockerHubImage(image string) bool {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return false
+	}
+	return reference.Domain(named) == dockerHubDomain
+}
+
+// ImageDomain returns the registry host of an image reference (e.g. docker.io for nginx:latest).
+func ImageDomain(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+	return reference.Domain(named), nil
+}
+
+// MatchImageRegistry returns the registry hosting the specified image or nil if none of the registries
+// is matching the image name. The registry URL and its mirrors are compared to the image name.
+// When multiple registries are defined on the same host (e.g. Gitlab registries defined with
+// a project path), the registry with the longest matching path is returned.
+func MatchImageRegistry(image string, registries []portainer.Registry) (*portainer.Registry, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, err
+	}
+	name := named.Name()
+
+	var matchingRegistry *portainer.Registry
+	matchingLength := 0
+	for idx := range registries {
+		names := []string{registries[idx].URL}
+		names = append(names, registries[idx].Mirrors...)
+
+		for _, n := range names {
+			registryName := registryName(n)
+			if registryName == "" {
+				continue
+			}
+
+			if (name == registryName || strings.HasPrefix(name, registryName+"/")) && len(registryName) > matchingLength {
+				matchingRegistry = &registries[idx]
+				matchingLength = len(registryName)
+			}
+		}
+	}
+
+	return matchingRegistry, nil
+}
+
+// registryName returns the registry URL without its scheme and trailing slash so that it can
+// be compared to the name of an image reference.
+func registryName(registryURL string) string {
+	name := registryURL
+	if idx := strings.Index(name, "://"); idx != -1 {
+		name = name[idx+3:]
+	}
+	return strings.TrimSuffix(name, "/")
+}
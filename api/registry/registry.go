@@ -0,0 +1,127 @@
+package registry
+
+import (
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/crypto"
+	"github.com/portainer/portainer/http/client"
+)
+
+const (
+	// tokenExpirationMargin is the duration before the expiration of a token
+	// after which a cached token is considered expired and will be refreshed.
+	tokenExpirationMargin = 5 * time.Minute
+)
+
+type (
+	// Service represents a service used to retrieve registry credentials.
+	// Credentials based on short-lived tokens are cached until they expire.
+	Service struct {
+		client *client.HTTPClient
+		tokens map[portainer.RegistryID]*registryToken
+		mutex  sync.Mutex
+	}
+
+	registryToken struct {
+		credentials    *portainer.RegistryCredentials
+		fingerprint    string
+		expirationTime time.Time
+	}
+)
+
+// NewService initializes a new service.
+func NewService() *Service {
+	return &Service{
+		client: client.NewHTTPClient(),
+		tokens: make(map[portainer.RegistryID]*registryToken),
+	}
+}
+
+// Credentials returns the credentials that must be used to authenticate against a registry.
+// For registries relying on token-based authentication (ECR, ACR), the stored credentials
+// are exchanged for a token which is re-used until it expires.
+func (service *Service) Credentials(registry *portainer.Registry) (*portainer.RegistryCredentials, error) {
+	if !registry.Authentication {
+		return &portainer.RegistryCredentials{ServerAddress: registry.URL}, nil
+	}
+
+	switch registry.Type {
+	case portainer.EcrRegistry:
+		return service.tokenCredentials(registry, service.exchangeEcrCredentials)
+	case portainer.AzureRegistry:
+		return service.tokenCredentials(registry, service.exchangeAzureCredentials)
+	case portainer.QuayRegistry, portainer.GitlabRegistry, portainer.CustomRegistry:
+		// Robot accounts (Quay) and deploy tokens (Gitlab) are directly accepted by the
+		// token service of these registries.
+		return &portainer.RegistryCredentials{
+			Username:      registry.Username,
+			Password:      registry.Password,
+			ServerAddress: registry.URL,
+		}, nil
+	}
+
+	return nil, portainer.ErrRegistryTypeNotSupported
+}
+
+type tokenExchange func(registry *portainer.Registry) (*portainer.RegistryCredentials, time.Time, error)
+
+func (service *Service) tokenCredentials(registry *portainer.Registry, exchange tokenExchange) (*portainer.RegistryCredentials, error) {
+	// Registries that are not persisted yet (e.g. during a connectivity test) are not cached.
+	if registry.ID == 0 {
+		credentials, _, err := exchange(registry)
+		return credentials, err
+	}
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	fingerprint := registryFingerprint(registry)
+
+	token := service.tokens[registry.ID]
+	if token != nil && token.fingerprint == fingerprint && time.Now().Add(tokenExpirationMargin).Before(token.expirationTime) {
+		return token.credentials, nil
+	}
+
+	credentials, expirationTime, err := exchange(registry)
+	if err != nil {
+		delete(service.tokens, registry.ID)
+		return nil, err
+	}
+
+	service.tokens[registry.ID] = &registryToken{
+		credentials:    credentials,
+		fingerprint:    fingerprint,
+		expirationTime: expirationTime,
+	}
+
+	return credentials, nil
+}
+
+// registryFingerprint is used to invalidate a cached token when the registry configuration is updated.
+func registryFingerprint(registry *portainer.Registry) string {
+	data := strings.Join([]string{
+		registry.URL,
+		registry.Username,
+		registry.Password,
+		registry.Ecr.Region,
+		registry.Azure.TenantID,
+	}, "\x00")
+
+	return hex.EncodeToString(crypto.HashFromBytes([]byte(data)))
+}
+
+// registryHost returns the host part of a registry URL, stripping any scheme or path.
+func registryHost(registryURL string) string {
+	host := registryURL
+	if idx := strings.Index(host, "://"); idx != -1 {
+		host = host[idx+3:]
+	}
+	if idx := strings.Index(host, "/"); idx != -1 {
+		host = host[:idx]
+	}
+	return host
+}
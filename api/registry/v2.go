@@ -0,0 +1,236 @@
+package registry
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	registryAPIVersionHeader = "Docker-Distribution-API-Version"
+	registryTimeout          = 10 * time.Second
+	dockerHubRegistryHost    = "registry-1.docker.io"
+)
+
+var challengeParameterRe = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+type (
+	authenticationChallenge struct {
+		scheme     string
+		parameters map[string]string
+	}
+
+	registryTokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+)
+
+// TestConnectivity pings the v2 API of a registry and goes through the authentication
+// handshake advertised by the registry using the registry credentials.
+// Failures are reported inside the returned report.
+func (service *Service) TestConnectivity(registry *portainer.Registry, tlsSkipVerify bool) *portainer.RegistryConnectivityReport {
+	report := &portainer.RegistryConnectivityReport{}
+	httpClient := newRegistryHTTPClient(tlsSkipVerify)
+	baseURL := registryBaseURL(registry.URL)
+
+	response, err := httpClient.Get(baseURL + "/v2/")
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	response.Body.Close()
+
+	report.Reachable = true
+	report.APIVersion = response.Header.Get(registryAPIVersionHeader)
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		if !registry.Authentication {
+			report.Authenticated = true
+			return report
+		}
+	case http.StatusUnauthorized:
+		if !registry.Authentication {
+			report.Error = portainer.ErrRegistryAuthRequired.Error()
+			return report
+		}
+	default:
+		report.Error = portainer.ErrRegistryAPINotSupported.Error()
+		return report
+	}
+
+	credentials, err := service.Credentials(registry)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	authorization, err := authorize(httpClient, response.Header.Get("WWW-Authenticate"), credentials, "")
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	request, err := http.NewRequest(http.MethodGet, baseURL+"/v2/", nil)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	request.Header.Set("Authorization", authorization)
+
+	response, err = httpClient.Do(request)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		report.Error = portainer.ErrRegistryInvalidCredentials.Error()
+		return report
+	}
+
+	report.Authenticated = true
+	return report
+}
+
+// authorize answers the authentication challenge returned by a registry and returns the value
+// of the Authorization header that must be used in subsequent requests.
+// An empty challenge is answered using basic authentication.
+func authorize(httpClient *http.Client, challengeHeader string, credentials *portainer.RegistryCredentials, scope string) (string, error) {
+	challenge := parseAuthenticationChallenge(challengeHeader)
+
+	switch strings.ToLower(challenge.scheme) {
+	case "", "basic":
+		auth := base64.StdEncoding.EncodeToString([]byte(credentials.Username + ":" + credentials.Password))
+		return "Basic " + auth, nil
+	case "bearer":
+		token, err := retrieveBearerToken(httpClient, challenge, credentials, scope)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	}
+
+	return "", portainer.ErrRegistryUnsupportedChallenge
+}
+
+// retrieveBearerToken requests a token from the token service referenced in the challenge realm,
+// as described in the Docker registry token authentication specification.
+func retrieveBearerToken(httpClient *http.Client, challenge *authenticationChallenge, credentials *portainer.RegistryCredentials, scope string) (string, error) {
+	realm := challenge.parameters["realm"]
+	if realm == "" {
+		return "", portainer.ErrRegistryUnsupportedChallenge
+	}
+
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", err
+	}
+
+	query := tokenURL.Query()
+	if service, ok := challenge.parameters["service"]; ok {
+		query.Set("service", service)
+	}
+	if scope == "" {
+		scope = challenge.parameters["scope"]
+	}
+	if scope != "" {
+		query.Set("scope", scope)
+	}
+	if credentials.Username != "" {
+		query.Set("account", credentials.Username)
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	request, err := http.NewRequest(http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if credentials.Username != "" {
+		request.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden {
+		return "", portainer.ErrRegistryInvalidCredentials
+	} else if response.StatusCode != http.StatusOK {
+		return "", portainer.ErrRegistryTokenExchangeFailure
+	}
+
+	var tokenResponse registryTokenResponse
+	err = json.NewDecoder(response.Body).Decode(&tokenResponse)
+	if err != nil {
+		return "", err
+	}
+
+	if tokenResponse.Token != "" {
+		return tokenResponse.Token, nil
+	}
+	if tokenResponse.AccessToken != "" {
+		return tokenResponse.AccessToken, nil
+	}
+	return "", portainer.ErrRegistryTokenExchangeFailure
+}
+
+// parseAuthenticationChallenge parses a WWW-Authenticate header such as
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io"
+func parseAuthenticationChallenge(header string) *authenticationChallenge {
+	challenge := &authenticationChallenge{
+		parameters: make(map[string]string),
+	}
+
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return challenge
+	}
+
+	parts := strings.SplitN(header, " ", 2)
+	challenge.scheme = parts[0]
+	if len(parts) == 2 {
+		for _, match := range challengeParameterRe.FindAllStringSubmatch(parts[1], -1) {
+			challenge.parameters[strings.ToLower(match[1])] = match[2]
+		}
+	}
+
+	return challenge
+}
+
+func newRegistryHTTPClient(tlsSkipVerify bool) *http.Client {
+	return &http.Client{
+		Timeout: registryTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: tlsSkipVerify},
+		},
+	}
+}
+
+// registryBaseURL returns the root URL of the registry API. HTTPS is used unless
+// the registry URL explicitly specifies another scheme. Any path (e.g. a Gitlab project) is ignored.
+func registryBaseURL(registryURL string) string {
+	scheme := "https"
+	if idx := strings.Index(registryURL, "://"); idx != -1 {
+		scheme = registryURL[:idx]
+	}
+
+	host := registryHost(registryURL)
+	if host == "docker.io" || host == "index.docker.io" {
+		host = dockerHubRegistryHost
+	}
+
+	return scheme + "://" + host
+}
@@ -0,0 +1,281 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/portainer/portainer"
+)
+
+// SchemaVersion2 is the version of the templates file format supporting typed inputs.
+const SchemaVersion2 = "2"
+
+// templatesFileV2 represents a templates file using the v2 format. The v1 format is a plain list of templates.
+type templatesFileV2 struct {
+	Version   string               `json:"version"`
+	Templates []portainer.Template `json:"templates"`
+}
+
+// ParseTemplates decodes the content of a templates file using either the v1 or the v2 format.
+// The templates are returned using the v2 schema with the v1 environment variables definition kept
+// in sync for backward compatibility.
+func ParseTemplates(data []byte) ([]portainer.Template, error) {
+	var templates []portainer.Template
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		err := json.Unmarshal(data, &templates)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var file templatesFileV2
+		err := json.Unmarshal(data, &file)
+		if err != nil {
+			return nil, err
+		}
+
+		if file.Version != SchemaVersion2 {
+			return nil, fmt.Errorf("unsupported templates file version: %s", file.Version)
+		}
+		templates = file.Templates
+	}
+
+	for idx := range templates {
+		err := NormalizeTemplate(&templates[idx])
+		if err != nil {
+			return nil, fmt.Errorf("invalid template %s: %s", templates[idx].Title, err)
+		}
+	}
+
+	return templates, nil
+}
+
+// NormalizeTemplate converts the v1 environment variables definition of a template to typed inputs
+// when the template does not define any input. The v1 definition is then regenerated from the inputs
+// so that clients relying on the v1 schema keep working. The inputs definition is validated.
+func NormalizeTemplate(template *portainer.Template) error {
+	if len(template.Inputs) == 0 && len(template.Env) > 0 {
+		template.Inputs = ConvertEnvToInputs(template.Env)
+	}
+
+	err := ValidateInputsDefinition(template.Inputs)
+	if err != nil {
+		return err
+	}
+
+	template.Env = ConvertInputsToEnv(template.Inputs)
+	return nil
+}
+
+// ConvertEnvToInputs converts a v1 environment variables definition to typed inputs.
+func ConvertEnvToInputs(env []portainer.TemplateEnv) []portainer.TemplateInput {
+	inputs := make([]portainer.TemplateInput, 0, len(env))
+
+	for _, envvar := range env {
+		input := portainer.TemplateInput{
+			Name:        envvar.Name,
+			Label:       envvar.Label,
+			Description: envvar.Description,
+			Type:        portainer.StringTemplateInput,
+			Default:     envvar.Default,
+			Preset:      envvar.Preset,
+		}
+
+		if len(envvar.Select) > 0 {
+			input.Type = portainer.SelectTemplateInput
+			input.Options = envvar.Select
+			for _, option := range envvar.Select {
+				if option.Default {
+					input.Default = option.Value
+				}
+			}
+		}
+
+		inputs = append(inputs, input)
+	}
+
+	return inputs
+}
+
+// ConvertInputsToEnv converts typed inputs to a v1 environment variables definition.
+// Validation rules, types and conditions cannot be expressed using the v1 schema and are dropped.
+func ConvertInputsToEnv(inputs []portainer.TemplateInput) []portainer.TemplateEnv {
+	env := make([]portainer.TemplateEnv, 0, len(inputs))
+
+	for _, input := range inputs {
+		envvar := portainer.TemplateEnv{
+			Name:        input.Name,
+			Label:       input.Label,
+			Description: input.Description,
+			Default:     input.Default,
+			Preset:      input.Preset,
+		}
+
+		if input.Type == portainer.SelectTemplateInput {
+			envvar.Default = ""
+			for _, option := range input.Options {
+				option.Default = option.Value == input.Default
+				envvar.Select = append(envvar.Select, option)
+			}
+		}
+
+		env = append(env, envvar)
+	}
+
+	return env
+}
+
+// ValidateInputsDefinition ensures that the typed inputs of a template are properly defined.
+// Inputs defined without a type are string inputs, their type is set accordingly.
+func ValidateInputsDefinition(inputs []portainer.TemplateInput) error {
+	names := make(map[string]bool)
+	for idx := range inputs {
+		input := &inputs[idx]
+		if input.Type == "" {
+			input.Type = portainer.StringTemplateInput
+		}
+
+		if input.Name == "" {
+			return portainer.Error("Invalid input name")
+		}
+		if names[input.Name] {
+			return fmt.Errorf("duplicate input: %s", input.Name)
+		}
+		names[input.Name] = true
+	}
+
+	for _, input := range inputs {
+		switch input.Type {
+		case portainer.StringTemplateInput, portainer.PasswordTemplateInput, portainer.MultilineTemplateInput, portainer.BoolTemplateInput:
+		case portainer.IntTemplateInput:
+			if input.Min != nil && input.Max != nil && *input.Min > *input.Max {
+				return fmt.Errorf("invalid range for input %s", input.Name)
+			}
+		case portainer.SelectTemplateInput:
+			if len(input.Options) == 0 {
+				return fmt.Errorf("no options defined for input %s", input.Name)
+			}
+		default:
+			return fmt.Errorf("unsupported type for input %s: %s", input.Name, input.Type)
+		}
+
+		if input.Regex != "" {
+			_, err := regexp.Compile(input.Regex)
+			if err != nil {
+				return fmt.Errorf("invalid regex for input %s: %s", input.Name, err)
+			}
+		}
+
+		if input.Condition != nil && (!names[input.Condition.Input] || input.Condition.Input == input.Name) {
+			return fmt.Errorf("invalid condition for input %s: unknown input %s", input.Name, input.Condition.Input)
+		}
+
+		if input.Default != "" {
+			err := validateInputValue(&input, input.Default)
+			if err != nil {
+				return fmt.Errorf("invalid default value: %s", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ResolveInputs validates the values specified for the inputs of a template and returns the environment
+// variables that must be used to deploy the template. Preset inputs always use their default value, inputs
+// without value use their default value and inputs whose condition is not met are ignored.
+func ResolveInputs(template *portainer.Template, values map[string]string) ([]portainer.Pair, error) {
+	resolved := make(map[string]string)
+	for _, input := range template.Inputs {
+		value, ok := values[input.Name]
+		if input.Preset || !ok || value == "" {
+			value = input.Default
+		}
+		resolved[input.Name] = value
+	}
+
+	env := make([]portainer.Pair, 0, len(template.Inputs))
+	for _, input := range template.Inputs {
+		if !conditionMet(template.Inputs, resolved, input.Condition) {
+			continue
+		}
+
+		value := resolved[input.Name]
+		if value == "" {
+			if input.Required {
+				return nil, fmt.Errorf("missing value for required input %s", input.Name)
+			}
+			continue
+		}
+
+		err := validateInputValue(&input, value)
+		if err != nil {
+			return nil, err
+		}
+
+		env = append(env, portainer.Pair{Name: input.Name, Value: value})
+	}
+
+	return env, nil
+}
+
+// conditionMet returns true when the input referenced in the condition is itself active and has the expected value.
+func conditionMet(inputs []portainer.TemplateInput, values map[string]string, condition *portainer.TemplateInputCondition) bool {
+	for depth := 0; condition != nil && depth < len(inputs); depth++ {
+		if values[condition.Input] != condition.Value {
+			return false
+		}
+
+		var next *portainer.TemplateInputCondition
+		for _, input := range inputs {
+			if input.Name == condition.Input {
+				next = input.Condition
+			}
+		}
+		condition = next
+	}
+
+	return condition == nil
+}
+
+func validateInputValue(input *portainer.TemplateInput, value string) error {
+	switch input.Type {
+	case portainer.IntTemplateInput:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for input %s: must be an integer", input.Name)
+		}
+		if input.Min != nil && number < *input.Min {
+			return fmt.Errorf("invalid value for input %s: must be greater than or equal to %d", input.Name, *input.Min)
+		}
+		if input.Max != nil && number > *input.Max {
+			return fmt.Errorf("invalid value for input %s: must be lower than or equal to %d", input.Name, *input.Max)
+		}
+	case portainer.BoolTemplateInput:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("invalid value for input %s: must be true or false", input.Name)
+		}
+	case portainer.SelectTemplateInput:
+		valid := false
+		for _, option := range input.Options {
+			if option.Value == value {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid value for input %s: must be one of the available options", input.Name)
+		}
+	}
+
+	if input.Regex != "" {
+		matched, err := regexp.MatchString(input.Regex, value)
+		if err != nil || !matched {
+			return fmt.Errorf("invalid value for input %s: must match %s", input.Name, input.Regex)
+		}
+	}
+
+	return nil
+}
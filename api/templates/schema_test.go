@@ -0,0 +1,230 @@
+package templates
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/portainer/portainer"
+)
+
+func intPointer(value int) *int {
+	return &value
+}
+
+func TestValidateInputsDefinition(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputs        []portainer.TemplateInput
+		expectedError bool
+		expectedTypes []portainer.TemplateInputType
+	}{
+		{
+			name:          "Missing type defaults to string",
+			inputs:        []portainer.TemplateInput{{Name: "NAME", Default: "value"}},
+			expectedTypes: []portainer.TemplateInputType{portainer.StringTemplateInput},
+		},
+		{
+			name:          "Explicit type is kept",
+			inputs:        []portainer.TemplateInput{{Name: "PORT", Type: portainer.IntTemplateInput, Default: "80"}},
+			expectedTypes: []portainer.TemplateInputType{portainer.IntTemplateInput},
+		},
+		{
+			name:          "Missing name",
+			inputs:        []portainer.TemplateInput{{Type: portainer.StringTemplateInput}},
+			expectedError: true,
+		},
+		{
+			name:          "Duplicate name",
+			inputs:        []portainer.TemplateInput{{Name: "A"}, {Name: "A"}},
+			expectedError: true,
+		},
+		{
+			name:          "Unsupported type",
+			inputs:        []portainer.TemplateInput{{Name: "A", Type: "float"}},
+			expectedError: true,
+		},
+		{
+			name:          "Invalid range",
+			inputs:        []portainer.TemplateInput{{Name: "A", Type: portainer.IntTemplateInput, Min: intPointer(10), Max: intPointer(1)}},
+			expectedError: true,
+		},
+		{
+			name:          "Select without options",
+			inputs:        []portainer.TemplateInput{{Name: "A", Type: portainer.SelectTemplateInput}},
+			expectedError: true,
+		},
+		{
+			name:          "Invalid regex",
+			inputs:        []portainer.TemplateInput{{Name: "A", Regex: "("}},
+			expectedError: true,
+		},
+		{
+			name:          "Condition on an unknown input",
+			inputs:        []portainer.TemplateInput{{Name: "A", Condition: &portainer.TemplateInputCondition{Input: "B", Value: "x"}}},
+			expectedError: true,
+		},
+		{
+			name:          "Condition on itself",
+			inputs:        []portainer.TemplateInput{{Name: "A", Condition: &portainer.TemplateInputCondition{Input: "A", Value: "x"}}},
+			expectedError: true,
+		},
+		{
+			name:          "Invalid default value",
+			inputs:        []portainer.TemplateInput{{Name: "A", Type: portainer.BoolTemplateInput, Default: "yes"}},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateInputsDefinition(test.inputs)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("Unexpected error: got %v, expected an error: %t", err, test.expectedError)
+			}
+
+			for idx, expectedType := range test.expectedTypes {
+				if test.inputs[idx].Type != expectedType {
+					t.Errorf("Unexpected type for input %s: got %q want %q", test.inputs[idx].Name, test.inputs[idx].Type, expectedType)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveInputs(t *testing.T) {
+	inputs := []portainer.TemplateInput{
+		{Name: "DB_ENABLED", Type: portainer.BoolTemplateInput, Default: "false"},
+		{Name: "DB_ENGINE", Type: portainer.SelectTemplateInput, Default: "postgres", Options: []portainer.TemplateEnvSelect{{Value: "postgres"}, {Value: "mysql"}}, Condition: &portainer.TemplateInputCondition{Input: "DB_ENABLED", Value: "true"}},
+		{Name: "DB_PORT", Type: portainer.IntTemplateInput, Min: intPointer(1), Max: intPointer(65535), Condition: &portainer.TemplateInputCondition{Input: "DB_ENGINE", Value: "mysql"}},
+		{Name: "APP_NAME", Type: portainer.StringTemplateInput, Required: true, Regex: "^[a-z]+$"},
+		{Name: "VERSION", Type: portainer.StringTemplateInput, Default: "1.0", Preset: true},
+	}
+
+	tests := []struct {
+		name          string
+		values        map[string]string
+		expected      []portainer.Pair
+		expectedError bool
+	}{
+		{
+			name:   "Defaults and inactive conditional inputs",
+			values: map[string]string{"APP_NAME": "app"},
+			expected: []portainer.Pair{
+				{Name: "DB_ENABLED", Value: "false"},
+				{Name: "APP_NAME", Value: "app"},
+				{Name: "VERSION", Value: "1.0"},
+			},
+		},
+		{
+			name:   "Condition met",
+			values: map[string]string{"APP_NAME": "app", "DB_ENABLED": "true"},
+			expected: []portainer.Pair{
+				{Name: "DB_ENABLED", Value: "true"},
+				{Name: "DB_ENGINE", Value: "postgres"},
+				{Name: "APP_NAME", Value: "app"},
+				{Name: "VERSION", Value: "1.0"},
+			},
+		},
+		{
+			name:   "Chained conditions met",
+			values: map[string]string{"APP_NAME": "app", "DB_ENABLED": "true", "DB_ENGINE": "mysql", "DB_PORT": "3306"},
+			expected: []portainer.Pair{
+				{Name: "DB_ENABLED", Value: "true"},
+				{Name: "DB_ENGINE", Value: "mysql"},
+				{Name: "DB_PORT", Value: "3306"},
+				{Name: "APP_NAME", Value: "app"},
+				{Name: "VERSION", Value: "1.0"},
+			},
+		},
+		{
+			name:   "Chained condition not met when its parent is inactive",
+			values: map[string]string{"APP_NAME": "app", "DB_ENABLED": "false", "DB_ENGINE": "mysql", "DB_PORT": "3306"},
+			expected: []portainer.Pair{
+				{Name: "DB_ENABLED", Value: "false"},
+				{Name: "APP_NAME", Value: "app"},
+				{Name: "VERSION", Value: "1.0"},
+			},
+		},
+		{
+			name:   "Preset value cannot be overridden",
+			values: map[string]string{"APP_NAME": "app", "VERSION": "2.0"},
+			expected: []portainer.Pair{
+				{Name: "DB_ENABLED", Value: "false"},
+				{Name: "APP_NAME", Value: "app"},
+				{Name: "VERSION", Value: "1.0"},
+			},
+		},
+		{
+			name:          "Missing required value",
+			values:        map[string]string{},
+			expectedError: true,
+		},
+		{
+			name:          "Value not matching the regex",
+			values:        map[string]string{"APP_NAME": "App1"},
+			expectedError: true,
+		},
+		{
+			name:          "Invalid option",
+			values:        map[string]string{"APP_NAME": "app", "DB_ENABLED": "true", "DB_ENGINE": "sqlite"},
+			expectedError: true,
+		},
+		{
+			name:          "Value out of range",
+			values:        map[string]string{"APP_NAME": "app", "DB_ENABLED": "true", "DB_ENGINE": "mysql", "DB_PORT": "70000"},
+			expectedError: true,
+		},
+		{
+			name:          "Invalid boolean",
+			values:        map[string]string{"APP_NAME": "app", "DB_ENABLED": "yes"},
+			expectedError: true,
+		},
+	}
+
+	template := &portainer.Template{Inputs: inputs}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, err := ResolveInputs(template, test.values)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("Unexpected error: got %v, expected an error: %t", err, test.expectedError)
+			}
+
+			if !test.expectedError && !reflect.DeepEqual(env, test.expected) {
+				t.Errorf("Unexpected environment: got %v want %v", env, test.expected)
+			}
+		})
+	}
+}
+
+func TestConditionMet(t *testing.T) {
+	inputs := []portainer.TemplateInput{
+		{Name: "A"},
+		{Name: "B", Condition: &portainer.TemplateInputCondition{Input: "A", Value: "on"}},
+		{Name: "C", Condition: &portainer.TemplateInputCondition{Input: "B", Value: "on"}},
+		{Name: "LOOP1", Condition: &portainer.TemplateInputCondition{Input: "LOOP2", Value: "on"}},
+		{Name: "LOOP2", Condition: &portainer.TemplateInputCondition{Input: "LOOP1", Value: "on"}},
+	}
+
+	tests := []struct {
+		name      string
+		values    map[string]string
+		condition *portainer.TemplateInputCondition
+		expected  bool
+	}{
+		{name: "No condition", condition: nil, expected: true},
+		{name: "Value matches", values: map[string]string{"A": "on"}, condition: inputs[1].Condition, expected: true},
+		{name: "Value does not match", values: map[string]string{"A": "off"}, condition: inputs[1].Condition, expected: false},
+		{name: "Chained conditions match", values: map[string]string{"A": "on", "B": "on"}, condition: inputs[2].Condition, expected: true},
+		{name: "Parent condition does not match", values: map[string]string{"A": "off", "B": "on"}, condition: inputs[2].Condition, expected: false},
+		{name: "Cyclic conditions are never met", values: map[string]string{"LOOP1": "on", "LOOP2": "on"}, condition: inputs[3].Condition, expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := conditionMet(inputs, test.values, test.condition); result != test.expected {
+				t.Errorf("Unexpected result: got %t want %t", result, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package templates
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/http/client"
+)
+
+const defaultTemplatesFilePath = "templates.json"
+
+// Service represents a service used to import the templates defined in the template sources
+// configured in the settings.
+type Service struct {
+	templateService portainer.TemplateService
+	settingsService portainer.SettingsService
+	gitService      portainer.GitService
+	mutex           sync.Mutex
+}
+
+// NewService returns a new instance of Service.
+func NewService(templateService portainer.TemplateService, settingsService portainer.SettingsService, gitService portainer.GitService) *Service {
+	return &Service{
+		templateService: templateService,
+		settingsService: settingsService,
+		gitService:      gitService,
+	}
+}
+
+// RefreshTemplateSources imports the templates of every template source. The templates imported from
+// a source that is no longer defined are removed while the templates created locally are left untouched.
+// The outcome of the refresh of each source is stored in the settings.
+func (service *Service) RefreshTemplateSources() error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	settings, err := service.settingsService.Settings()
+	if err != nil {
+		return err
+	}
+
+	sources := make(map[portainer.TemplateSourceID]bool)
+	for idx := range settings.TemplateSources {
+		source := &settings.TemplateSources[idx]
+		service.refreshSource(source)
+		sources[source.ID] = true
+	}
+
+	err = service.removeOrphanTemplates(sources)
+	if err != nil {
+		return err
+	}
+
+	return service.updateSourcesStatus(settings.TemplateSources)
+}
+
+// RefreshTemplateSource imports the templates of a single template source.
+func (service *Service) RefreshTemplateSource(ID portainer.TemplateSourceID) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	settings, err := service.settingsService.Settings()
+	if err != nil {
+		return err
+	}
+
+	for _, source := range settings.TemplateSources {
+		if source.ID == ID {
+			service.refreshSource(&source)
+			return service.updateSourcesStatus([]portainer.TemplateSource{source})
+		}
+	}
+
+	return portainer.ErrObjectNotFound
+}
+
+func (service *Service) refreshSource(source *portainer.TemplateSource) {
+	source.LastRefresh = time.Now().Unix()
+	source.LastError = ""
+
+	templates, err := service.fetchTemplates(source)
+	if err == nil {
+		err = service.reconcileTemplates(source.ID, templates)
+	}
+
+	if err != nil {
+		log.Printf("template error: unable to refresh template source (source=%s, URL=%s) (err=%s)\n", source.Name, source.URL, err)
+		source.LastError = err.Error()
+	}
+}
+
+func (service *Service) fetchTemplates(source *portainer.TemplateSource) ([]portainer.Template, error) {
+	var data []byte
+	var err error
+
+	switch source.Type {
+	case portainer.URLTemplateSource:
+		data, err = client.Get(source.URL)
+	case portainer.GitTemplateSource:
+		data, err = service.fetchRepositoryTemplates(source)
+	default:
+		err = portainer.ErrTemplateSourceTypeNotSupported
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseTemplates(data)
+}
+
+func (service *Service) fetchRepositoryTemplates(source *portainer.TemplateSource) ([]byte, error) {
+	projectPath, err := ioutil.TempDir("", "portainer-templates-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(projectPath)
+
+	if source.RepositoryAuthentication {
+		err = service.gitService.ClonePrivateRepositoryWithBasicAuth(source.URL, source.RepositoryReferenceName, projectPath, source.RepositoryUsername, source.RepositoryPassword)
+	} else {
+		err = service.gitService.ClonePublicRepository(source.URL, source.RepositoryReferenceName, projectPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	filePath := source.FilePath
+	if filePath == "" {
+		filePath = defaultTemplatesFilePath
+	}
+
+	templatesFilePath, err := resolveTemplatesFilePath(projectPath, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(templatesFilePath)
+}
+
+// resolveTemplatesFilePath returns the path of the templates file inside the repository. Symbolic links are
+// resolved and the file must be located inside the repository.
+func resolveTemplatesFilePath(projectPath, filePath string) (string, error) {
+	resolvedProjectPath, err := filepath.EvalSymlinks(projectPath)
+	if err != nil {
+		return "", err
+	}
+
+	templatesFilePath, err := filepath.EvalSymlinks(filepath.Join(projectPath, filepath.FromSlash(filePath)))
+	if err != nil {
+		return "", portainer.ErrInvalidTemplateSourceFilePath
+	}
+
+	if !strings.HasPrefix(templatesFilePath, resolvedProjectPath+string(filepath.Separator)) {
+		return "", portainer.ErrInvalidTemplateSourceFilePath
+	}
+
+	return templatesFilePath, nil
+}
+
+// reconcileTemplates updates the templates previously imported from the source, creates the new ones
+// and removes the templates that are no longer available in the source.
+// Templates are matched using their type and title, a source defining several templates of the same type
+// with the same title is refused.
+func (service *Service) reconcileTemplates(sourceID portainer.TemplateSourceID, templates []portainer.Template) error {
+	titles := make(map[string]bool)
+	for idx := range templates {
+		key := templateKey(&templates[idx])
+		if titles[key] {
+			return portainer.ErrTemplateSourceDuplicateTitle
+		}
+		titles[key] = true
+	}
+
+	existingTemplates, err := service.templateService.Templates()
+	if err != nil {
+		return err
+	}
+
+	sourceTemplates := make(map[string]portainer.Template)
+	for _, template := range existingTemplates {
+		if template.SourceID == sourceID {
+			sourceTemplates[templateKey(&template)] = template
+		}
+	}
+
+	for _, template := range templates {
+		template.SourceID = sourceID
+		key := templateKey(&template)
+
+		if existingTemplate, ok := sourceTemplates[key]; ok {
+			template.ID = existingTemplate.ID
+			template.AuthorizedUsers = existingTemplate.AuthorizedUsers
+			template.AuthorizedTeams = existingTemplate.AuthorizedTeams
+			template.EndpointGroups = existingTemplate.EndpointGroups
+			err = service.templateService.UpdateTemplate(template.ID, &template)
+			if err != nil {
+				return err
+			}
+			delete(sourceTemplates, key)
+			continue
+		}
+
+		err = service.templateService.CreateTemplate(&template)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, template := range sourceTemplates {
+		err = service.templateService.DeleteTemplate(template.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (service *Service) removeOrphanTemplates(sources map[portainer.TemplateSourceID]bool) error {
+	templates, err := service.templateService.Templates()
+	if err != nil {
+		return err
+	}
+
+	for _, template := range templates {
+		if template.SourceID != 0 && !sources[template.SourceID] {
+			err = service.templateService.DeleteTemplate(template.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateSourcesStatus persists the outcome of the refresh of the sources. The settings are retrieved again
+// as they might have been updated during the refresh.
+func (service *Service) updateSourcesStatus(refreshedSources []portainer.TemplateSource) error {
+	settings, err := service.settingsService.Settings()
+	if err != nil {
+		return err
+	}
+
+	for _, refreshedSource := range refreshedSources {
+		for idx := range settings.TemplateSources {
+			if settings.TemplateSources[idx].ID == refreshedSource.ID {
+				settings.TemplateSources[idx].LastRefresh = refreshedSource.LastRefresh
+				settings.TemplateSources[idx].LastError = refreshedSource.LastError
+			}
+		}
+	}
+
+	return service.settingsService.UpdateSettings(settings)
+}
+
+func templateKey(template *portainer.Template) string {
+	return fmt.Sprintf("%d/%s", template.Type, template.Title)
+}